@@ -94,12 +94,22 @@ func (c *Client) getSummaryActivities(ctx context.Context, athleteID uint, from,
 		return nil, fmt.Errorf("could not call: %w", err)
 	}
 
-	var v []*SummaryActivity
-	err = json.Unmarshal(body, &v)
+	var raw []json.RawMessage
+	err = json.Unmarshal(body, &raw)
 	if err != nil {
 		return nil, err
 	}
 
+	v := make([]*SummaryActivity, 0, len(raw))
+	for _, r := range raw {
+		var a SummaryActivity
+		if err := json.Unmarshal(r, &a); err != nil {
+			return nil, err
+		}
+		a.Raw = r
+		v = append(v, &a)
+	}
+
 	return v, nil
 }
 
@@ -119,6 +129,7 @@ func (c *Client) GetDetailedActivity(ctx context.Context, athleteID, activityID
 	if err != nil {
 		return nil, err
 	}
+	v.Raw = body
 
 	return &v, nil
 
@@ -0,0 +1,33 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrActivityCursorNotFound = errors.New("activity cursor not found")
+
+// ActivityCursor is the last activity of an athlete that was synced. Activities are synced in order of
+// their start date; the activity ID orders activities starting in the same second.
+type ActivityCursor struct {
+	AthleteID  uint      `json:"athlete_id"`
+	StartDate  time.Time `json:"start_date"`
+	ActivityID uint      `json:"activity_id"`
+}
+
+// After reports whether the activity comes after the cursor.
+func (c ActivityCursor) After(startDate time.Time, activityID uint) bool {
+	if !startDate.Equal(c.StartDate) {
+		return startDate.After(c.StartDate)
+	}
+
+	return activityID > c.ActivityID
+}
+
+// ActivityCursorStore persists the ActivityCursor of each athlete.
+type ActivityCursorStore interface {
+	// GetActivityCursor returns ErrActivityCursorNotFound if no cursor was saved for the athlete.
+	GetActivityCursor(ctx context.Context, athleteID uint) (*ActivityCursor, error)
+	SaveActivityCursor(ctx context.Context, cursor *ActivityCursor) error
+}
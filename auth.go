@@ -15,6 +15,67 @@ type Token struct {
 	*oauth2.Token
 	AthleteID uint   `json:"athlete_id"`
 	Scope     string `json:"scope"`
+	// Revoked is set once Strava rejects the refresh token, e.g. after the athlete deauthorized the app.
+	Revoked bool `json:"revoked,omitempty"`
+	// Raw holds additional fields returned by the token endpoint, e.g. the athlete summary.
+	Raw map[string]any `json:"raw,omitempty"`
+}
+
+// Clone returns a deep copy of the token.
+func (t *Token) Clone() *Token {
+	c := *t
+	if t.Token != nil {
+		ot := *t.Token
+		c.Token = &ot
+	}
+	if t.Raw != nil {
+		c.Raw = cloneValue(t.Raw).(map[string]any)
+	}
+
+	return &c
+}
+
+// cloneValue copies maps and slices of decoded JSON values.
+func cloneValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[k] = cloneValue(e)
+		}
+		return m
+	case []any:
+		s := make([]any, len(v))
+		for i, e := range v {
+			s[i] = cloneValue(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
+
+// tokenExtraKeys are the additional fields Strava returns from the token endpoint.
+var tokenExtraKeys = []string{"athlete", "expires_at"}
+
+// tokenRaw collects the additional fields of oauthToken on top of base.
+func tokenRaw(base map[string]any, oauthToken *oauth2.Token) map[string]any {
+	raw := make(map[string]any, len(base)+len(tokenExtraKeys))
+	for k, v := range base {
+		raw[k] = v
+	}
+
+	for _, k := range tokenExtraKeys {
+		if v := oauthToken.Extra(k); v != nil {
+			raw[k] = v
+		}
+	}
+
+	if len(raw) == 0 {
+		return nil
+	}
+
+	return raw
 }
 
 func (c *Client) AuthRedirectURL() string {
@@ -22,6 +83,12 @@ func (c *Client) AuthRedirectURL() string {
 }
 
 func (c *Client) AuthCodeURL(redirectURL string, scopes []string) string {
+	return c.AuthCodeURLWithState(redirectURL, scopes, OAuthStaticState)
+}
+
+// AuthCodeURLWithState is AuthCodeURL with a state of the caller, e.g. a random one per login. The caller
+// checks the state of the redirect and exchanges the code with AuthExchangeCode.
+func (c *Client) AuthCodeURLWithState(redirectURL string, scopes []string, state string) string {
 	oacfg := c.oacfg
 	oacfg.RedirectURL = redirectURL
 	if redirectURL != "" {
@@ -31,7 +98,7 @@ func (c *Client) AuthCodeURL(redirectURL string, scopes []string) string {
 		oacfg.Scopes = scopes
 	}
 
-	return oacfg.AuthCodeURL(OAuthStaticState, oauth2.AccessTypeOffline)
+	return oacfg.AuthCodeURL(state, oauth2.AccessTypeOffline)
 }
 
 func (c *Client) AuthExchange(ctx context.Context, code, scope, state string) (uint, error) {
@@ -39,7 +106,13 @@ func (c *Client) AuthExchange(ctx context.Context, code, scope, state string) (u
 		return 0, fmt.Errorf("invalid state: %s", state)
 	}
 
-	oauthToken, err := c.oacfg.Exchange(ctx, code)
+	return c.AuthExchangeCode(ctx, code, scope)
+}
+
+// AuthExchangeCode exchanges the code for a token and saves it like AuthExchange, without checking the
+// state. Use it with AuthCodeURLWithState, after checking the state.
+func (c *Client) AuthExchangeCode(ctx context.Context, code, scope string) (uint, error) {
+	oauthToken, err := c.oacfg.Exchange(c.oauthContext(ctx), code)
 	if err != nil {
 		return 0, fmt.Errorf("could not exchange code for token: %w", err)
 	}
@@ -54,11 +127,18 @@ func (c *Client) AuthExchange(ctx context.Context, code, scope, state string) (u
 		Token:     oauthToken,
 		AthleteID: athleteID,
 		Scope:     scope,
+		Raw:       tokenRaw(nil, oauthToken),
 	}
 
 	if err := c.tstore.Save(ctx, token); err != nil {
 		return 0, fmt.Errorf("could not save token: %w", err)
 	}
 
+	c.hooks.tokenCreated(ctx, TokenEvent{
+		AthleteID: athleteID,
+		NewExpiry: token.Expiry,
+		Scope:     scope,
+	})
+
 	return athleteID, nil
 }
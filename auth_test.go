@@ -32,3 +32,17 @@ func TestClient_AuthCodeURL(t *testing.T) {
 	// assert
 	assert.Equal(t, want, got)
 }
+
+func TestClient_AuthCodeURLWithState(t *testing.T) {
+	// arrange
+	c := NewClient("client_id", "client_secret", "", nil)
+
+	// act
+	got := c.AuthCodeURLWithState("http://127.0.0.1:8089/exchange_token", nil, "random")
+
+	// assert
+	u, err := url.Parse(got)
+	assert.NoErr(t, err)
+	assert.Eq(t, "random", u.Query().Get("state"))
+	assert.Eq(t, "http://127.0.0.1:8089/exchange_token", u.Query().Get("redirect_uri"))
+}
@@ -0,0 +1,70 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var ErrBackfillNotFound = errors.New("backfill not found")
+
+// Backfill is the progress of fetching an athlete's history. The history between From and To is fetched
+// in windows of Window, newest first; Before moves back to From as windows are fetched.
+type Backfill struct {
+	AthleteID uint          `json:"athlete_id"`
+	From      time.Time     `json:"from"`
+	To        time.Time     `json:"to"`
+	Window    time.Duration `json:"window"`
+	// Before is the start of the last fetched window, history before it is pending.
+	Before time.Time `json:"before"`
+
+	Activities int `json:"activities"`
+	// Requests is the number of API requests spent.
+	Requests int `json:"requests"`
+
+	// LastError is the error of the last failed window, Abandoned is set if the athlete's
+	// token is missing or revoked.
+	LastError string `json:"last_error,omitempty"`
+	Abandoned bool   `json:"abandoned,omitempty"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Done reports whether the whole history was fetched.
+func (b *Backfill) Done() bool {
+	return !b.Before.After(b.From)
+}
+
+// Pending reports whether windows are left to fetch.
+func (b *Backfill) Pending() bool {
+	return !b.Done() && !b.Abandoned
+}
+
+// WindowsLeft returns the number of windows left to fetch.
+func (b *Backfill) WindowsLeft() int {
+	if b.Done() || b.Window <= 0 {
+		return 0
+	}
+
+	left := b.Before.Sub(b.From)
+	return int((left + b.Window - 1) / b.Window)
+}
+
+// WindowsDone returns the number of fetched windows.
+func (b *Backfill) WindowsDone() int {
+	if b.Window <= 0 {
+		return 0
+	}
+
+	done := b.To.Sub(b.Before)
+	return int((done + b.Window - 1) / b.Window)
+}
+
+// BackfillStore persists the Backfill of each athlete.
+type BackfillStore interface {
+	// GetBackfill returns ErrBackfillNotFound if no backfill was saved for the athlete.
+	GetBackfill(ctx context.Context, athleteID uint) (*Backfill, error)
+	SaveBackfill(ctx context.Context, backfill *Backfill) error
+	// ListBackfills returns the backfills of all athletes, including finished ones.
+	ListBackfills(ctx context.Context) ([]*Backfill, error)
+}
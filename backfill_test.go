@@ -0,0 +1,24 @@
+package strava
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestBackfill_Windows(t *testing.T) {
+	// arrange
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := &Backfill{From: from, To: from.Add(25 * time.Hour), Window: 10 * time.Hour, Before: from.Add(15 * time.Hour)}
+
+	// act & assert
+	assert.Eq(t, 1, b.WindowsDone())
+	assert.Eq(t, 2, b.WindowsLeft())
+	assert.True(t, b.Pending())
+
+	b.Before = from
+	assert.Eq(t, 3, b.WindowsDone())
+	assert.Eq(t, 0, b.WindowsLeft())
+	assert.False(t, b.Pending())
+}
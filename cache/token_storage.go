@@ -0,0 +1,314 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+const (
+	DefaultSize = 1000
+	DefaultTTL  = 5 * time.Minute
+)
+
+// TokenStorage is a read-through cache in front of another token storage.
+// Saves are written through, deletes invalidate the cached token.
+type TokenStorage struct {
+	next strava.TokenStorage
+	// namespace is the client ID of views returned by ForClient, which share the cache.
+	namespace string
+
+	*cache
+}
+
+var (
+	_ strava.TokenStorage    = (*TokenStorage)(nil)
+	_ strava.TokenLister     = (*TokenStorage)(nil)
+	_ strava.TokenDeleter    = (*TokenStorage)(nil)
+	_ strava.TokenLocker     = (*TokenStorage)(nil)
+	_ strava.TokenNamespacer = (*TokenStorage)(nil)
+)
+
+// cache is the LRU list shared by a storage and its namespaced views.
+type cache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mu      sync.Mutex
+	entries map[key]*list.Element
+	lru     *list.List
+	// fills are the keys read from the storage at the moment.
+	fills map[key]*fill
+
+	hits      atomic.Uint64
+	misses    atomic.Uint64
+	evictions atomic.Uint64
+}
+
+type key struct {
+	namespace string
+	athleteID uint
+}
+
+type entry struct {
+	key       key
+	token     *strava.Token
+	expiresAt time.Time
+}
+
+// fill counts the writes of a key while it's read from the storage, so a read started before a
+// concurrent Save doesn't replace the saved token with the one it read.
+type fill struct {
+	readers int
+	writes  uint64
+}
+
+type Option func(*TokenStorage)
+
+// WithSize sets the maximum number of cached tokens.
+func WithSize(n int) Option {
+	return func(ts *TokenStorage) {
+		if n > 0 {
+			ts.size = n
+		}
+	}
+}
+
+// WithTTL sets how long a token is served from the cache before it's read from the storage again.
+func WithTTL(d time.Duration) Option {
+	return func(ts *TokenStorage) {
+		ts.ttl = d
+	}
+}
+
+func NewTokenStorage(next strava.TokenStorage, opts ...Option) *TokenStorage {
+	ts := &TokenStorage{
+		next: next,
+		cache: &cache{
+			size:    DefaultSize,
+			ttl:     DefaultTTL,
+			now:     time.Now,
+			entries: make(map[key]*list.Element),
+			lru:     list.New(),
+			fills:   make(map[key]*fill),
+		},
+	}
+
+	for _, opt := range opts {
+		opt(ts)
+	}
+
+	return ts
+}
+
+// ForClient returns a view of the storage's namespace. Views share the cache, its size and its stats.
+func (ts *TokenStorage) ForClient(clientID string) strava.TokenStorage {
+	return &TokenStorage{
+		next:      strava.ForClient(ts.next, clientID),
+		namespace: clientID,
+		cache:     ts.cache,
+	}
+}
+
+// Stats are the cache's counters since its creation, shared with the views returned by ForClient.
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+func (ts *TokenStorage) Stats() Stats {
+	ts.mu.Lock()
+	size := ts.lru.Len()
+	ts.mu.Unlock()
+
+	return Stats{
+		Hits:      ts.hits.Load(),
+		Misses:    ts.misses.Load(),
+		Evictions: ts.evictions.Load(),
+		Size:      size,
+	}
+}
+
+func (ts *TokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	k := ts.key(athleteID)
+	if token, ok := ts.load(k); ok {
+		ts.hits.Add(1)
+		return token, nil
+	}
+	ts.misses.Add(1)
+
+	writes := ts.startFill(k)
+	token, err := ts.next.Get(ctx, athleteID)
+	if err != nil {
+		ts.endFill(k, writes, nil)
+		return nil, err
+	}
+
+	ts.endFill(k, writes, token)
+	return token.Clone(), nil
+}
+
+func (ts *TokenStorage) Save(ctx context.Context, token *strava.Token) error {
+	if err := ts.next.Save(ctx, token); err != nil {
+		// The storage may or may not have the token now.
+		ts.Invalidate(token.AthleteID)
+		return err
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	k := ts.key(token.AthleteID)
+	ts.written(k)
+	ts.store(k, token)
+	return nil
+}
+
+func (ts *TokenStorage) Delete(ctx context.Context, athleteID uint) error {
+	deleter, ok := ts.next.(strava.TokenDeleter)
+	if !ok {
+		return fmt.Errorf("token storage %T doesn't implement TokenDeleter", ts.next)
+	}
+
+	defer ts.Invalidate(athleteID)
+	return deleter.Delete(ctx, athleteID)
+}
+
+// List always reads from the storage.
+func (ts *TokenStorage) List(ctx context.Context) ([]*strava.Token, error) {
+	lister, ok := ts.next.(strava.TokenLister)
+	if !ok {
+		return nil, fmt.Errorf("token storage %T doesn't implement TokenLister", ts.next)
+	}
+
+	return lister.List(ctx)
+}
+
+// LockToken locks the token in the storage if it supports locking. The cached token is dropped
+// once the lock is acquired, as another process may have refreshed it.
+func (ts *TokenStorage) LockToken(ctx context.Context, athleteID uint) (func() error, error) {
+	locker, ok := ts.next.(strava.TokenLocker)
+	if !ok {
+		return func() error { return nil }, nil
+	}
+
+	unlock, err := locker.LockToken(ctx, athleteID)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.Invalidate(athleteID)
+	return unlock, nil
+}
+
+// Invalidate drops the athlete's token from the cache.
+func (ts *TokenStorage) Invalidate(athleteID uint) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	k := ts.key(athleteID)
+	ts.written(k)
+	if el, ok := ts.entries[k]; ok {
+		ts.remove(el)
+	}
+}
+
+func (ts *TokenStorage) key(athleteID uint) key {
+	return key{namespace: ts.namespace, athleteID: athleteID}
+}
+
+func (c *cache) load(k key) (*strava.Token, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[k]
+	if !ok {
+		return nil, false
+	}
+
+	e := el.Value.(*entry)
+	if c.ttl > 0 && !c.now().Before(e.expiresAt) {
+		c.remove(el)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(el)
+	return e.token.Clone(), true
+}
+
+// startFill registers a read of the key from the storage and returns the number of its writes so far.
+func (c *cache) startFill(k key) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f, ok := c.fills[k]
+	if !ok {
+		f = &fill{}
+		c.fills[k] = f
+	}
+	f.readers++
+
+	return f.writes
+}
+
+// endFill caches the token read from the storage, unless the key was written since startFill.
+func (c *cache) endFill(k key, writes uint64, token *strava.Token) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f := c.fills[k]
+	f.readers--
+	if f.readers == 0 {
+		delete(c.fills, k)
+	}
+
+	if token != nil && f.writes == writes {
+		c.store(k, token)
+	}
+}
+
+// written marks a write of the key for the reads in progress. c.mu must be held.
+func (c *cache) written(k key) {
+	if f, ok := c.fills[k]; ok {
+		f.writes++
+	}
+}
+
+// store caches the token. c.mu must be held.
+func (c *cache) store(k key, token *strava.Token) {
+	e := &entry{
+		key:       k,
+		token:     token.Clone(),
+		expiresAt: c.now().Add(c.ttl),
+	}
+
+	if el, ok := c.entries[k]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+
+	c.entries[k] = c.lru.PushFront(e)
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+		c.evictions.Add(1)
+	}
+}
+
+func (c *cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*entry).key)
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/inmemory"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestTokenStorage(t *testing.T) {
+	storagetest.RunTokenStorageSuite(t, func(t *testing.T) strava.TokenStorage {
+		return NewTokenStorage(&inmemory.TokenStorage{})
+	})
+}
+
+func TestTokenStorage_Stats(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	now := time.Now()
+	next := &inmemory.TokenStorage{}
+	for i := uint(1); i <= 3; i++ {
+		_ = next.Save(ctx, storagetest.NewToken(i))
+	}
+
+	ts := NewTokenStorage(next, WithSize(2), WithTTL(time.Minute))
+	ts.now = func() time.Time { return now }
+
+	// act
+	_, _ = ts.Get(ctx, 1) // miss
+	_, _ = ts.Get(ctx, 1) // hit
+	_, _ = ts.Get(ctx, 2) // miss
+	_, _ = ts.Get(ctx, 3) // miss, evicts 1
+	_, _ = ts.Get(ctx, 1) // miss, evicts 2
+	now = now.Add(time.Hour)
+	_, _ = ts.Get(ctx, 3) // miss, expired
+
+	// assert
+	assert.Eq(t, Stats{Hits: 1, Misses: 5, Evictions: 2, Size: 2}, ts.Stats())
+}
+
+func TestTokenStorage_ForClient(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	now := time.Now()
+	ts := NewTokenStorage(&inmemory.TokenStorage{}, WithSize(10))
+	ts.now = func() time.Time { return now }
+
+	a := ts.ForClient("a").(*TokenStorage)
+	b := ts.ForClient("b").(*TokenStorage)
+	again := ts.ForClient("a").(*TokenStorage)
+
+	// act
+	errA := a.Save(ctx, storagetest.NewToken(1))
+	_, errB := b.Get(ctx, 1)         // miss
+	_, errAgain := again.Get(ctx, 1) // hit of the token saved by a
+
+	// assert
+	assert.NoErr(t, errA)
+	assert.ErrIs(t, errB, strava.ErrTokenNotFound)
+	assert.NoErr(t, errAgain)
+	assert.Eq(t, Stats{Hits: 1, Misses: 1, Size: 1}, ts.Stats())
+	assert.Eq(t, ts.Stats(), a.Stats())
+
+	// act: invalidating one view reaches the others
+	a.Invalidate(1)
+
+	// assert
+	_, ok := again.load(again.key(1))
+	assert.False(t, ok)
+	assert.Eq(t, 0, ts.Stats().Size)
+}
+
+// blockingTokenStorage returns the token it held when Get was called once release is closed.
+type blockingTokenStorage struct {
+	strava.TokenStorage
+	reading chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingTokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token, error) {
+	token, err := s.TokenStorage.Get(ctx, athleteID)
+	close(s.reading)
+	<-s.release
+	return token, err
+}
+
+func TestTokenStorage_GetConcurrentSave(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	next := &inmemory.TokenStorage{}
+	old := storagetest.NewToken(1)
+	_ = next.Save(ctx, old)
+
+	blocking := &blockingTokenStorage{TokenStorage: next, reading: make(chan struct{}), release: make(chan struct{})}
+	ts := NewTokenStorage(blocking)
+
+	saved := storagetest.NewToken(1)
+	saved.AccessToken = "saved"
+
+	// act: the read of the old token completes after the save
+	done := make(chan error)
+	go func() {
+		_, err := ts.Get(ctx, 1)
+		done <- err
+	}()
+	<-blocking.reading
+	errSave := ts.Save(ctx, saved)
+	close(blocking.release)
+	errGet := <-done
+
+	// assert
+	assert.NoErr(t, errSave)
+	assert.NoErr(t, errGet)
+
+	token, ok := ts.load(ts.key(1))
+	assert.True(t, ok)
+	assert.Eq(t, "saved", token.AccessToken)
+}
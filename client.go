@@ -3,13 +3,16 @@ package strava
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/http/httputil"
+	"net/netip"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/oauth2"
@@ -28,6 +31,58 @@ type TokenStorage interface {
 	Save(ctx context.Context, token *Token) error
 }
 
+// TokenNamespacer is implemented by token storages that can keep tokens of several Strava applications apart.
+type TokenNamespacer interface {
+	// ForClient returns a view of the storage holding the tokens of the application with the given client ID.
+	ForClient(clientID string) TokenStorage
+}
+
+// ForClient returns the view of ts for the application with the given client ID. If ts doesn't
+// implement TokenNamespacer, the returned storage fails with ErrNamespacesUnsupported.
+func ForClient(ts TokenStorage, clientID string) TokenStorage {
+	if ns, ok := ts.(TokenNamespacer); ok {
+		return ns.ForClient(clientID)
+	}
+
+	return unsupportedNamespace{ts}
+}
+
+type unsupportedNamespace struct {
+	ts TokenStorage
+}
+
+func (u unsupportedNamespace) Get(context.Context, uint) (*Token, error) {
+	return nil, fmt.Errorf("%T: %w", u.ts, ErrNamespacesUnsupported)
+}
+
+func (u unsupportedNamespace) Save(context.Context, *Token) error {
+	return fmt.Errorf("%T: %w", u.ts, ErrNamespacesUnsupported)
+}
+
+// TokenDeleter is implemented by token storages that can delete tokens.
+// Deleting a missing token is not an error.
+type TokenDeleter interface {
+	Delete(ctx context.Context, athleteID uint) error
+}
+
+// TokenLocker is implemented by token storages shared between processes.
+// The client holds the lock while refreshing a token, so replicas don't refresh it concurrently.
+type TokenLocker interface {
+	LockToken(ctx context.Context, athleteID uint) (unlock func() error, err error)
+}
+
+// TokenLister is implemented by token storages that can enumerate all stored tokens.
+type TokenLister interface {
+	List(ctx context.Context) ([]*Token, error)
+}
+
+// TokenPager is implemented by token storages that can enumerate their tokens page by page,
+// so large storages aren't held in memory. MigrateTokens prefers it over TokenLister.
+type TokenPager interface {
+	// ListAfter returns up to limit tokens of athletes with IDs above afterID, ordered by athlete ID.
+	ListAfter(ctx context.Context, afterID uint, limit int) ([]*Token, error)
+}
+
 type Option func(*Client)
 
 func NewClient(id, secret, redirectURL string, ts TokenStorage, opts ...Option) *Client {
@@ -47,35 +102,97 @@ func NewClient(id, secret, redirectURL string, ts TokenStorage, opts ...Option)
 		tstore: ts,
 		lmt:    nil,
 		logger: slog.Default(),
+
+		webhookMaxBodySize: DefaultWebhookMaxBodySize,
 	}
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+	c.dispatcher = newDispatcher(c)
 
 	for _, opt := range opts {
 		opt(c)
 	}
+	c.dispatcher.init()
+
+	if !c.hooksSet {
+		c.hooks = NewSlogTokenHooks(c.logger)
+	}
+
+	if c.namespaceTokens {
+		if _, ok := ts.(TokenNamespacer); !ok {
+			c.err = fmt.Errorf("token namespace: %T: %w", ts, ErrNamespacesUnsupported)
+			c.logger.Error("invalid strava client options", slog.String("clientID", id), slog.Any("error", c.err))
+		}
+		c.tstore = ForClient(ts, id)
+	}
 
 	return c
 }
 
+// Err returns the error of invalid options, e.g. WithTokenNamespace with a token storage that doesn't
+// implement TokenNamespacer. Token requests of such a client fail.
+func (c *Client) Err() error {
+	return c.err
+}
+
 type Client struct {
-	transport *http.Transport
+	transport http.RoundTripper
+
+	oacfg           oauth2.Config
+	tstore          TokenStorage
+	namespaceTokens bool
+	// err is the error of invalid options.
+	err error
 
-	oacfg  oauth2.Config
-	tstore TokenStorage
+	lmt       *rate.Limiter
+	rateLimit atomic.Pointer[RateLimit]
 
-	lmt *rate.Limiter
+	hooks    TokenHooks
+	hooksSet bool
 
 	maxRetries uint
 	retryDelay time.Duration
 
 	webhookCallbackURL string
-	subscriptionID     uint
-	eventHandlers      []EventHandler
-	eventHandlersLock  sync.RWMutex
+	subscriptionID     atomic.Uint64
+	webhookState       WebhookStateStore
+	verifyToken        string
+	verifyTokenMu      sync.Mutex
+
+	webhookAllowedIPs     []netip.Prefix
+	webhookClientIPHeader string
+	webhookMaxBodySize    int64
+	eventHandlers         []EventHandler
+	eventHandlersLock     sync.RWMutex
+	dispatcher            *dispatcher
+	seen                  SeenStore
+	seenTTL               time.Duration
+	eventStats            eventStats
+
+	deleteDeauthorizedTokens bool
+	onDeauthorized           DeauthorizedHandler
+
+	// ctx is the client's lifecycle context passed to event handlers, canceled on Shutdown.
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	logger *slog.Logger
 	debug  bool
 }
 
+// Shutdown stops accepting webhook events and waits until the queued and in-flight ones are handled.
+// If ctx is done first, the context passed to the handlers is canceled and ctx's error is returned.
+func (c *Client) Shutdown(ctx context.Context) error {
+	err := c.dispatcher.shutdown(ctx)
+	c.cancel()
+
+	return err
+}
+
+// ClientID returns the client ID of the Strava application, which is also its application ID.
+func (c *Client) ClientID() string {
+	return c.oacfg.ClientID
+}
+
 func (c *Client) call(ctx context.Context, athleteID uint, req *http.Request, retries uint) ([]byte, error) {
 	if c.lmt != nil && !c.lmt.Allow() {
 		c.logger.Warn("rate limit exceeded: waiting...")
@@ -114,6 +231,8 @@ func (c *Client) call(ctx context.Context, athleteID uint, req *http.Request, re
 	}
 	defer resp.Body.Close()
 
+	c.updateRateLimit(resp.Header)
+
 	if c.debug {
 		respDump, err := httputil.DumpResponse(resp, true)
 		if err != nil {
@@ -129,14 +248,14 @@ func (c *Client) call(ctx context.Context, athleteID uint, req *http.Request, re
 	}
 
 	if resp.StatusCode >= http.StatusBadRequest {
-		var details any = body
+		serr := &StatusError{StatusCode: resp.StatusCode, Body: body}
 
-		var apiErr Fault
-		if err := json.Unmarshal(body, &apiErr); err == nil {
-			details = apiErr
+		var fault Fault
+		if err := json.Unmarshal(body, &fault); err == nil {
+			serr.Fault = &fault
 		}
 
-		return nil, fmt.Errorf("API error (status code %d): %v", resp.StatusCode, details)
+		return nil, serr
 	}
 
 	return body, nil
@@ -163,13 +282,7 @@ func (c *Client) getHttpClientFor(ctx context.Context, athleteID uint) (*http.Cl
 		return nil, err
 	}
 
-	if c.transport != nil {
-		ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
-			Transport: c.transport,
-		})
-	}
-
-	hc := c.oacfg.Client(ctx, token)
+	hc := c.oacfg.Client(c.oauthContext(ctx), token)
 	hc.Timeout = HTTPClientTimeout
 	return hc, nil
 }
@@ -180,18 +293,142 @@ func (c *Client) token(ctx context.Context, athleteID uint) (*oauth2.Token, erro
 		return nil, fmt.Errorf("get token from %T: %w", c.tstore, err)
 	}
 
+	if token.Revoked {
+		return nil, ErrTokenRevoked
+	}
+
 	if !token.Valid() {
-		oauthToken, err := c.oacfg.TokenSource(ctx, token.Token).Token()
+		token, err = c.lockedRefreshToken(ctx, athleteID, false)
 		if err != nil {
-			return nil, fmt.Errorf("refresh token: %w", err)
+			return nil, err
 		}
-		token.Token = oauthToken
+	}
+
+	return token.Token, nil
+}
+
+// RefreshToken refreshes the athlete's token regardless of its expiry and saves the result.
+func (c *Client) RefreshToken(ctx context.Context, athleteID uint) (*Token, error) {
+	return c.lockedRefreshToken(ctx, athleteID, true)
+}
 
-		err = c.tstore.Save(ctx, token)
+// lockedRefreshToken refreshes the token holding the storage's lock if it supports locking.
+// Unless forced, the token isn't refreshed if another process refreshed it in the meantime.
+func (c *Client) lockedRefreshToken(ctx context.Context, athleteID uint, force bool) (*Token, error) {
+	if locker, ok := c.tstore.(TokenLocker); ok {
+		unlock, err := locker.LockToken(ctx, athleteID)
 		if err != nil {
-			return nil, fmt.Errorf("save token to %T: %w", c.tstore, err)
+			return nil, fmt.Errorf("lock token in %T: %w", c.tstore, err)
 		}
+		defer func() {
+			if err := unlock(); err != nil {
+				c.logger.WarnContext(ctx, "unlock token", slog.Uint64("athleteID", uint64(athleteID)), slog.Any("error", err))
+			}
+		}()
 	}
 
-	return token.Token, nil
+	token, err := c.tstore.Get(ctx, athleteID)
+	if err != nil {
+		return nil, fmt.Errorf("get token from %T: %w", c.tstore, err)
+	}
+
+	if token.Revoked {
+		return nil, ErrTokenRevoked
+	}
+
+	if !force && token.Valid() {
+		return token, nil
+	}
+
+	return c.refreshToken(ctx, token)
+}
+
+func (c *Client) refreshToken(ctx context.Context, token *Token) (*Token, error) {
+	event := TokenEvent{
+		AthleteID: token.AthleteID,
+		OldExpiry: token.Expiry,
+		Scope:     token.Scope,
+	}
+
+	// Only pass the refresh token, so the token source can't reuse a still valid access token.
+	expired := &oauth2.Token{RefreshToken: token.RefreshToken}
+
+	oauthToken, err := c.oacfg.TokenSource(c.oauthContext(ctx), expired).Token()
+	if err != nil {
+		event.Err = err
+
+		if !isRevokedTokenError(err) {
+			c.hooks.refreshFailed(ctx, event)
+			return nil, fmt.Errorf("refresh token: %w", err)
+		}
+
+		revoked := *token
+		revoked.Revoked = true
+		if err := c.tstore.Save(ctx, &revoked); err != nil {
+			return nil, fmt.Errorf("save revoked token to %T: %w", c.tstore, err)
+		}
+
+		c.hooks.tokenRevoked(ctx, event)
+		return nil, fmt.Errorf("refresh token: %w", ErrTokenRevoked)
+	}
+
+	refreshed := &Token{
+		Token:     oauthToken,
+		AthleteID: token.AthleteID,
+		Scope:     token.Scope,
+		Raw:       tokenRaw(token.Raw, oauthToken),
+	}
+
+	if err := c.tstore.Save(ctx, refreshed); err != nil {
+		event.Err = err
+		c.hooks.refreshFailed(ctx, event)
+		return nil, fmt.Errorf("save token to %T: %w", c.tstore, err)
+	}
+
+	event.NewExpiry = refreshed.Expiry
+	c.hooks.tokenRefreshed(ctx, event)
+
+	return refreshed, nil
+}
+
+// oauthContext makes the oauth2 package use the client's transport for token requests.
+func (c *Client) oauthContext(ctx context.Context) context.Context {
+	if c.transport == nil {
+		return ctx
+	}
+
+	return context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: c.transport,
+		Timeout:   HTTPClientTimeout,
+	})
+}
+
+// isRevokedTokenError reports whether the token endpoint rejected the refresh token itself.
+func isRevokedTokenError(err error) bool {
+	var rerr *oauth2.RetrieveError
+	if !errors.As(err, &rerr) {
+		return false
+	}
+
+	if rerr.ErrorCode == "invalid_grant" {
+		return true
+	}
+
+	if rerr.Response == nil || rerr.Response.StatusCode != http.StatusBadRequest {
+		return false
+	}
+
+	// Strava doesn't follow RFC 6749 here and responds with its own fault format.
+	var fault Fault
+	if err := json.Unmarshal(rerr.Body, &fault); err != nil {
+		return false
+	}
+
+	for _, e := range fault.Errors {
+		if e.Field == "refresh_token" && e.Code == "invalid" {
+			return true
+		}
+	}
+
+	return false
 }
@@ -0,0 +1,185 @@
+// Command strava-webhook-sim sends Strava's push subscription requests to a local webhook callback.
+//
+// Usage:
+//
+//	strava-webhook-sim validate --url http://localhost:8000/callback --verify-token secret
+//	strava-webhook-sim send --url http://localhost:8000/callback --subscription-id 1 --type update --athlete 7 --activity 1 --title "Morning Ride"
+//	strava-webhook-sim replay --url http://localhost:8000/callback --subscription-id 1 --file events.jsonl --rate 5
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/webhooksim"
+)
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "validate":
+		err = validate(ctx, os.Args[2:])
+	case "send":
+		err = send(ctx, os.Args[2:])
+	case "replay":
+		err = replay(ctx, os.Args[2:])
+	case "help", "-h", "--help":
+		usage()
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, `Usage: %s <command> [flags]
+
+Commands:
+  validate  perform the subscription validation handshake
+  send      send a synthetic event
+  replay    send the events of a JSON lines file
+`, os.Args[0])
+}
+
+// simulatorFlags adds the flags shared by all commands.
+func simulatorFlags(fs *flag.FlagSet) func() (*webhooksim.Simulator, error) {
+	callbackURL := fs.String("url", "http://localhost:8000/callback", "webhook callback URL")
+	verifyToken := fs.String("verify-token", "", "verify token of the client, see strava.WithWebhookVerifyToken")
+	subscriptionID := fs.Uint("subscription-id", 0, "subscription ID set in sent events, see strava.WithWebhookSubscriptionID")
+
+	return func() (*webhooksim.Simulator, error) {
+		if *callbackURL == "" {
+			return nil, fmt.Errorf("--url is required")
+		}
+
+		return webhooksim.New(*callbackURL,
+			webhooksim.WithVerifyToken(*verifyToken),
+			webhooksim.WithSubscriptionID(*subscriptionID),
+		), nil
+	}
+}
+
+func validate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	simulator := simulatorFlags(fs)
+	_ = fs.Parse(args)
+
+	sim, err := simulator()
+	if err != nil {
+		return err
+	}
+
+	if err := sim.Validate(ctx); err != nil {
+		return err
+	}
+
+	fmt.Println("validation succeeded")
+	return nil
+}
+
+func send(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("send", flag.ExitOnError)
+	simulator := simulatorFlags(fs)
+	eventType := fs.String("type", "create", "event type: create, update, delete or deauthorize")
+	athleteID := fs.Uint("athlete", 0, "athlete ID")
+	activityID := fs.Uint("activity", 0, "activity ID")
+	title := fs.String("title", "", "changed activity title of update events")
+	activityType := fs.String("activity-type", "", "changed activity type of update events")
+	private := fs.String("private", "", "changed visibility of update events: true or false")
+	_ = fs.Parse(args)
+
+	sim, err := simulator()
+	if err != nil {
+		return err
+	}
+
+	if *athleteID == 0 {
+		return fmt.Errorf("--athlete is required")
+	}
+	if *eventType != "deauthorize" && *activityID == 0 {
+		return fmt.Errorf("--activity is required for %s events", *eventType)
+	}
+
+	var event strava.Event
+	switch *eventType {
+	case "create":
+		event = webhooksim.ActivityCreate(*athleteID, *activityID)
+	case "update":
+		var p *bool
+		switch *private {
+		case "":
+		case "true", "false":
+			v := *private == "true"
+			p = &v
+		default:
+			return fmt.Errorf("invalid --private %q", *private)
+		}
+
+		event = webhooksim.ActivityUpdate(*athleteID, *activityID, webhooksim.ActivityUpdates(*title, *activityType, p))
+	case "delete":
+		event = webhooksim.ActivityDelete(*athleteID, *activityID)
+	case "deauthorize":
+		event = webhooksim.Deauthorize(*athleteID)
+	default:
+		return fmt.Errorf("unknown event type %q", *eventType)
+	}
+
+	if err := sim.Send(ctx, event); err != nil {
+		return err
+	}
+
+	fmt.Printf("sent %s %s event\n", event.ObjectType, event.AspectType)
+	return nil
+}
+
+func replay(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("replay", flag.ExitOnError)
+	simulator := simulatorFlags(fs)
+	file := fs.String("file", "", "JSON lines file with one event per line, - for stdin")
+	perSecond := fs.Float64("rate", 1, "events per second, 0 for no limit")
+	_ = fs.Parse(args)
+
+	sim, err := simulator()
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		fs.Usage()
+		return fmt.Errorf("--file is required")
+	}
+
+	r := os.Stdin
+	if *file != "-" {
+		f, err := os.Open(*file)
+		if err != nil {
+			return fmt.Errorf("open events: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	sent, err := sim.Replay(ctx, r, *perSecond)
+	fmt.Printf("sent %d events\n", sent)
+	return err
+}
@@ -0,0 +1,325 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/export"
+)
+
+func athleteShow(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("athlete show", flag.ExitOnError)
+	open := sessionFlags(fs)
+	_ = fs.Parse(args)
+
+	s, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.close() }()
+
+	athlete, err := s.c.GetAthlete(ctx, s.athleteID)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(athlete)
+}
+
+func activitiesList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("activities list", flag.ExitOnError)
+	open := sessionFlags(fs)
+	after := fs.String("after", "", "list activities started after this date, YYYY-MM-DD or RFC 3339")
+	before := fs.String("before", "", "list activities started before this date, defaults to now")
+	format := fs.String("format", formatTable, "output format: json, table or csv")
+	_ = fs.Parse(args)
+
+	if err := checkFormat(*format, formatJSON, formatTable, formatCSV); err != nil {
+		return err
+	}
+
+	from, err := parseDate(*after)
+	if err != nil {
+		return err
+	}
+	if from.IsZero() {
+		from = time.Unix(0, 0)
+	}
+
+	to, err := parseDate(*before)
+	if err != nil {
+		return err
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	s, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.close() }()
+
+	print, done := activityPrinter(*format, s.athleteID)
+
+	if err := s.c.GetSummaryActivitiesWithCallback(ctx, s.athleteID, from, to, print); err != nil {
+		return err
+	}
+
+	return done()
+}
+
+// activityPrinter prints pages of activities in the format as they arrive, so long histories aren't held
+// in memory. done finishes the output.
+func activityPrinter(format string, athleteID uint) (print func(page []*strava.SummaryActivity) error, done func() error) {
+	switch format {
+	case formatJSON:
+		arr := &jsonArray{}
+		print = func(page []*strava.SummaryActivity) error {
+			for _, a := range page {
+				if err := arr.print(a); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		return print, arr.close
+	case formatCSV:
+		w := export.NewCSVWriter[export.Activity](stdout)
+		print = func(page []*strava.SummaryActivity) error {
+			records := make([]export.Activity, 0, len(page))
+			for _, a := range page {
+				records = append(records, export.ActivityRecord(athleteID, a))
+			}
+			_, err := w.Write(records)
+			return err
+		}
+		return print, w.Close
+	}
+
+	w := newTable("ID\tSTART\tTYPE\tNAME\tDISTANCE\tMOVING TIME")
+	print = func(page []*strava.SummaryActivity) error {
+		for _, a := range page {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n", a.ID, a.StartDateLocal.Format("2006-01-02 15:04"), a.SportType, a.Name, formatDistance(a.Distance), formatDuration(a.MovingTime))
+		}
+		return nil
+	}
+	return print, w.Flush
+}
+
+// activityFlags adds the flags of commands on one activity.
+func activityFlags(fs *flag.FlagSet) (func(ctx context.Context) (*session, error), *uint) {
+	open := sessionFlags(fs)
+	activityID := fs.Uint("id", 0, "activity ID")
+
+	return func(ctx context.Context) (*session, error) {
+		if *activityID == 0 {
+			return nil, fmt.Errorf("--id is required")
+		}
+		return open(ctx)
+	}, activityID
+}
+
+func activityShow(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("activity show", flag.ExitOnError)
+	open, activityID := activityFlags(fs)
+	_ = fs.Parse(args)
+
+	s, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.close() }()
+
+	activity, err := s.c.GetDetailedActivity(ctx, s.athleteID, *activityID)
+	if err != nil {
+		return err
+	}
+
+	return printJSON(activity)
+}
+
+func activityLaps(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("activity laps", flag.ExitOnError)
+	open, activityID := activityFlags(fs)
+	format := fs.String("format", formatTable, "output format: json, table or csv")
+	_ = fs.Parse(args)
+
+	if err := checkFormat(*format, formatJSON, formatTable, formatCSV); err != nil {
+		return err
+	}
+
+	s, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.close() }()
+
+	laps, err := s.c.GetActivityLaps(ctx, s.athleteID, *activityID)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case formatJSON:
+		return printJSON(laps)
+	case formatCSV:
+		return printCSV(export.LapRecords(s.athleteID, *activityID, laps))
+	}
+
+	w := newTable("LAP\tNAME\tDISTANCE\tMOVING TIME\tPACE\tHEARTRATE")
+	for _, l := range laps {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s/km\t%.0f\n", l.LapIndex, l.Name, formatDistance(l.Distance), formatDuration(l.MovingTime), l.AveragePace(), l.AverageHeartrate)
+	}
+	return w.Flush()
+}
+
+func activityStreams(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("activity streams", flag.ExitOnError)
+	open, activityID := activityFlags(fs)
+	types := fs.String("types", "", "comma-separated stream types, defaults to all")
+	format := fs.String("format", formatCSV, "output format: json or csv")
+	_ = fs.Parse(args)
+
+	if err := checkFormat(*format, formatJSON, formatCSV); err != nil {
+		return err
+	}
+
+	streamTypes := export.DefaultStreams
+	if *types != "" {
+		streamTypes = []strava.StreamType{strava.StreamTypeTime}
+		for _, t := range strings.Split(*types, ",") {
+			streamTypes = append(streamTypes, strava.StreamType(strings.TrimSpace(t)))
+		}
+	}
+
+	s, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.close() }()
+
+	streams, err := s.c.GetActivityStreams(ctx, s.athleteID, *activityID, streamTypes...)
+	if err != nil {
+		return err
+	}
+
+	if *format == formatJSON {
+		return printJSON(streams)
+	}
+
+	// The samples are timed from the start of the activity.
+	activity, err := s.c.GetDetailedActivity(ctx, s.athleteID, *activityID)
+	if err != nil {
+		return err
+	}
+
+	return printCSV(export.SampleRecords(s.athleteID, &activity.SummaryActivity, streams))
+}
+
+func activityUpdate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("activity update", flag.ExitOnError)
+	open, activityID := activityFlags(fs)
+	name := fs.String("name", "", "new name")
+	description := fs.String("description", "", "new description")
+	_ = fs.Parse(args)
+
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+	if !set["name"] && !set["description"] {
+		return fmt.Errorf("--name or --description is required")
+	}
+
+	s, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.close() }()
+
+	// The API sets both, so keep the one not given.
+	if !set["name"] || !set["description"] {
+		activity, err := s.c.GetDetailedActivity(ctx, s.athleteID, *activityID)
+		if err != nil {
+			return err
+		}
+		if !set["name"] {
+			*name = activity.Name
+		}
+		if !set["description"] {
+			*description = activity.Description
+		}
+	}
+
+	if err := s.c.UpdateActivity(ctx, s.athleteID, *activityID, *name, *description); err != nil {
+		return err
+	}
+
+	fmt.Printf("updated activity %d\n", *activityID)
+	return nil
+}
+
+func upload(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("upload", flag.ExitOnError)
+	open := sessionFlags(fs)
+	file := fs.String("file", "", "FIT, TCX or GPX file, optionally gzipped")
+	var params strava.UploadParams
+	fs.StringVar(&params.DataType, "data-type", "", "fit, tcx or gpx with an optional .gz suffix, defaults to the file extension")
+	fs.StringVar(&params.Name, "name", "", "activity name")
+	fs.StringVar(&params.Description, "description", "", "activity description")
+	fs.BoolVar(&params.Trainer, "trainer", false, "mark the activity as done on a trainer")
+	fs.BoolVar(&params.Commute, "commute", false, "mark the activity as a commute")
+	fs.StringVar(&params.ExternalID, "external-id", "", "identifier of the file in your system")
+	wait := fs.Duration("wait", 2*time.Minute, "wait this long for the upload to be processed, 0 to return immediately")
+	_ = fs.Parse(args)
+
+	if *file == "" {
+		fs.Usage()
+		return fmt.Errorf("--file is required")
+	}
+
+	f, err := os.Open(*file)
+	if err != nil {
+		return fmt.Errorf("open file: %w", err)
+	}
+	defer f.Close()
+
+	s, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.close() }()
+
+	u, err := s.c.UploadActivity(ctx, s.athleteID, *file, f, params)
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(*wait)
+	for !u.Done() && time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+
+		if u, err = s.c.GetUpload(ctx, s.athleteID, u.ID); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case u.Error != "":
+		return fmt.Errorf("upload %d failed: %s", u.ID, u.Error)
+	case u.ActivityID != 0:
+		fmt.Printf("uploaded activity %d\n", u.ActivityID)
+	default:
+		fmt.Printf("upload %d: %s\n", u.ID, u.Status)
+	}
+
+	return nil
+}
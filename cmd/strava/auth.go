@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/marvell/strava-go"
+)
+
+// authLogin runs the OAuth flow with a redirect to a loopback server, which Strava allows for any application.
+func authLogin(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("auth login", flag.ExitOnError)
+	client := clientFlags(fs)
+	storage := storageFlag(fs)
+	port := fs.Int("port", 8089, "port of the loopback server receiving the redirect")
+	scope := fs.String("scope", "read,activity:read_all", "comma-separated scopes to request")
+	_ = fs.Parse(args)
+
+	ts, closeTS, err := storage()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeTS() }()
+
+	// Strava expects comma-separated scopes, while oauth2 would join them with spaces.
+	c, err := client(ts, strava.WithScopes(*scope))
+	if err != nil {
+		return err
+	}
+
+	state, err := newState()
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", fmt.Sprintf("127.0.0.1:%d", *port))
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+
+	redirectURL := fmt.Sprintf("http://127.0.0.1:%d/exchange_token", *port)
+
+	results := make(chan loginResult, 1)
+
+	mux := http.NewServeMux()
+	mux.Handle("/exchange_token", loginHandler(c, state, results))
+
+	srv := &http.Server{Handler: mux}
+	go func() { _ = srv.Serve(ln) }()
+	defer func() { _ = srv.Shutdown(context.Background()) }()
+
+	fmt.Printf("Open this URL in your browser to authorize the application:\n\n  %s\n\n", c.AuthCodeURLWithState(redirectURL, nil, state))
+
+	select {
+	case res := <-results:
+		if res.err != nil {
+			return res.err
+		}
+
+		fmt.Printf("stored the token of athlete %d\n", res.athleteID)
+		return nil
+	case <-ctx.Done():
+		return errors.New("interrupted before the authorization completed")
+	}
+}
+
+type loginResult struct {
+	athleteID uint
+	err       error
+}
+
+// newState returns a random OAuth state, so only the redirect of this login is accepted.
+func newState() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate state: %w", err)
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// loginHandler exchanges the code of the redirect having state, and sends the result of the first
+// redirect to results.
+func loginHandler(c *strava.Client, state string, results chan<- loginResult) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+
+		var res loginResult
+		switch {
+		case q.Get("error") != "":
+			res.err = fmt.Errorf("authorization failed: %s", q.Get("error"))
+		case subtle.ConstantTimeCompare([]byte(q.Get("state")), []byte(state)) != 1:
+			res.err = errors.New("authorization failed: invalid state")
+		default:
+			res.athleteID, res.err = c.AuthExchangeCode(r.Context(), q.Get("code"), q.Get("scope"))
+		}
+
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintf(w, "Athlete %d authorized, you can close this window.\n", res.athleteID)
+		}
+
+		select {
+		case results <- res:
+		default:
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gookit/goutil/testutil/assert"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/inmemory"
+)
+
+// tokenEndpoint answers the token exchanges of a client.
+type tokenEndpoint struct {
+	exchanges int
+}
+
+func (e *tokenEndpoint) RoundTrip(r *http.Request) (*http.Response, error) {
+	e.exchanges++
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"token_type":"Bearer","access_token":"access","refresh_token":"refresh","expires_in":21600,"athlete":{"id":7}}`)),
+		Request:    r,
+	}, nil
+}
+
+func TestNewState(t *testing.T) {
+	// act
+	s1, err1 := newState()
+	s2, err2 := newState()
+
+	// assert
+	assert.NoErr(t, err1)
+	assert.NoErr(t, err2)
+	assert.Len(t, s1, 32)
+	assert.NotEq(t, s1, s2)
+}
+
+func TestLoginHandler(t *testing.T) {
+	tests := []struct {
+		name          string
+		query         url.Values
+		wantStatus    int
+		wantErr       string
+		wantExchanges int
+	}{
+		{
+			name:          "authorized",
+			query:         url.Values{"code": {"code"}, "scope": {"read"}, "state": {"state"}},
+			wantStatus:    http.StatusOK,
+			wantExchanges: 1,
+		},
+		{
+			name:       "invalid state",
+			query:      url.Values{"code": {"code"}, "scope": {"read"}, "state": {strava.OAuthStaticState}},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    "invalid state",
+		},
+		{
+			name:       "missing state",
+			query:      url.Values{"code": {"code"}, "scope": {"read"}},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    "invalid state",
+		},
+		{
+			name:       "denied",
+			query:      url.Values{"error": {"access_denied"}, "state": {"state"}},
+			wantStatus: http.StatusBadRequest,
+			wantErr:    "access_denied",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ts := &inmemory.TokenStorage{}
+			endpoint := &tokenEndpoint{}
+			c := strava.NewClient("client_id", "client_secret", "", ts, strava.WithTransport(endpoint))
+			results := make(chan loginResult, 1)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, "/exchange_token?"+tt.query.Encode(), nil)
+
+			// act
+			loginHandler(c, "state", results).ServeHTTP(rec, req)
+
+			// assert
+			assert.Eq(t, tt.wantStatus, rec.Code)
+			assert.Eq(t, tt.wantExchanges, endpoint.exchanges)
+
+			res := <-results
+			if tt.wantErr != "" {
+				assert.ErrSubMsg(t, res.err, tt.wantErr)
+				return
+			}
+			assert.NoErr(t, res.err)
+			assert.Eq(t, uint(7), res.athleteID)
+
+			token, err := ts.Get(context.Background(), 7)
+			assert.NoErr(t, err)
+			assert.Eq(t, "access", token.AccessToken)
+			assert.Eq(t, "read", token.Scope)
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Command strava performs everyday operations against the Strava API with tokens of a token storage.
+//
+// Credentials are taken from the STRAVA_ID and STRAVA_SECRET environment variables or the --client-id
+// and --client-secret flags, the storage from STRAVA_TOKEN_STORAGE or --storage.
+//
+// Usage:
+//
+//	strava auth login --scope read,activity:read_all
+//	strava activities list --after 2024-01-01 --format csv
+//	strava activity streams --id 123 --types heartrate,watts
+//	strava upload --file ride.fit --name "Morning Ride"
+//	strava webhook create --callback-url https://example.com/callback --verify-token secret
+//	strava tokens refresh --athlete 7
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/internal/storageurl"
+)
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, args []string) error
+}
+
+var commands = []command{
+	{"auth login", "authorize an athlete in the browser and store the token", authLogin},
+	{"athlete show", "show the athlete's profile", athleteShow},
+	{"activities list", "list the athlete's activities", activitiesList},
+	{"activity show", "show an activity", activityShow},
+	{"activity laps", "list the laps of an activity", activityLaps},
+	{"activity streams", "print the stream samples of an activity", activityStreams},
+	{"activity update", "change the name or description of an activity", activityUpdate},
+	{"upload", "upload an activity file", upload},
+	{"webhook list", "list the push subscriptions of the application", webhookList},
+	{"webhook create", "create a push subscription", webhookCreate},
+	{"webhook delete", "delete a push subscription", webhookDelete},
+	{"tokens list", "list the stored tokens", tokensList},
+	{"tokens refresh", "refresh a token", tokensRefresh},
+	{"tokens revoke", "revoke the application's access and delete the token", tokensRevoke},
+}
+
+func main() {
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "help", "-h", "--help":
+		usage()
+		return
+	}
+
+	cmd, args, ok := lookup(os.Args[1:])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", strings.Join(os.Args[1:min(3, len(os.Args))], " "))
+		usage()
+		os.Exit(2)
+	}
+
+	if err := cmd.run(ctx, args); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// lookup returns the command named by the first one or two arguments, and the remaining arguments.
+func lookup(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+
+	return command{}, nil, false
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-17s %s\n", cmd.name, cmd.usage)
+	}
+	fmt.Fprintf(os.Stderr, "\nStorage URLs: %s\n", storageurl.Usage)
+}
+
+// clientFlags adds the flags of the application's credentials.
+func clientFlags(fs *flag.FlagSet) func(ts strava.TokenStorage, opts ...strava.Option) (*strava.Client, error) {
+	clientID := fs.String("client-id", os.Getenv("STRAVA_ID"), "client ID of the application, defaults to $STRAVA_ID")
+	clientSecret := fs.String("client-secret", os.Getenv("STRAVA_SECRET"), "client secret of the application, defaults to $STRAVA_SECRET")
+	debug := fs.Bool("debug", false, "log requests and responses")
+
+	return func(ts strava.TokenStorage, opts ...strava.Option) (*strava.Client, error) {
+		if *clientID == "" || *clientSecret == "" {
+			return nil, fmt.Errorf("--client-id and --client-secret are required")
+		}
+
+		if *debug {
+			slog.SetLogLoggerLevel(slog.LevelDebug)
+			opts = append(opts, strava.WithDebug())
+		}
+
+		return strava.NewClient(*clientID, *clientSecret, "", ts, opts...), nil
+	}
+}
+
+// storageFlag adds the flag of the token storage. The returned function releases its resources.
+func storageFlag(fs *flag.FlagSet) func() (storageurl.Storage, func() error, error) {
+	storage := fs.String("storage", defaultStorage(), "token storage URL, defaults to $STRAVA_TOKEN_STORAGE")
+
+	return func() (storageurl.Storage, func() error, error) {
+		ts, closeTS, err := storageurl.Open(*storage)
+		if err != nil {
+			return nil, nil, fmt.Errorf("open storage: %w", err)
+		}
+		return ts, closeTS, nil
+	}
+}
+
+// defaultStorage returns $STRAVA_TOKEN_STORAGE, or a file storage in the user's config directory.
+func defaultStorage() string {
+	if v := os.Getenv("STRAVA_TOKEN_STORAGE"); v != "" {
+		return v
+	}
+
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "file://tokens"
+	}
+
+	return "file://" + filepath.Join(dir, "strava", "tokens")
+}
+
+// athleteFlag adds the flag of the athlete. Without it, the athlete of the only valid token in the storage is used.
+func athleteFlag(fs *flag.FlagSet) func(ctx context.Context, ts storageurl.Storage) (uint, error) {
+	def, _ := strconv.ParseUint(os.Getenv("STRAVA_ATHLETE_ID"), 10, 0)
+	athleteID := fs.Uint("athlete", uint(def), "athlete ID, defaults to $STRAVA_ATHLETE_ID or the only athlete in the storage")
+
+	return func(ctx context.Context, ts storageurl.Storage) (uint, error) {
+		if *athleteID != 0 {
+			return *athleteID, nil
+		}
+
+		tokens, err := ts.List(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("list tokens: %w", err)
+		}
+
+		var ids []uint
+		for _, token := range tokens {
+			if !token.Revoked {
+				ids = append(ids, token.AthleteID)
+			}
+		}
+
+		if len(ids) != 1 {
+			return 0, fmt.Errorf("--athlete is required, the storage has tokens of %d athletes", len(ids))
+		}
+
+		return ids[0], nil
+	}
+}
+
+// session is an opened token storage, a client using it and the athlete of the command.
+type session struct {
+	c         *strava.Client
+	ts        storageurl.Storage
+	athleteID uint
+	close     func() error
+}
+
+// sessionFlags adds the flags of commands calling the API on behalf of an athlete.
+func sessionFlags(fs *flag.FlagSet) func(ctx context.Context) (*session, error) {
+	client := clientFlags(fs)
+	storage := storageFlag(fs)
+	athlete := athleteFlag(fs)
+
+	return func(ctx context.Context) (*session, error) {
+		ts, closeTS, err := storage()
+		if err != nil {
+			return nil, err
+		}
+
+		c, err := client(ts)
+		if err != nil {
+			return nil, errors.Join(err, closeTS())
+		}
+
+		athleteID, err := athlete(ctx, ts)
+		if err != nil {
+			return nil, errors.Join(err, closeTS())
+		}
+
+		return &session{c: c, ts: ts, athleteID: athleteID, close: closeTS}, nil
+	}
+}
+
+// parseDate parses a date as YYYY-MM-DD in local time or RFC 3339. The zero time is returned for "".
+func parseDate(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.ParseInLocation(time.DateOnly, v, time.Local); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q, expected YYYY-MM-DD or RFC 3339", v)
+	}
+
+	return t, nil
+}
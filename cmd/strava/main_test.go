@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+
+	"github.com/marvell/strava-go"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantName string
+		wantArgs []string
+		wantOK   bool
+	}{
+		{args: []string{"auth", "login", "--port", "9000"}, wantName: "auth login", wantArgs: []string{"--port", "9000"}, wantOK: true},
+		{args: []string{"upload", "--file", "ride.fit"}, wantName: "upload", wantArgs: []string{"--file", "ride.fit"}, wantOK: true},
+		{args: []string{"activity", "laps"}, wantName: "activity laps", wantArgs: []string{}, wantOK: true},
+		{args: []string{"activity"}},
+		{args: []string{"activity", "delete"}},
+		{args: []string{"auth", "--port", "9000"}},
+		{args: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			// act
+			cmd, args, ok := lookup(tt.args)
+
+			// assert
+			assert.Eq(t, tt.wantOK, ok)
+			assert.Eq(t, tt.wantName, cmd.name)
+			if tt.wantOK {
+				assert.Eq(t, tt.wantArgs, args)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: ""},
+		{value: "2024-03-01", want: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)},
+		{value: "2024-03-01T06:30:00Z", want: time.Date(2024, 3, 1, 6, 30, 0, 0, time.UTC)},
+		{value: "2024-03-01T06:30:00+02:00", want: time.Date(2024, 3, 1, 4, 30, 0, 0, time.UTC)},
+		{value: "01.03.2024", wantErr: true},
+		{value: "2024-03-01 06:30", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			// act
+			got, err := parseDate(tt.value)
+
+			// assert
+			if tt.wantErr {
+				assert.ErrSubMsg(t, err, "expected YYYY-MM-DD or RFC 3339")
+				return
+			}
+			assert.NoErr(t, err)
+			assert.True(t, tt.want.Equal(got), "want %s, got %s", tt.want, got)
+		})
+	}
+}
+
+// setStdout captures the output of the commands.
+func setStdout(t *testing.T) *bytes.Buffer {
+	var buf bytes.Buffer
+	old := stdout
+	stdout = &buf
+	t.Cleanup(func() { stdout = old })
+
+	return &buf
+}
+
+func testActivityPages() [][]*strava.SummaryActivity {
+	start := time.Date(2024, 3, 1, 6, 30, 0, 0, time.UTC)
+	activity := func(id uint, name string) *strava.SummaryActivity {
+		return &strava.SummaryActivity{ID: id, Name: name, SportType: "Run", StartDate: start, StartDateLocal: start, Distance: 5000, MovingTime: 1500}
+	}
+
+	return [][]*strava.SummaryActivity{
+		{activity(1, "Morning Run"), activity(2, "Lunch, Run")},
+		{},
+		{activity(3, "Evening Run")},
+	}
+}
+
+func TestActivityPrinter(t *testing.T) {
+	t.Run("json", func(t *testing.T) {
+		// arrange
+		out := setStdout(t)
+		print, done := activityPrinter(formatJSON, 7)
+
+		// act
+		for _, page := range testActivityPages() {
+			assert.NoErr(t, print(page))
+		}
+		assert.NoErr(t, done())
+
+		// assert
+		var got []strava.SummaryActivity
+		assert.NoErr(t, json.Unmarshal(out.Bytes(), &got))
+		assert.Len(t, got, 3)
+		assert.Eq(t, "Lunch, Run", got[1].Name)
+		assert.Eq(t, uint(3), got[2].ID)
+	})
+
+	t.Run("json without activities", func(t *testing.T) {
+		// arrange
+		out := setStdout(t)
+		_, done := activityPrinter(formatJSON, 7)
+
+		// act
+		err := done()
+
+		// assert
+		assert.NoErr(t, err)
+		assert.Eq(t, "[]\n", out.String())
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		// arrange
+		out := setStdout(t)
+		print, done := activityPrinter(formatCSV, 7)
+
+		// act
+		for _, page := range testActivityPages() {
+			assert.NoErr(t, print(page))
+		}
+		assert.NoErr(t, done())
+
+		// assert
+		rows, err := csv.NewReader(out).ReadAll()
+		assert.NoErr(t, err)
+		assert.Len(t, rows, 4)
+		assert.Eq(t, []string{"athlete_id", "id"}, rows[0][:2])
+		assert.Eq(t, []string{"7", "2"}, rows[2][:2])
+		assert.Contains(t, rows[2], "Lunch, Run")
+		assert.Eq(t, "3", rows[3][1])
+	})
+
+	t.Run("table", func(t *testing.T) {
+		// arrange
+		out := setStdout(t)
+		print, done := activityPrinter(formatTable, 7)
+
+		// act
+		for _, page := range testActivityPages() {
+			assert.NoErr(t, print(page))
+		}
+		assert.NoErr(t, done())
+
+		// assert
+		lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+		assert.Len(t, lines, 4)
+		assert.StrContains(t, lines[0], "MOVING TIME")
+		assert.StrContains(t, lines[1], "2024-03-01 06:30")
+		assert.StrContains(t, lines[3], "Evening Run")
+	})
+}
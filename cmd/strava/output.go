@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/marvell/strava-go/export"
+)
+
+// stdout receives the output of the commands, replaced in tests.
+var stdout io.Writer = os.Stdout
+
+const (
+	formatJSON  = "json"
+	formatTable = "table"
+	formatCSV   = "csv"
+)
+
+func checkFormat(format string, allowed ...string) error {
+	for _, f := range allowed {
+		if format == f {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid --format %q, expected one of %v", format, allowed)
+}
+
+func printJSON(v any) error {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// jsonArray prints the values of consecutive calls as one JSON array, without buffering them.
+type jsonArray struct {
+	n int
+}
+
+func (a *jsonArray) print(values ...any) error {
+	for _, v := range values {
+		data, err := json.MarshalIndent(v, "  ", "  ")
+		if err != nil {
+			return err
+		}
+
+		sep := ",\n  "
+		if a.n == 0 {
+			sep = "[\n  "
+		}
+		a.n++
+
+		if _, err := fmt.Fprintf(stdout, "%s%s", sep, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *jsonArray) close() error {
+	if a.n == 0 {
+		_, err := fmt.Fprintln(stdout, "[]")
+		return err
+	}
+
+	_, err := fmt.Fprintln(stdout, "\n]")
+	return err
+}
+
+func newTable(header string) *tabwriter.Writer {
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, header)
+	return w
+}
+
+func printCSV[T any](records []T) error {
+	w := export.NewCSVWriter[T](stdout)
+	if _, err := w.Write(records); err != nil {
+		return err
+	}
+
+	return w.Close()
+}
+
+func formatDistance(meters float64) string {
+	return fmt.Sprintf("%.2f km", meters/1000)
+}
+
+func formatDuration(seconds int) string {
+	return (time.Duration(seconds) * time.Second).String()
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+func tokensList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tokens list", flag.ExitOnError)
+	storage := storageFlag(fs)
+	_ = fs.Parse(args)
+
+	ts, closeTS, err := storage()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeTS() }()
+
+	tokens, err := ts.List(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].AthleteID < tokens[j].AthleteID })
+
+	w := newTable("ATHLETE\tSCOPE\tEXPIRES\tREVOKED")
+	for _, token := range tokens {
+		expiry := "-"
+		if token.Token != nil && !token.Expiry.IsZero() {
+			expiry = token.Expiry.Local().Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", token.AthleteID, token.Scope, expiry, strconv.FormatBool(token.Revoked))
+	}
+	return w.Flush()
+}
+
+func tokensRefresh(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tokens refresh", flag.ExitOnError)
+	open := sessionFlags(fs)
+	_ = fs.Parse(args)
+
+	s, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.close() }()
+
+	token, err := s.c.RefreshToken(ctx, s.athleteID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("refreshed the token of athlete %d, expires %s\n", s.athleteID, token.Expiry.Local().Format("2006-01-02 15:04"))
+	return nil
+}
+
+func tokensRevoke(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("tokens revoke", flag.ExitOnError)
+	open := sessionFlags(fs)
+	_ = fs.Parse(args)
+
+	s, err := open(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = s.close() }()
+
+	if err := s.c.Deauthorize(ctx, s.athleteID); err != nil {
+		return err
+	}
+
+	fmt.Printf("revoked the access to athlete %d\n", s.athleteID)
+	return nil
+}
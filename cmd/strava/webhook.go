@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+
+	"github.com/marvell/strava-go"
+)
+
+func webhookList(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("webhook list", flag.ExitOnError)
+	client := clientFlags(fs)
+	_ = fs.Parse(args)
+
+	c, err := client(nil)
+	if err != nil {
+		return err
+	}
+
+	subs, err := c.GetSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := newTable("ID\tCALLBACK URL\tCREATED")
+	for _, sub := range subs {
+		fmt.Fprintf(w, "%d\t%s\t%s\n", sub.ID, sub.CallbackURL, sub.CreatedAt.Format("2006-01-02 15:04"))
+	}
+	return w.Flush()
+}
+
+// webhookCreate creates a subscription. Strava validates the callback URL before responding, so the
+// webhook must be running with the same verify token.
+func webhookCreate(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("webhook create", flag.ExitOnError)
+	client := clientFlags(fs)
+	callbackURL := fs.String("callback-url", "", "webhook callback URL")
+	verifyToken := fs.String("verify-token", "", "verify token of the running webhook, see strava.WithWebhookVerifyToken")
+	_ = fs.Parse(args)
+
+	if *callbackURL == "" || *verifyToken == "" {
+		fs.Usage()
+		return fmt.Errorf("--callback-url and --verify-token are required")
+	}
+
+	c, err := client(nil, strava.WithWebhookCallbackURL(*callbackURL), strava.WithWebhookVerifyToken(*verifyToken))
+	if err != nil {
+		return err
+	}
+
+	id, err := c.CreateSubscription(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("created subscription %d\n", id)
+	return nil
+}
+
+func webhookDelete(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("webhook delete", flag.ExitOnError)
+	client := clientFlags(fs)
+	id := fs.Uint("id", 0, "subscription ID")
+	_ = fs.Parse(args)
+
+	if *id == 0 {
+		fs.Usage()
+		return fmt.Errorf("--id is required")
+	}
+
+	c, err := client(nil)
+	if err != nil {
+		return err
+	}
+
+	if err := c.DeleteSubscription(ctx, *id); err != nil {
+		return err
+	}
+
+	fmt.Printf("deleted subscription %d\n", *id)
+	return nil
+}
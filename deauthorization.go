@@ -0,0 +1,82 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// DeauthorizedHandler is called when an athlete revokes the application's access, e.g. to delete their data.
+type DeauthorizedHandler func(ctx context.Context, athleteID uint) error
+
+// handleDeauthorization forgets the athlete's token if configured and calls the OnDeauthorized handler.
+// It runs before the event handlers, which still receive the event.
+func (c *Client) handleDeauthorization(ctx context.Context, event Event) error {
+	athleteID := event.OwnerID
+	c.logger.InfoContext(ctx, "athlete deauthorized the application", slog.Uint64("athleteID", uint64(athleteID)))
+
+	if c.deleteDeauthorizedTokens {
+		if err := c.forgetToken(ctx, athleteID); err != nil {
+			return fmt.Errorf("forget token of deauthorized athlete %d: %w", athleteID, err)
+		}
+	}
+
+	if c.onDeauthorized != nil {
+		if err := c.onDeauthorized(ctx, athleteID); err != nil {
+			return fmt.Errorf("deauthorized athlete %d: %w", athleteID, err)
+		}
+	}
+
+	return nil
+}
+
+// Deauthorize revokes the application's access to the athlete's data and forgets the token, like a
+// deauthorization by the athlete. Strava sends a deauthorization event to the webhook afterwards.
+func (c *Client) Deauthorize(ctx context.Context, athleteID uint) error {
+	req, err := http.NewRequest(http.MethodPost, OAuthBaseURL+"/deauthorize", nil)
+	if err != nil {
+		return fmt.Errorf("could not create request: %w", err)
+	}
+
+	if _, err := c.call(ctx, athleteID, req, c.maxRetries); err != nil {
+		return fmt.Errorf("could not call: %w", err)
+	}
+
+	if err := c.forgetToken(ctx, athleteID); err != nil {
+		return fmt.Errorf("forget token of athlete %d: %w", athleteID, err)
+	}
+
+	return nil
+}
+
+// forgetToken deletes the athlete's token. Storages that can't delete tokens keep it marked as revoked.
+func (c *Client) forgetToken(ctx context.Context, athleteID uint) error {
+	if d, ok := c.tstore.(TokenDeleter); ok {
+		if err := d.Delete(ctx, athleteID); err != nil {
+			return fmt.Errorf("delete token from %T: %w", c.tstore, err)
+		}
+		return nil
+	}
+
+	token, err := c.tstore.Get(ctx, athleteID)
+	if err != nil {
+		if errors.Is(err, ErrTokenNotFound) {
+			return nil
+		}
+		return fmt.Errorf("get token from %T: %w", c.tstore, err)
+	}
+
+	if token.Revoked {
+		return nil
+	}
+
+	revoked := token.Clone()
+	revoked.Revoked = true
+	if err := c.tstore.Save(ctx, revoked); err != nil {
+		return fmt.Errorf("save revoked token to %T: %w", c.tstore, err)
+	}
+
+	return nil
+}
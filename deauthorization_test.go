@@ -0,0 +1,115 @@
+package strava
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+)
+
+// saveOnlyTokenStorage hides the Delete method of the wrapped storage.
+type saveOnlyTokenStorage struct {
+	TokenStorage
+}
+
+func TestClient_Deauthorization(t *testing.T) {
+	const deauthorization = `{"object_type":"athlete","object_id":7,"aspect_type":"update","owner_id":7,"subscription_id":1,"updates":{"authorized":"false"}}`
+
+	tests := []struct {
+		name        string
+		wrap        func(ts *testTokenStorage) TokenStorage
+		deleteToken bool
+		wantFound   bool
+		wantRevoked bool
+	}{
+		{"keep token", func(ts *testTokenStorage) TokenStorage { return ts }, false, true, false},
+		{"delete token", func(ts *testTokenStorage) TokenStorage { return ts }, true, false, false},
+		{"revoke token without deleter", func(ts *testTokenStorage) TokenStorage { return saveOnlyTokenStorage{ts} }, true, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			ts := newTestTokenStorage()
+			assert.NoErr(t, ts.Save(ctx, &Token{Token: &oauth2.Token{AccessToken: "access"}, AthleteID: 7}))
+
+			var (
+				mu           sync.Mutex
+				deauthorized []uint
+				events       []Event
+			)
+
+			opts := []Option{WithOnDeauthorized(func(ctx context.Context, athleteID uint) error {
+				mu.Lock()
+				defer mu.Unlock()
+				deauthorized = append(deauthorized, athleteID)
+				return nil
+			})}
+			if tt.deleteToken {
+				opts = append(opts, WithDeauthorizedTokenDeletion())
+			}
+
+			c := NewClient("client_id", "client_secret", "", tt.wrap(ts), opts...)
+			c.subscriptionID.Store(1)
+			_ = c.RegisterEventHandler(func(ctx context.Context, event Event) error {
+				mu.Lock()
+				defer mu.Unlock()
+				events = append(events, event)
+				return nil
+			})
+			c.StartWebhookWorkers()
+
+			// act
+			rec := httptest.NewRecorder()
+			c.WebhookCallback(rec, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(deauthorization)))
+			assert.NoErr(t, c.Shutdown(ctx))
+
+			// assert
+			assert.Eq(t, http.StatusOK, rec.Code)
+			assert.Eq(t, []uint{7}, deauthorized)
+			assert.Len(t, events, 1)
+
+			token, err := ts.Get(ctx, 7)
+			if !tt.wantFound {
+				assert.ErrIs(t, err, ErrTokenNotFound)
+				return
+			}
+			assert.NoErr(t, err)
+			assert.Eq(t, tt.wantRevoked, token.Revoked)
+		})
+	}
+}
+
+func TestClient_Deauthorize(t *testing.T) {
+	// arrange
+	var authorization string
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/oauth/deauthorize" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		authorization = r.Header.Get("Authorization")
+		_, _ = w.Write([]byte(`{"access_token":"access"}`))
+	})
+
+	ctx := context.Background()
+	ts := newTestTokenStorage()
+	assert.NoErr(t, ts.Save(ctx, &Token{Token: &oauth2.Token{AccessToken: "access"}, AthleteID: 7}))
+	c := NewClient("client_id", "client_secret", "", ts, WithTransport(newTestAPITransport(t, api)))
+
+	// act
+	err := c.Deauthorize(ctx, 7)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, "Bearer access", authorization)
+
+	_, err = ts.Get(ctx, 7)
+	assert.ErrIs(t, err, ErrTokenNotFound)
+}
@@ -0,0 +1,94 @@
+package strava
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultEventDedupTTL is how long event keys are remembered by default.
+const DefaultEventDedupTTL = 24 * time.Hour
+
+// SeenStore remembers the keys of received webhook events.
+type SeenStore interface {
+	// MarkSeen records the key for the ttl and reports whether it was recorded already.
+	MarkSeen(ctx context.Context, key string, ttl time.Duration) (seen bool, err error)
+	// Forget removes the key, so the event is handled if it's delivered again.
+	Forget(ctx context.Context, key string) error
+}
+
+// EventStats counts the webhook events received by the client.
+type EventStats struct {
+	Received   uint64
+	Duplicates uint64
+}
+
+type eventStats struct {
+	received   atomic.Uint64
+	duplicates atomic.Uint64
+}
+
+// EventStats returns the number of received webhook events and of duplicates dropped by the deduplication.
+func (c *Client) EventStats() EventStats {
+	return EventStats{
+		Received:   c.eventStats.received.Load(),
+		Duplicates: c.eventStats.duplicates.Load(),
+	}
+}
+
+// IdempotencyKey identifies the event among redeliveries. Events differing in any field,
+// including the updates, have different keys.
+func (e Event) IdempotencyKey() string {
+	keys := make([]string, 0, len(e.Updates))
+	for k := range e.Updates {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%q=%q;", k, e.Updates[k])
+	}
+
+	return fmt.Sprintf("%d:%d:%s:%d:%s:%d:%s",
+		e.SubscriptionID, e.OwnerID, e.ObjectType, e.ObjectID, e.AspectType, e.EventTime,
+		hex.EncodeToString(h.Sum(nil))[:16])
+}
+
+// markEventSeen reports whether the event is a duplicate. Events are handled if the store fails.
+func (c *Client) markEventSeen(ctx context.Context, event Event) bool {
+	c.eventStats.received.Add(1)
+
+	if c.seen == nil {
+		return false
+	}
+
+	seen, err := c.seen.MarkSeen(ctx, event.IdempotencyKey(), c.seenTTL)
+	if err != nil {
+		c.logger.WarnContext(ctx, "check webhook event duplicate", slog.Any("event", event), slog.Any("error", err))
+		return false
+	}
+
+	if seen {
+		c.eventStats.duplicates.Add(1)
+		c.logger.InfoContext(ctx, "webhook event duplicate dropped", slog.Any("event", event))
+	}
+
+	return seen
+}
+
+// forgetEvent removes the key of an event the client failed to accept, so its redelivery isn't dropped.
+func (c *Client) forgetEvent(ctx context.Context, event Event) {
+	if c.seen == nil {
+		return
+	}
+
+	if err := c.seen.Forget(ctx, event.IdempotencyKey()); err != nil {
+		c.logger.WarnContext(ctx, "forget webhook event", slog.Any("event", event), slog.Any("error", err))
+	}
+}
@@ -0,0 +1,98 @@
+package strava
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+// testSeenStore is a SeenStore without expiry.
+type testSeenStore struct {
+	mu   sync.Mutex
+	keys map[string]bool
+}
+
+func (s *testSeenStore) MarkSeen(_ context.Context, key string, _ time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := s.keys[key]
+	s.keys[key] = true
+	return seen, nil
+}
+
+func (s *testSeenStore) Forget(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.keys, key)
+	return nil
+}
+
+func TestEvent_IdempotencyKey(t *testing.T) {
+	// arrange
+	event := Event{
+		ObjectType:     EventObjectTypeActivity,
+		ObjectID:       1360128428,
+		AspectType:     EventAspectTypeUpdate,
+		Updates:        map[string]string{"title": "Messy", "type": "Ride"},
+		OwnerID:        134815,
+		SubscriptionID: 120475,
+		EventTime:      1516126040,
+	}
+
+	same := event
+	same.Updates = map[string]string{"type": "Ride", "title": "Messy"}
+
+	renamed := event
+	renamed.Updates = map[string]string{"title": "Tidy", "type": "Ride"}
+
+	// act
+	key := event.IdempotencyKey()
+
+	// assert
+	assert.True(t, strings.HasPrefix(key, "120475:134815:activity:1360128428:update:1516126040:"))
+	assert.Eq(t, key, same.IdempotencyKey())
+	assert.NotEq(t, key, renamed.IdempotencyKey())
+}
+
+func TestClient_EventDeduplication(t *testing.T) {
+	// arrange
+	c := NewClient("client_id", "client_secret", "", nil,
+		WithEventDeduplication(&testSeenStore{keys: map[string]bool{}}, 0),
+	)
+	c.subscriptionID.Store(1)
+
+	var handled atomic.Int32
+	_ = c.RegisterEventHandler(func(ctx context.Context, event Event) error {
+		handled.Add(1)
+		return nil
+	})
+	c.StartWebhookWorkers()
+
+	bodies := []string{
+		`{"object_type":"activity","object_id":1,"aspect_type":"create","owner_id":2,"subscription_id":1,"event_time":3}`,
+		`{"object_type":"activity","object_id":1,"aspect_type":"create","owner_id":2,"subscription_id":1,"event_time":3}`,
+		`{"object_type":"activity","object_id":1,"aspect_type":"update","owner_id":2,"subscription_id":1,"event_time":4,"updates":{"title":"a"}}`,
+		`{"object_type":"activity","object_id":1,"aspect_type":"update","owner_id":2,"subscription_id":1,"event_time":4,"updates":{"title":"b"}}`,
+	}
+
+	// act
+	for _, body := range bodies {
+		rec := httptest.NewRecorder()
+		c.WebhookCallback(rec, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body)))
+		assert.Eq(t, http.StatusOK, rec.Code)
+	}
+	assert.NoErr(t, c.Shutdown(context.Background()))
+
+	// assert
+	assert.Eq(t, int32(3), handled.Load())
+	assert.Eq(t, EventStats{Received: 4, Duplicates: 1}, c.EventStats())
+}
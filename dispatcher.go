@@ -0,0 +1,256 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWebhookWorkers   = 4
+	DefaultWebhookQueueSize = 100
+)
+
+var (
+	ErrEventQueueFull = errors.New("event queue is full")
+	ErrShuttingDown   = errors.New("client is shutting down")
+)
+
+// dispatcher runs event handlers on a fixed number of workers. Events are passed to the workers
+// over a channel, or through the EventQueue if one is set.
+type dispatcher struct {
+	c *Client
+
+	workers   int
+	queueSize int
+
+	store       EventQueue
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	start  sync.Once
+	events chan Event
+	wake   chan struct{}
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func newDispatcher(c *Client) *dispatcher {
+	return &dispatcher{
+		c:           c,
+		workers:     DefaultWebhookWorkers,
+		queueSize:   DefaultWebhookQueueSize,
+		maxAttempts: DefaultEventMaxAttempts,
+		minBackoff:  DefaultEventMinBackoff,
+		maxBackoff:  DefaultEventMaxBackoff,
+		wake:        make(chan struct{}, 1),
+		stop:        make(chan struct{}),
+	}
+}
+
+// init creates the channel of the workers, once the options are applied.
+func (d *dispatcher) init() {
+	d.events = make(chan Event, d.queueSize)
+}
+
+// startWorkers starts the workers once. Until then events are queued, so they are only handled once
+// all handlers are registered.
+func (d *dispatcher) startWorkers() {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return
+	}
+
+	d.start.Do(func() {
+		for i := 0; i < d.workers; i++ {
+			d.wg.Add(1)
+			if d.store != nil {
+				go d.workQueue()
+			} else {
+				go d.work()
+			}
+		}
+	})
+}
+
+// enqueue queues the event without blocking on workers. With an EventQueue it returns once the event is persisted.
+func (d *dispatcher) enqueue(ctx context.Context, event Event) error {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if d.closed {
+		return ErrShuttingDown
+	}
+
+	if d.store != nil {
+		if err := d.store.Enqueue(ctx, event); err != nil {
+			return fmt.Errorf("persist event: %w", err)
+		}
+
+		d.notify()
+		return nil
+	}
+
+	select {
+	case d.events <- event:
+		return nil
+	default:
+		return ErrEventQueueFull
+	}
+}
+
+// notify wakes an idle queue worker.
+func (d *dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *dispatcher) work() {
+	defer d.wg.Done()
+
+	for event := range d.events {
+		if err := d.handle(d.c.ctx, event); err != nil {
+			d.c.logger.ErrorContext(d.c.ctx, "webhook event handled with error", slog.Any("event", event), slog.Any("error", err))
+		}
+	}
+}
+
+func (d *dispatcher) workQueue() {
+	defer d.wg.Done()
+
+	for {
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+
+		qe, err := d.store.Claim(d.c.ctx, eventClaimTimeout)
+		if err != nil {
+			if !errors.Is(err, ErrNoEventDue) {
+				d.c.logger.ErrorContext(d.c.ctx, "claim webhook event", slog.Any("error", err))
+			}
+
+			select {
+			case <-d.stop:
+				return
+			case <-d.wake:
+			case <-time.After(eventPollInterval):
+			}
+			continue
+		}
+
+		// More events may be due, let another idle worker look.
+		d.notify()
+		d.process(qe)
+	}
+}
+
+// process handles a claimed event and acks, retries or dead-letters it.
+func (d *dispatcher) process(qe *QueuedEvent) {
+	err := d.handle(d.c.ctx, qe.Event)
+
+	// Record the outcome even if handlers were canceled by Shutdown.
+	ctx := context.WithoutCancel(d.c.ctx)
+	logger := d.c.logger.With(slog.Uint64("id", qe.ID), slog.Any("event", qe.Event), slog.Int("attempts", qe.Attempts))
+
+	switch {
+	case err == nil:
+		err = d.store.Ack(ctx, qe.ID, qe.Attempts)
+	case qe.Attempts >= d.maxAttempts:
+		logger.ErrorContext(ctx, "webhook event dead-lettered", slog.Any("error", err))
+		err = d.store.DeadLetter(ctx, qe.ID, qe.Attempts, err.Error())
+	default:
+		delay := eventBackoff(qe.Attempts, d.minBackoff, d.maxBackoff)
+		logger.WarnContext(ctx, "webhook event handled with error, retrying", slog.Any("error", err), slog.Duration("delay", delay))
+		err = d.store.Retry(ctx, qe.ID, qe.Attempts, time.Now().Add(delay), err.Error())
+		time.AfterFunc(delay, d.notify)
+	}
+
+	switch {
+	case errors.Is(err, ErrEventNotFound):
+		logger.WarnContext(ctx, "webhook event was claimed again before its outcome was recorded")
+	case err != nil:
+		logger.ErrorContext(ctx, "update queued webhook event", slog.Any("error", err))
+	}
+}
+
+// handle runs all handlers and returns their joined errors.
+func (d *dispatcher) handle(ctx context.Context, event Event) error {
+	d.c.eventHandlersLock.RLock()
+	handlers := d.c.eventHandlers
+	d.c.eventHandlersLock.RUnlock()
+
+	var errs []error
+	if event.IsDeauthorization() {
+		if err := safeHandle(ctx, d.c.handleDeauthorization, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, handler := range handlers {
+		if err := safeHandle(ctx, handler, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// safeHandle calls the handler and turns a panic into an error.
+func safeHandle(ctx context.Context, handler EventHandler, event Event) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	return handler(ctx, event)
+}
+
+// shutdown stops accepting events and waits for in-flight ones. Without an EventQueue it also
+// waits for queued events, starting the workers if needed. Persisted events are left for the next start.
+func (d *dispatcher) shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		d.start.Do(func() {
+			if d.store != nil {
+				return
+			}
+
+			for i := 0; i < d.workers; i++ {
+				d.wg.Add(1)
+				go d.work()
+			}
+		})
+		close(d.stop)
+		close(d.events)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
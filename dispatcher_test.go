@@ -0,0 +1,305 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestClient_Shutdown(t *testing.T) {
+	// arrange
+	c := NewClient("client_id", "client_secret", "", nil, WithWebhookWorkers(2, 10))
+	c.subscriptionID.Store(1)
+
+	var handled atomic.Int32
+	_ = c.RegisterEventHandler(func(ctx context.Context, event Event) error {
+		time.Sleep(10 * time.Millisecond)
+		handled.Add(1)
+		return nil
+	})
+	_ = c.RegisterEventHandler(func(ctx context.Context, event Event) error {
+		panic("handler bug")
+	})
+	c.StartWebhookWorkers()
+
+	post := func() int {
+		rec := httptest.NewRecorder()
+		c.WebhookCallback(rec, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(`{"object_type":"activity","aspect_type":"create","subscription_id":1}`)))
+		return rec.Code
+	}
+
+	for i := 0; i < 5; i++ {
+		assert.Eq(t, http.StatusOK, post())
+	}
+
+	// act
+	err := c.Shutdown(context.Background())
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, int32(5), handled.Load())
+	assert.Eq(t, http.StatusServiceUnavailable, post())
+}
+
+func TestClient_ShutdownTimeout(t *testing.T) {
+	// arrange
+	c := NewClient("client_id", "client_secret", "", nil)
+	c.subscriptionID.Store(1)
+
+	canceled := make(chan error, 1)
+	_ = c.RegisterEventHandler(func(ctx context.Context, event Event) error {
+		<-ctx.Done()
+		canceled <- ctx.Err()
+		return nil
+	})
+	c.StartWebhookWorkers()
+
+	rec := httptest.NewRecorder()
+	c.WebhookCallback(rec, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(`{"subscription_id":1}`)))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// act
+	err := c.Shutdown(ctx)
+
+	// assert
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.ErrIs(t, <-canceled, context.Canceled)
+}
+
+// testEventQueue is a minimal EventQueue for the client tests.
+type testEventQueue struct {
+	mu      sync.Mutex
+	lastID  uint64
+	pending map[uint64]*QueuedEvent
+	dead    map[uint64]*QueuedEvent
+}
+
+func newTestEventQueue() *testEventQueue {
+	return &testEventQueue{pending: map[uint64]*QueuedEvent{}, dead: map[uint64]*QueuedEvent{}}
+}
+
+func (q *testEventQueue) Enqueue(_ context.Context, event Event) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.lastID++
+	q.pending[q.lastID] = &QueuedEvent{ID: q.lastID, Event: event, NextAttempt: time.Now(), EnqueuedAt: time.Now()}
+	return nil
+}
+
+func (q *testEventQueue) Claim(_ context.Context, timeout time.Duration) (*QueuedEvent, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, e := range q.pending {
+		if !e.NextAttempt.After(time.Now()) {
+			e.Attempts++
+			e.NextAttempt = time.Now().Add(timeout)
+			c := *e
+			return &c, nil
+		}
+	}
+	return nil, ErrNoEventDue
+}
+
+func (q *testEventQueue) Ack(_ context.Context, id uint64, attempts int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if e, ok := q.pending[id]; !ok || e.Attempts != attempts {
+		return ErrEventNotFound
+	}
+	delete(q.pending, id)
+	return nil
+}
+
+func (q *testEventQueue) Retry(_ context.Context, id uint64, attempts int, next time.Time, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if e, ok := q.pending[id]; !ok || e.Attempts != attempts {
+		return ErrEventNotFound
+	}
+
+	q.pending[id].NextAttempt = next
+	q.pending[id].LastError = reason
+	return nil
+}
+
+func (q *testEventQueue) DeadLetter(_ context.Context, id uint64, attempts int, reason string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if e, ok := q.pending[id]; !ok || e.Attempts != attempts {
+		return ErrEventNotFound
+	}
+
+	q.dead[id] = q.pending[id]
+	q.dead[id].LastError = reason
+	delete(q.pending, id)
+	return nil
+}
+
+func (q *testEventQueue) DeadLetters(context.Context) ([]*QueuedEvent, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var events []*QueuedEvent
+	for _, e := range q.dead {
+		c := *e
+		events = append(events, &c)
+	}
+	return events, nil
+}
+
+func (q *testEventQueue) Replay(_ context.Context, id uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.pending[id] = q.dead[id]
+	q.pending[id].Attempts = 0
+	q.pending[id].NextAttempt = time.Now()
+	delete(q.dead, id)
+	return nil
+}
+
+func (q *testEventQueue) size() (pending, dead int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.pending), len(q.dead)
+}
+
+func TestClient_EventQueue(t *testing.T) {
+	// arrange
+	q := newTestEventQueue()
+	c := NewClient("client_id", "client_secret", "", nil,
+		WithEventQueue(q),
+		WithEventRetry(3, time.Millisecond, 5*time.Millisecond),
+	)
+	c.subscriptionID.Store(1)
+
+	var flakyCalls atomic.Int32
+	_ = c.RegisterEventHandler(func(ctx context.Context, event Event) error {
+		switch event.ObjectID {
+		case 1:
+			if flakyCalls.Add(1) < 3 {
+				return errors.New("temporary failure")
+			}
+			return nil
+		case 2:
+			return errors.New("permanent failure")
+		}
+		return nil
+	})
+	c.StartWebhookWorkers()
+
+	// act
+	for _, body := range []string{`{"object_id":1,"subscription_id":1}`, `{"object_id":2,"subscription_id":1}`} {
+		rec := httptest.NewRecorder()
+		c.WebhookCallback(rec, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(body)))
+		assert.Eq(t, http.StatusOK, rec.Code)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if pending, dead := q.size(); pending == 0 && dead == 1 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// assert
+	assert.NoErr(t, c.Shutdown(context.Background()))
+	assert.Eq(t, int32(3), flakyCalls.Load())
+
+	dead, err := q.DeadLetters(context.Background())
+	assert.NoErr(t, err)
+	assert.Len(t, dead, 1)
+	assert.Eq(t, uint(2), dead[0].Event.ObjectID)
+	assert.Eq(t, 3, dead[0].Attempts)
+	assert.Eq(t, "permanent failure", dead[0].LastError)
+}
+
+func TestClient_StartWebhookWorkers(t *testing.T) {
+	// arrange: an event persisted by an earlier process, and one received before the handlers are registered
+	q := newTestEventQueue()
+	assert.NoErr(t, q.Enqueue(context.Background(), Event{ObjectID: 1, SubscriptionID: 1}))
+
+	c := NewClient("client_id", "client_secret", "", nil, WithEventQueue(q))
+	c.subscriptionID.Store(1)
+
+	rec := httptest.NewRecorder()
+	c.WebhookCallback(rec, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(`{"object_id":2,"subscription_id":1}`)))
+	assert.Eq(t, http.StatusOK, rec.Code)
+
+	var first, second atomic.Int32
+	_ = c.RegisterEventHandler(func(ctx context.Context, event Event) error {
+		first.Add(1)
+		return nil
+	})
+
+	time.Sleep(20 * time.Millisecond)
+	pending, _ := q.size()
+	assert.Eq(t, 2, pending)
+
+	_ = c.RegisterEventHandler(func(ctx context.Context, event Event) error {
+		second.Add(1)
+		return nil
+	})
+
+	// act
+	c.StartWebhookWorkers()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if pending, _ := q.size(); pending == 0 || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// assert
+	assert.NoErr(t, c.Shutdown(context.Background()))
+	assert.Eq(t, int32(2), first.Load())
+	assert.Eq(t, int32(2), second.Load())
+}
+
+func TestClient_ShutdownWithoutWorkers(t *testing.T) {
+	// arrange
+	c := NewClient("client_id", "client_secret", "", nil)
+	c.subscriptionID.Store(1)
+
+	var handled atomic.Int32
+	_ = c.RegisterEventHandler(func(ctx context.Context, event Event) error {
+		handled.Add(1)
+		return nil
+	})
+
+	rec := httptest.NewRecorder()
+	c.WebhookCallback(rec, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(`{"subscription_id":1}`)))
+
+	// act
+	err := c.Shutdown(context.Background())
+
+	// assert: queued events are drained
+	assert.NoErr(t, err)
+	assert.Eq(t, http.StatusOK, rec.Code)
+	assert.Eq(t, int32(1), handled.Load())
+}
+
+func TestEventBackoff(t *testing.T) {
+	assert.Eq(t, time.Second, eventBackoff(1, time.Second, time.Minute))
+	assert.Eq(t, 4*time.Second, eventBackoff(3, time.Second, time.Minute))
+	assert.Eq(t, time.Minute, eventBackoff(20, time.Second, time.Minute))
+}
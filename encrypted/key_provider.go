@@ -0,0 +1,60 @@
+package encrypted
+
+import (
+	"context"
+	"fmt"
+)
+
+// KeyProvider provides AES keys for sealing tokens.
+// Keys are identified by IDs, so tokens sealed with a rotated out key can still be opened.
+type KeyProvider interface {
+	// CurrentKey returns the key used to seal tokens on save.
+	CurrentKey(ctx context.Context) (id string, key []byte, err error)
+	// Key returns the key with the given ID.
+	Key(ctx context.Context, id string) ([]byte, error)
+}
+
+// KeyRing is a static KeyProvider.
+type KeyRing struct {
+	currentID string
+	keys      map[string][]byte
+}
+
+var _ KeyProvider = (*KeyRing)(nil)
+
+// NewKeyRing creates a key ring that seals tokens with the currentID key
+// and opens tokens with any of the keys. Keys must be 16, 24 or 32 bytes long.
+func NewKeyRing(currentID string, keys map[string][]byte) (*KeyRing, error) {
+	if _, ok := keys[currentID]; !ok {
+		return nil, fmt.Errorf("current key %q is not in the key ring", currentID)
+	}
+
+	kr := &KeyRing{
+		currentID: currentID,
+		keys:      make(map[string][]byte, len(keys)),
+	}
+	for id, key := range keys {
+		switch len(key) {
+		case 16, 24, 32:
+		default:
+			return nil, fmt.Errorf("key %q: invalid AES key size %d", id, len(key))
+		}
+
+		kr.keys[id] = append([]byte(nil), key...)
+	}
+
+	return kr, nil
+}
+
+func (kr *KeyRing) CurrentKey(_ context.Context) (string, []byte, error) {
+	return kr.currentID, kr.keys[kr.currentID], nil
+}
+
+func (kr *KeyRing) Key(_ context.Context, id string) ([]byte, error) {
+	key, ok := kr.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", id)
+	}
+
+	return key, nil
+}
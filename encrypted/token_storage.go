@@ -0,0 +1,300 @@
+package encrypted
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+
+	"github.com/marvell/strava-go"
+)
+
+// sealedPrefix marks access tokens holding a sealed token: "strava-go:enc:v2:<key id>:<base64 nonce+ciphertext>".
+// The key ID ends at the last ':', so it may contain colons.
+const sealedPrefix = "strava-go:enc:v2:"
+
+// sealedPrefixV1 marks tokens sealed before the client namespace was bound to them. They are still
+// read, and re-sealed on the next save or by Migrate.
+const sealedPrefixV1 = "strava-go:enc:v1:"
+
+var ErrPlaintextToken = errors.New("token is not encrypted")
+
+// TokenStorage wraps another token storage and keeps tokens in it sealed with AES-GCM.
+//
+// The wrapped storage only sees the athlete ID, expiry, scope and revocation flag in plain text,
+// the whole token is sealed into the access token field.
+type TokenStorage struct {
+	next           strava.TokenStorage
+	keys           KeyProvider
+	allowPlaintext bool
+
+	// clientID is the namespace of the view, empty for the storage itself.
+	clientID string
+}
+
+var (
+	_ strava.TokenStorage    = (*TokenStorage)(nil)
+	_ strava.TokenLister     = (*TokenStorage)(nil)
+	_ strava.TokenDeleter    = (*TokenStorage)(nil)
+	_ strava.TokenNamespacer = (*TokenStorage)(nil)
+)
+
+type Option func(*TokenStorage)
+
+// WithPlaintextFallback allows reading tokens saved before the storage was encrypted.
+// They are sealed on the next save or by Migrate.
+func WithPlaintextFallback() Option {
+	return func(ts *TokenStorage) {
+		ts.allowPlaintext = true
+	}
+}
+
+func NewTokenStorage(next strava.TokenStorage, keys KeyProvider, opts ...Option) *TokenStorage {
+	ts := &TokenStorage{
+		next: next,
+		keys: keys,
+	}
+
+	for _, opt := range opts {
+		opt(ts)
+	}
+
+	return ts
+}
+
+func (ts *TokenStorage) ForClient(clientID string) strava.TokenStorage {
+	view := *ts
+	view.next = strava.ForClient(ts.next, clientID)
+	view.clientID = clientID
+	return &view
+}
+
+func (ts *TokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token, error) {
+	stored, err := ts.next.Get(ctx, athleteID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.open(ctx, stored)
+}
+
+func (ts *TokenStorage) Save(ctx context.Context, token *strava.Token) error {
+	sealed, err := ts.seal(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return ts.next.Save(ctx, sealed)
+}
+
+func (ts *TokenStorage) List(ctx context.Context) ([]*strava.Token, error) {
+	stored, err := ts.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := make([]*strava.Token, 0, len(stored))
+	for _, t := range stored {
+		token, err := ts.open(ctx, t)
+		if err != nil {
+			return nil, fmt.Errorf("athlete %d: %w", t.AthleteID, err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+func (ts *TokenStorage) Delete(ctx context.Context, athleteID uint) error {
+	deleter, ok := ts.next.(strava.TokenDeleter)
+	if !ok {
+		return fmt.Errorf("token storage %T doesn't implement TokenDeleter", ts.next)
+	}
+
+	return deleter.Delete(ctx, athleteID)
+}
+
+// Migrate seals plaintext tokens and re-seals tokens sealed with other than the current key or
+// in the old format. Views returned by ForClient migrate their own namespace only. It returns the number of rewritten tokens. The wrapped storage must implement strava.TokenLister.
+func (ts *TokenStorage) Migrate(ctx context.Context) (int, error) {
+	stored, err := ts.list(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	currentID, _, err := ts.keys.CurrentKey(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("get current key: %w", err)
+	}
+
+	var n int
+	for _, t := range stored {
+		prefix, keyID, _, sealed := parseSealed(t)
+		if sealed && prefix == sealedPrefix && keyID == currentID {
+			continue
+		}
+
+		token := t
+		if sealed {
+			if token, err = ts.open(ctx, t); err != nil {
+				return n, fmt.Errorf("athlete %d: %w", t.AthleteID, err)
+			}
+		}
+
+		if err := ts.Save(ctx, token); err != nil {
+			return n, fmt.Errorf("athlete %d: %w", t.AthleteID, err)
+		}
+		n++
+	}
+
+	return n, nil
+}
+
+func (ts *TokenStorage) list(ctx context.Context) ([]*strava.Token, error) {
+	lister, ok := ts.next.(strava.TokenLister)
+	if !ok {
+		return nil, fmt.Errorf("token storage %T doesn't implement TokenLister", ts.next)
+	}
+
+	return lister.List(ctx)
+}
+
+func (ts *TokenStorage) seal(ctx context.Context, token *strava.Token) (*strava.Token, error) {
+	keyID, key, err := ts.keys.CurrentKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("get current key: %w", err)
+	}
+
+	plaintext, err := json.Marshal(token)
+	if err != nil {
+		return nil, fmt.Errorf("marshal token: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	ciphertext := aead.Seal(nonce, nonce, plaintext, additionalData(ts.clientID, token.AthleteID))
+
+	sealed := &strava.Token{
+		Token: &oauth2.Token{
+			AccessToken: sealedPrefix + keyID + ":" + base64.RawStdEncoding.EncodeToString(ciphertext),
+		},
+		AthleteID: token.AthleteID,
+		Scope:     token.Scope,
+		Revoked:   token.Revoked,
+	}
+	if token.Token != nil {
+		sealed.Expiry = token.Expiry
+	}
+
+	return sealed, nil
+}
+
+func (ts *TokenStorage) open(ctx context.Context, stored *strava.Token) (*strava.Token, error) {
+	prefix, keyID, data, ok := parseSealed(stored)
+	if !ok {
+		if !ts.allowPlaintext {
+			return nil, ErrPlaintextToken
+		}
+		return stored, nil
+	}
+
+	key, err := ts.keys.Key(ctx, keyID)
+	if err != nil {
+		return nil, fmt.Errorf("get key: %w", err)
+	}
+
+	ciphertext, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("decode sealed token: %w", err)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("sealed token is too short")
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+
+	aad := additionalData(ts.clientID, stored.AthleteID)
+	if prefix == sealedPrefixV1 {
+		aad = additionalDataV1(stored.AthleteID)
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, aad)
+	if err != nil {
+		return nil, fmt.Errorf("open sealed token: %w", err)
+	}
+
+	var token strava.Token
+	if err := json.Unmarshal(plaintext, &token); err != nil {
+		return nil, fmt.Errorf("unmarshal token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func parseSealed(t *strava.Token) (prefix, keyID, data string, ok bool) {
+	if t.Token == nil {
+		return "", "", "", false
+	}
+
+	for _, p := range []string{sealedPrefix, sealedPrefixV1} {
+		rest, found := strings.CutPrefix(t.AccessToken, p)
+		if !found {
+			continue
+		}
+
+		// Key IDs may contain ':', base64 doesn't.
+		i := strings.LastIndexByte(rest, ':')
+		if i < 0 {
+			return "", "", "", false
+		}
+
+		return p, rest[:i], rest[i+1:], true
+	}
+
+	return "", "", "", false
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("create GCM: %w", err)
+	}
+
+	return aead, nil
+}
+
+// additionalData binds a sealed token to its athlete and client namespace, so it can't be swapped
+// with another athlete's one or copied into another application's namespace.
+func additionalData(clientID string, athleteID uint) []byte {
+	return append(binary.BigEndian.AppendUint64(nil, uint64(athleteID)), clientID...)
+}
+
+// additionalDataV1 is the additional data of tokens sealed in the old format, bound to the athlete only.
+func additionalDataV1(athleteID uint) []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(athleteID))
+}
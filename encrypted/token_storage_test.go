@@ -0,0 +1,186 @@
+package encrypted
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/inmemory"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestTokenStorage(t *testing.T) {
+	storagetest.RunTokenStorageSuite(t, func(t *testing.T) strava.TokenStorage {
+		keys, _ := NewKeyRing("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+		return NewTokenStorage(&inmemory.TokenStorage{}, keys)
+	})
+}
+
+func testToken(athleteID uint) *strava.Token {
+	return &strava.Token{
+		Token: &oauth2.Token{
+			AccessToken:  "access",
+			TokenType:    "Bearer",
+			RefreshToken: "refresh",
+			Expiry:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		AthleteID: athleteID,
+		Scope:     "read,activity:read",
+	}
+}
+
+func TestTokenStorage_KeyRotation(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	next := &inmemory.TokenStorage{}
+	oldKeys, _ := NewKeyRing("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	newKeys, _ := NewKeyRing("k2", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32), "k2": bytes.Repeat([]byte{2}, 32)})
+
+	err := NewTokenStorage(next, oldKeys).Save(ctx, testToken(1))
+	assert.NoErr(t, err)
+
+	ts := NewTokenStorage(next, newKeys)
+
+	// act
+	got, err := ts.Get(ctx, 1)
+	assert.NoErr(t, err)
+	n, migrateErr := ts.Migrate(ctx)
+
+	// assert
+	assert.Eq(t, "refresh", got.RefreshToken)
+	assert.Eq(t, "read,activity:read", got.Scope)
+	assert.NoErr(t, migrateErr)
+	assert.Eq(t, 1, n)
+
+	stored, _ := next.Get(ctx, 1)
+	assert.True(t, strings.HasPrefix(stored.AccessToken, sealedPrefix+"k2:"))
+	assert.Empty(t, stored.RefreshToken)
+}
+
+func TestTokenStorage_KeyIDWithColon(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	next := &inmemory.TokenStorage{}
+	keys, err := NewKeyRing("2024:a", map[string][]byte{"2024:a": bytes.Repeat([]byte{1}, 32), "2024": bytes.Repeat([]byte{2}, 32)})
+	assert.NoErr(t, err)
+	ts := NewTokenStorage(next, keys)
+
+	// act
+	err = ts.Save(ctx, testToken(1))
+	assert.NoErr(t, err)
+	got, getErr := ts.Get(ctx, 1)
+	n, migrateErr := ts.Migrate(ctx)
+
+	// assert
+	assert.NoErr(t, getErr)
+	assert.Eq(t, "refresh", got.RefreshToken)
+	assert.NoErr(t, migrateErr)
+	assert.Eq(t, 0, n)
+
+	stored, _ := next.Get(ctx, 1)
+	assert.True(t, strings.HasPrefix(stored.AccessToken, sealedPrefix+"2024:a:"))
+}
+
+func TestTokenStorage_MigratePlaintext(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	next := &inmemory.TokenStorage{}
+	_ = next.Save(ctx, testToken(1))
+	keys, _ := NewKeyRing("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 16)})
+	ts := NewTokenStorage(next, keys)
+
+	_, err := ts.Get(ctx, 1)
+	assert.ErrIs(t, err, ErrPlaintextToken)
+
+	// act
+	n, err := ts.Migrate(ctx)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, n)
+
+	got, err := ts.Get(ctx, 1)
+	assert.NoErr(t, err)
+	assert.Eq(t, "access", got.AccessToken)
+	assert.Eq(t, "refresh", got.RefreshToken)
+}
+
+func TestTokenStorage_ClientNamespace(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	next := &inmemory.TokenStorage{}
+	keys, _ := NewKeyRing("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	ts := NewTokenStorage(next, keys)
+
+	err := ts.ForClient("a").Save(ctx, testToken(1))
+	assert.NoErr(t, err)
+
+	// act: copy the sealed token of application a into the namespace of application b
+	stored, err := strava.ForClient(next, "a").Get(ctx, 1)
+	assert.NoErr(t, err)
+	assert.NoErr(t, strava.ForClient(next, "b").Save(ctx, stored))
+
+	_, errB := ts.ForClient("b").Get(ctx, 1)
+	gotA, errA := ts.ForClient("a").Get(ctx, 1)
+
+	// assert
+	assert.ErrSubMsg(t, errB, "open sealed token")
+	assert.NoErr(t, errA)
+	assert.Eq(t, "refresh", gotA.RefreshToken)
+}
+
+// sealV1 seals the token in the format used before the client namespace was bound to tokens.
+func sealV1(t *testing.T, keyID string, key []byte, token *strava.Token) *strava.Token {
+	t.Helper()
+
+	plaintext, err := json.Marshal(token)
+	assert.NoErr(t, err)
+	aead, err := newAEAD(key)
+	assert.NoErr(t, err)
+
+	nonce := make([]byte, aead.NonceSize())
+	ciphertext := aead.Seal(nonce, nonce, plaintext, additionalDataV1(token.AthleteID))
+
+	return &strava.Token{
+		Token:     &oauth2.Token{AccessToken: sealedPrefixV1 + keyID + ":" + base64.RawStdEncoding.EncodeToString(ciphertext)},
+		AthleteID: token.AthleteID,
+	}
+}
+
+func TestTokenStorage_MigrateV1(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	next := &inmemory.TokenStorage{}
+	key := bytes.Repeat([]byte{1}, 32)
+	keys, _ := NewKeyRing("k1", map[string][]byte{"k1": key})
+	ts := NewTokenStorage(next, keys).ForClient("a").(*TokenStorage)
+
+	err := strava.ForClient(next, "a").Save(ctx, sealV1(t, "k1", key, testToken(1)))
+	assert.NoErr(t, err)
+
+	got, err := ts.Get(ctx, 1)
+	assert.NoErr(t, err)
+	assert.Eq(t, "refresh", got.RefreshToken)
+
+	// act
+	n, err := ts.Migrate(ctx)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, n)
+
+	stored, _ := strava.ForClient(next, "a").Get(ctx, 1)
+	assert.True(t, strings.HasPrefix(stored.AccessToken, sealedPrefix+"k1:"))
+
+	got, err = ts.Get(ctx, 1)
+	assert.NoErr(t, err)
+	assert.Eq(t, "refresh", got.RefreshToken)
+}
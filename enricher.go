@@ -0,0 +1,254 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// EnrichedEvent is a webhook event with the resources it refers to.
+type EnrichedEvent struct {
+	Event
+
+	// Activity is set for activity create and update events.
+	Activity *DetailedActivity
+	// Laps and Streams are set if the Enricher is configured to fetch them.
+	Laps    []*Lap
+	Streams *StreamSet
+	// Athlete is set for athlete update events, except deauthorizations.
+	Athlete *DetailedAthlete
+
+	// Gone reports that the activity was deleted or made private before it could be fetched.
+	Gone bool
+}
+
+// EnrichedEventHandler handles webhook events with their fetched resources.
+type EnrichedEventHandler func(ctx context.Context, event EnrichedEvent) error
+
+type EnricherOption func(*Enricher)
+
+// WithEnrichDebounce delays activity create and update events by d, so the activity is fetched once
+// for a burst of events. The burst is passed to the handler as a single event with the merged updates,
+// whose aspect type is create if the burst contains one.
+//
+// Debounced events are handled after the EventHandler returned, so their errors are logged instead of retried.
+// NewEnricher refuses it for clients with an EventQueue, which would acknowledge the events before they're handled.
+func WithEnrichDebounce(d time.Duration) EnricherOption {
+	return func(e *Enricher) {
+		e.debounce = d
+	}
+}
+
+// WithEnrichLaps fetches the laps of activities.
+func WithEnrichLaps() EnricherOption {
+	return func(e *Enricher) {
+		e.laps = true
+	}
+}
+
+// WithEnrichStreams fetches the given streams of activities.
+func WithEnrichStreams(types ...StreamType) EnricherOption {
+	return func(e *Enricher) {
+		e.streams = types
+	}
+}
+
+// Enricher fetches the resources webhook events refer to before passing them to a handler.
+// Register its Handle method with Client.RegisterEventHandler.
+type Enricher struct {
+	c       *Client
+	handler EnrichedEventHandler
+
+	debounce time.Duration
+	laps     bool
+	streams  []StreamType
+
+	mu      sync.Mutex
+	pending map[uint]*pendingEvent
+	wg      sync.WaitGroup
+}
+
+type pendingEvent struct {
+	event Event
+	timer *time.Timer
+}
+
+func NewEnricher(c *Client, handler EnrichedEventHandler, opts ...EnricherOption) (*Enricher, error) {
+	e := &Enricher{
+		c:       c,
+		handler: handler,
+		pending: make(map[uint]*pendingEvent),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	if e.debounce > 0 && c.dispatcher.store != nil {
+		return nil, errors.New("enrich debounce can't be used with an event queue")
+	}
+
+	return e, nil
+}
+
+// Handle enriches the event and passes it to the handler, or delays it if debouncing.
+func (e *Enricher) Handle(ctx context.Context, event Event) error {
+	if event.ObjectType != EventObjectTypeActivity || e.debounce <= 0 {
+		return e.handle(ctx, event)
+	}
+
+	e.mu.Lock()
+	p, ok := e.pending[event.ObjectID]
+
+	if event.AspectType == EventAspectTypeDelete {
+		// The activity is gone, there's nothing left to fetch for the pending events.
+		if ok && p.timer.Stop() {
+			delete(e.pending, event.ObjectID)
+			e.wg.Done()
+		}
+		e.mu.Unlock()
+
+		return e.handle(ctx, event)
+	}
+
+	if ok {
+		p.event = mergeEvents(p.event, event)
+		e.mu.Unlock()
+		return nil
+	}
+
+	p = &pendingEvent{event: event}
+	e.pending[event.ObjectID] = p
+	e.wg.Add(1)
+	p.timer = time.AfterFunc(e.debounce, func() { e.fire(event.ObjectID) })
+	e.mu.Unlock()
+
+	return nil
+}
+
+// fire handles the pending event of the activity.
+func (e *Enricher) fire(activityID uint) {
+	e.mu.Lock()
+	p, ok := e.pending[activityID]
+	delete(e.pending, activityID)
+	e.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	defer e.wg.Done()
+
+	ctx := e.c.ctx
+	if err := e.handle(ctx, p.event); err != nil {
+		e.c.logger.ErrorContext(ctx, "debounced webhook event handled with error", slog.Any("event", p.event), slog.Any("error", err))
+	}
+}
+
+// Flush handles the pending debounced events without waiting for their debounce window,
+// and waits until they're handled. Call it before the client's Shutdown.
+func (e *Enricher) Flush(ctx context.Context) error {
+	e.mu.Lock()
+	for id, p := range e.pending {
+		if p.timer.Stop() {
+			go e.fire(id)
+		}
+	}
+	e.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		e.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// mergeEvents coalesces a later event of the same activity into an earlier one.
+func mergeEvents(earlier, later Event) Event {
+	merged := later
+	if earlier.AspectType == EventAspectTypeCreate {
+		merged.AspectType = EventAspectTypeCreate
+	}
+
+	if len(earlier.Updates) > 0 || len(later.Updates) > 0 {
+		merged.Updates = make(map[string]string, len(earlier.Updates)+len(later.Updates))
+		for k, v := range earlier.Updates {
+			merged.Updates[k] = v
+		}
+		for k, v := range later.Updates {
+			merged.Updates[k] = v
+		}
+	}
+
+	return merged
+}
+
+// handle fetches the event's resources and calls the handler.
+func (e *Enricher) handle(ctx context.Context, event Event) error {
+	enriched, err := e.enrich(ctx, event)
+	if err != nil {
+		return fmt.Errorf("enrich event: %w", err)
+	}
+
+	return e.handler(ctx, enriched)
+}
+
+func (e *Enricher) enrich(ctx context.Context, event Event) (EnrichedEvent, error) {
+	enriched := EnrichedEvent{Event: event}
+
+	switch {
+	case event.ObjectType == EventObjectTypeActivity && event.AspectType != EventAspectTypeDelete:
+		err := e.enrichActivity(ctx, &enriched)
+		if errors.Is(err, ErrNotFound) {
+			return EnrichedEvent{Event: event, Gone: true}, nil
+		}
+		if err != nil {
+			return enriched, err
+		}
+	case event.ObjectType == EventObjectTypeAthlete && event.AspectType == EventAspectTypeUpdate && !event.IsDeauthorization():
+		athlete, err := e.c.GetAthlete(ctx, event.OwnerID)
+		if err != nil {
+			return enriched, fmt.Errorf("get athlete %d: %w", event.OwnerID, err)
+		}
+		enriched.Athlete = athlete
+	}
+
+	return enriched, nil
+}
+
+func (e *Enricher) enrichActivity(ctx context.Context, enriched *EnrichedEvent) error {
+	athleteID, activityID := enriched.OwnerID, enriched.ObjectID
+
+	activity, err := e.c.GetDetailedActivity(ctx, athleteID, activityID)
+	if err != nil {
+		return fmt.Errorf("get activity %d: %w", activityID, err)
+	}
+	enriched.Activity = activity
+
+	if e.laps {
+		laps, err := e.c.GetActivityLaps(ctx, athleteID, activityID)
+		if err != nil {
+			return fmt.Errorf("get laps of activity %d: %w", activityID, err)
+		}
+		enriched.Laps = laps
+	}
+
+	if len(e.streams) > 0 {
+		streams, err := e.c.GetActivityStreams(ctx, athleteID, activityID, e.streams...)
+		if err != nil {
+			return fmt.Errorf("get streams of activity %d: %w", activityID, err)
+		}
+		enriched.Streams = streams
+	}
+
+	return nil
+}
@@ -0,0 +1,195 @@
+package strava
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+)
+
+// testActivityAPI emulates the activity and athlete endpoints. Activity 404 doesn't exist.
+type testActivityAPI struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (a *testActivityAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.calls[r.URL.Path]++
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/api/v3/activities/1":
+		_, _ = w.Write([]byte(`{"id":1,"name":"Morning Ride"}`))
+	case "/api/v3/activities/1/laps":
+		_, _ = w.Write([]byte(`[{"id":11},{"id":12}]`))
+	case "/api/v3/activities/1/streams":
+		_, _ = w.Write([]byte(`{"time":{"data":[0,1,2],"series_type":"distance","original_size":3,"resolution":"high"},"latlng":{"data":[[47.1,8.5],[47.2,8.6],[47.3,8.7]]}}`))
+	case "/api/v3/athlete":
+		_, _ = w.Write([]byte(`{"id":7,"firstname":"Jane"}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"message":"Record Not Found","errors":[{"resource":"Activity","field":"id","code":"not found"}]}`))
+	}
+}
+
+func (a *testActivityAPI) count(path string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return a.calls[path]
+}
+
+func newTestEnricherClient(t *testing.T, api http.Handler) *Client {
+	ts := newTestTokenStorage(&Token{
+		Token:     &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)},
+		AthleteID: 7,
+	})
+
+	return NewClient("client_id", "client_secret", "", ts, WithTransport(newTestAPITransport(t, api)))
+}
+
+func TestEnricher_Handle(t *testing.T) {
+	tests := []struct {
+		name  string
+		event Event
+		check func(t *testing.T, e EnrichedEvent)
+	}{
+		{
+			name:  "activity",
+			event: Event{ObjectType: EventObjectTypeActivity, ObjectID: 1, AspectType: EventAspectTypeCreate, OwnerID: 7},
+			check: func(t *testing.T, e EnrichedEvent) {
+				assert.Eq(t, "Morning Ride", e.Activity.Name)
+				assert.Len(t, e.Laps, 2)
+				assert.Eq(t, []int{0, 1, 2}, e.Streams.Time.Data)
+				assert.Eq(t, [2]float64{47.3, 8.7}, e.Streams.LatLng.Data[2])
+				assert.Nil(t, e.Streams.Heartrate)
+				assert.False(t, e.Gone)
+			},
+		},
+		{
+			name:  "gone activity",
+			event: Event{ObjectType: EventObjectTypeActivity, ObjectID: 404, AspectType: EventAspectTypeUpdate, OwnerID: 7},
+			check: func(t *testing.T, e EnrichedEvent) {
+				assert.Nil(t, e.Activity)
+				assert.True(t, e.Gone)
+			},
+		},
+		{
+			name:  "deleted activity",
+			event: Event{ObjectType: EventObjectTypeActivity, ObjectID: 1, AspectType: EventAspectTypeDelete, OwnerID: 7},
+			check: func(t *testing.T, e EnrichedEvent) {
+				assert.Nil(t, e.Activity)
+				assert.False(t, e.Gone)
+			},
+		},
+		{
+			name:  "athlete",
+			event: Event{ObjectType: EventObjectTypeAthlete, ObjectID: 7, AspectType: EventAspectTypeUpdate, OwnerID: 7},
+			check: func(t *testing.T, e EnrichedEvent) {
+				assert.Eq(t, "Jane", e.Athlete.FirstName)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			c := newTestEnricherClient(t, &testActivityAPI{calls: map[string]int{}})
+
+			var got EnrichedEvent
+			e, err := NewEnricher(c, func(ctx context.Context, event EnrichedEvent) error {
+				got = event
+				return nil
+			}, WithEnrichLaps(), WithEnrichStreams(StreamTypeTime, StreamTypeLatLng))
+			assert.NoErr(t, err)
+
+			// act
+			err = e.Handle(context.Background(), tt.event)
+
+			// assert
+			assert.NoErr(t, err)
+			assert.Eq(t, tt.event, got.Event)
+			tt.check(t, got)
+		})
+	}
+}
+
+func TestEnricher_Debounce(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	api := &testActivityAPI{calls: map[string]int{}}
+	c := newTestEnricherClient(t, api)
+
+	var (
+		mu  sync.Mutex
+		got []EnrichedEvent
+	)
+	e, err := NewEnricher(c, func(ctx context.Context, event EnrichedEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, event)
+		return nil
+	}, WithEnrichDebounce(time.Hour))
+	assert.NoErr(t, err)
+
+	burst := []Event{
+		{ObjectType: EventObjectTypeActivity, ObjectID: 1, AspectType: EventAspectTypeCreate, OwnerID: 7},
+		{ObjectType: EventObjectTypeActivity, ObjectID: 1, AspectType: EventAspectTypeUpdate, OwnerID: 7, Updates: map[string]string{"title": "Lunch Ride"}},
+		{ObjectType: EventObjectTypeActivity, ObjectID: 1, AspectType: EventAspectTypeUpdate, OwnerID: 7, Updates: map[string]string{"type": "Ride"}},
+		// The pending events of a deleted activity are dropped.
+		{ObjectType: EventObjectTypeActivity, ObjectID: 2, AspectType: EventAspectTypeCreate, OwnerID: 7},
+		{ObjectType: EventObjectTypeActivity, ObjectID: 2, AspectType: EventAspectTypeDelete, OwnerID: 7},
+	}
+
+	// act
+	for _, event := range burst {
+		assert.NoErr(t, e.Handle(ctx, event))
+	}
+	err = e.Flush(ctx)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 1, api.count("/api/v3/activities/1"))
+	assert.Eq(t, 0, api.count("/api/v3/activities/2"))
+	assert.Len(t, got, 2)
+
+	assert.Eq(t, EventAspectTypeDelete, got[0].AspectType)
+	assert.Eq(t, uint(2), got[0].ObjectID)
+
+	assert.Eq(t, EventAspectTypeCreate, got[1].AspectType)
+	assert.Eq(t, map[string]string{"title": "Lunch Ride", "type": "Ride"}, got[1].Updates)
+	assert.Eq(t, "Morning Ride", got[1].Activity.Name)
+}
+
+func TestStatusError(t *testing.T) {
+	// arrange
+	c := newTestEnricherClient(t, &testActivityAPI{calls: map[string]int{}})
+
+	// act
+	_, err := c.GetDetailedActivity(context.Background(), 7, 404)
+
+	// assert
+	assert.ErrIs(t, err, ErrNotFound)
+	assert.ErrMsg(t, err, "could not call strava: API error (status code 404): {[{not found id Activity}] Record Not Found }")
+}
+
+func TestNewEnricher_DebounceWithEventQueue(t *testing.T) {
+	// arrange
+	c := NewClient("client_id", "client_secret", "", nil, WithEventQueue(newTestEventQueue()))
+	handler := func(ctx context.Context, event EnrichedEvent) error { return nil }
+
+	// act
+	_, debounceErr := NewEnricher(c, handler, WithEnrichDebounce(time.Second))
+	_, err := NewEnricher(c, handler, WithEnrichLaps())
+
+	// assert
+	assert.ErrSubMsg(t, debounceErr, "event queue")
+	assert.NoErr(t, err)
+}
@@ -3,12 +3,49 @@ package strava
 import (
 	"errors"
 	"fmt"
+	"net/http"
 )
 
 var (
 	ErrTokenNotFound = errors.New("token not found")
+	ErrTokenRevoked  = errors.New("token revoked")
+
+	ErrNamespacesUnsupported = errors.New("token storage doesn't support namespaces")
+
+	// ErrNotFound matches StatusErrors of missing resources, e.g. deleted or private activities.
+	ErrNotFound = errors.New("resource not found")
+	// ErrRateLimited matches StatusErrors of requests rejected for exceeding a rate limit, see Client.RateLimit.
+	ErrRateLimited = errors.New("rate limit exceeded")
 )
 
+// StatusError is returned for Strava API responses with an error status code.
+type StatusError struct {
+	StatusCode int
+	// Fault is the decoded response body, nil if the body isn't a Strava fault.
+	Fault *Fault
+	Body  []byte
+}
+
+func (e *StatusError) Error() string {
+	var details any = string(e.Body)
+	if e.Fault != nil {
+		details = *e.Fault
+	}
+
+	return fmt.Sprintf("API error (status code %d): %v", e.StatusCode, details)
+}
+
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	}
+
+	return false
+}
+
 type APIError struct {
 	Status   int    `json:"status"`
 	Resource string `json:"resource"`
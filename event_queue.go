@@ -0,0 +1,75 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	DefaultEventMaxAttempts = 8
+	DefaultEventMinBackoff  = time.Second
+	DefaultEventMaxBackoff  = 10 * time.Minute
+
+	// eventClaimTimeout is how long a claimed event stays invisible to other workers.
+	// Events of a crashed process are claimed again after it.
+	eventClaimTimeout = 10 * time.Minute
+	// eventPollInterval is how often idle workers look for due events.
+	eventPollInterval = 5 * time.Second
+)
+
+var (
+	// ErrNoEventDue is returned by EventQueue.Claim when no event is ready for processing.
+	ErrNoEventDue = errors.New("no event due")
+	// ErrEventNotFound is returned for missing events, and by Ack, Retry and DeadLetter if the event
+	// was claimed again since, e.g. after the claim timed out.
+	ErrEventNotFound = errors.New("event not found")
+)
+
+// QueuedEvent is a webhook event persisted in an EventQueue.
+type QueuedEvent struct {
+	ID    uint64 `json:"id"`
+	Event Event  `json:"event"`
+
+	// Attempts counts the claims of the event, including the current one.
+	Attempts int `json:"attempts"`
+	// NextAttempt is when the event can be claimed again.
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+	EnqueuedAt  time.Time `json:"enqueued_at"`
+}
+
+// EventQueue persists webhook events until they are handled. Events failing more than the allowed
+// number of attempts are moved to the dead letters, from which they can be replayed.
+//
+// Ack, Retry and DeadLetter take the Attempts of the claimed event as its lease, and only change the
+// event if it wasn't claimed again since.
+type EventQueue interface {
+	Enqueue(ctx context.Context, event Event) error
+	// Claim returns the due event with the earliest NextAttempt, increments its attempts and hides it
+	// from other claims for the timeout. It returns ErrNoEventDue if no event is due.
+	Claim(ctx context.Context, timeout time.Duration) (*QueuedEvent, error)
+	// Ack removes the handled event.
+	Ack(ctx context.Context, id uint64, attempts int) error
+	// Retry makes the event due again at next.
+	Retry(ctx context.Context, id uint64, attempts int, next time.Time, reason string) error
+	// DeadLetter moves the event to the dead letters.
+	DeadLetter(ctx context.Context, id uint64, attempts int, reason string) error
+
+	DeadLetters(ctx context.Context) ([]*QueuedEvent, error)
+	// Replay moves the dead letter back to the queue with its attempts reset.
+	Replay(ctx context.Context, id uint64) error
+}
+
+// eventBackoff returns the delay before the next attempt of an event that failed the given number of times.
+func eventBackoff(attempts int, minDelay, maxDelay time.Duration) time.Duration {
+	d := minDelay
+	for i := 1; i < attempts && d < maxDelay; i++ {
+		d *= 2
+	}
+
+	if d > maxDelay {
+		return maxDelay
+	}
+	return d
+}
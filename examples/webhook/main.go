@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/caarlos0/env/v11"
 	"github.com/marvell/strava-go"
@@ -44,18 +45,25 @@ func main() {
 		failIfError(err)
 	}()
 
-	err = cl.InitWebhook(ctx)
-	failIfError(err)
-
-	err = cl.RegisterEventHandler(func(event strava.Event) error {
+	// Handlers are registered first, InitWebhook starts handling events.
+	err = cl.RegisterEventHandler(func(ctx context.Context, event strava.Event) error {
 		slog.Info("got new event", "event", event)
 		return nil
 	})
 	failIfError(err)
 
+	err = cl.InitWebhook(ctx)
+	failIfError(err)
+
 	<-ctx.Done()
 
 	// clean up
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer shutdownCancel()
+
+	err = cl.Shutdown(shutdownCtx)
+	failIfError(err)
+
 	err = cl.CloseWebhook(context.Background())
 	failIfError(err)
 }
@@ -0,0 +1,177 @@
+// Package export writes the activities of athletes to columnar files for analysis, as Parquet or CSV.
+// Activities are streamed from the API page by page, so an export of years of history is never held in
+// memory at once.
+package export
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// DefaultStreams are the streams exported as samples unless set with WithSamples.
+var DefaultStreams = []strava.StreamType{
+	strava.StreamTypeTime,
+	strava.StreamTypeDistance,
+	strava.StreamTypeLatLng,
+	strava.StreamTypeAltitude,
+	strava.StreamTypeVelocitySmooth,
+	strava.StreamTypeHeartrate,
+	strava.StreamTypeCadence,
+	strava.StreamTypeWatts,
+	strava.StreamTypeTemp,
+	strava.StreamTypeMoving,
+	strava.StreamTypeGradeSmooth,
+}
+
+type Option func(*Exporter)
+
+// WithLaps exports the laps of each activity to w, at the cost of a request per activity.
+func WithLaps(w Writer[Lap]) Option {
+	return func(e *Exporter) {
+		e.laps = w
+	}
+}
+
+// WithSplits exports the kilometer and mile splits of each activity to w, at the cost of a request
+// per activity for its details. The laps are taken from the details too.
+func WithSplits(w Writer[Split]) Option {
+	return func(e *Exporter) {
+		e.splits = w
+	}
+}
+
+// WithSamples exports the given streams of each activity to w, at the cost of a request per activity.
+// The time stream is always fetched. By default DefaultStreams are exported.
+func WithSamples(w Writer[Sample], types ...strava.StreamType) Option {
+	return func(e *Exporter) {
+		e.samples = w
+		if len(types) > 0 {
+			e.streams = append([]strava.StreamType{strava.StreamTypeTime}, types...)
+		}
+	}
+}
+
+// Exporter writes the activities of athletes and optionally their laps, splits and stream samples.
+type Exporter struct {
+	c          *strava.Client
+	activities Writer[Activity]
+	laps       Writer[Lap]
+	splits     Writer[Split]
+	samples    Writer[Sample]
+	streams    []strava.StreamType
+}
+
+func New(c *strava.Client, activities Writer[Activity], opts ...Option) *Exporter {
+	e := &Exporter{
+		c:          c,
+		activities: activities,
+		streams:    DefaultStreams,
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// Export writes the activities the athlete started between from and to, and returns their number.
+// An activity is written after its laps, splits and samples were fetched, so activities deleted while
+// exporting are skipped with all their records. The writers aren't closed, so the activities of several
+// athletes can be exported to the same files.
+func (e *Exporter) Export(ctx context.Context, athleteID uint, from, to time.Time) (int, error) {
+	exported := 0
+	err := e.c.GetSummaryActivitiesWithCallback(ctx, athleteID, from, to, func(page []*strava.SummaryActivity) error {
+		records := make([]Activity, 0, len(page))
+		for _, a := range page {
+			ok, err := e.exportActivity(ctx, athleteID, a)
+			if err != nil {
+				return err
+			}
+			if ok {
+				records = append(records, ActivityRecord(athleteID, a))
+			}
+		}
+
+		if _, err := e.activities.Write(records); err != nil {
+			return fmt.Errorf("write activities: %w", err)
+		}
+
+		exported += len(records)
+		return nil
+	})
+	if err != nil {
+		return exported, fmt.Errorf("export activities of athlete %d: %w", athleteID, err)
+	}
+
+	return exported, nil
+}
+
+// exportActivity fetches the laps, splits and samples of the activity and writes them. Nothing is written
+// and false is returned if the activity was deleted.
+func (e *Exporter) exportActivity(ctx context.Context, athleteID uint, a *strava.SummaryActivity) (bool, error) {
+	var (
+		splits  []Split
+		laps    []*strava.Lap
+		samples []Sample
+	)
+
+	if e.splits != nil {
+		d, err := e.c.GetDetailedActivity(ctx, athleteID, a.ID)
+		if errors.Is(err, strava.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("get activity %d: %w", a.ID, err)
+		}
+
+		splits = SplitRecords(athleteID, d)
+		laps = d.Laps
+	}
+
+	if e.laps != nil && laps == nil {
+		var err error
+		laps, err = e.c.GetActivityLaps(ctx, athleteID, a.ID)
+		if errors.Is(err, strava.ErrNotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("get laps of activity %d: %w", a.ID, err)
+		}
+	}
+
+	if e.samples != nil {
+		streams, err := e.c.GetActivityStreams(ctx, athleteID, a.ID, e.streams...)
+		// Manual activities have no streams.
+		if err != nil && !errors.Is(err, strava.ErrNotFound) {
+			return false, fmt.Errorf("get streams of activity %d: %w", a.ID, err)
+		}
+		if err == nil {
+			samples = SampleRecords(athleteID, a, streams)
+		}
+	}
+
+	if e.splits != nil {
+		if _, err := e.splits.Write(splits); err != nil {
+			return false, fmt.Errorf("write splits: %w", err)
+		}
+	}
+
+	if e.laps != nil {
+		if _, err := e.laps.Write(LapRecords(athleteID, a.ID, laps)); err != nil {
+			return false, fmt.Errorf("write laps: %w", err)
+		}
+	}
+
+	if e.samples != nil {
+		if _, err := e.samples.Write(samples); err != nil {
+			return false, fmt.Errorf("write samples: %w", err)
+		}
+	}
+
+	return true, nil
+}
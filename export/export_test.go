@@ -0,0 +1,265 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"github.com/parquet-go/parquet-go"
+	"golang.org/x/oauth2"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/inmemory"
+)
+
+var testStart = time.Date(2024, 5, 31, 6, 0, 0, 0, time.UTC)
+
+// testAPI emulates the activity endpoints for athlete 7, with activities 1 to n. Odd activities are
+// manual and have no streams. Deleted activities are listed, but their other endpoints aren't found.
+type testAPI struct {
+	n       int
+	deleted map[int]bool
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var id int
+	switch {
+	case r.URL.Path == "/api/v3/athlete/activities":
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		perPage, _ := strconv.Atoi(r.URL.Query().Get("per_page"))
+
+		activities := []*strava.SummaryActivity{}
+		for id := (page-1)*perPage + 1; id <= min(page*perPage, a.n); id++ {
+			start := testStart.Add(time.Duration(id) * time.Hour)
+			activities = append(activities, &strava.SummaryActivity{
+				ID:             uint(id),
+				Name:           fmt.Sprintf("Activity %d", id),
+				StartDate:      start,
+				StartDateLocal: start.Add(2 * time.Hour),
+				Timezone:       "(GMT+01:00) Europe/Berlin",
+				StartLatLng:    []float64{52.5, 13.4},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(activities)
+		return
+	case scan(r.URL.Path, "/api/v3/activities/%d/laps", &id) && !a.deleted[id]:
+		_, _ = fmt.Fprintf(w, `[{"id":%d1,"lap_index":1},{"id":%d2,"lap_index":2}]`, id, id)
+		return
+	case scan(r.URL.Path, "/api/v3/activities/%d/streams", &id):
+		if id%2 == 0 && !a.deleted[id] {
+			_, _ = w.Write([]byte(`{"time":{"data":[0,1,2]},"heartrate":{"data":[120,121,122]},"latlng":{"data":[[52.5,13.4],[52.6,13.5],[52.7,13.6]]}}`))
+			return
+		}
+	case scan(r.URL.Path, "/api/v3/activities/%d", &id) && !a.deleted[id]:
+		_, _ = fmt.Fprintf(w, `{"id":%d,"splits_metric":[{"split":1},{"split":2}],"splits_standard":[{"split":1}],"laps":[{"id":%d1}]}`, id, id)
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	_, _ = w.Write([]byte(`{"message":"Record Not Found"}`))
+}
+
+func scan(path, format string, id *int) bool {
+	var rest string
+	n, _ := fmt.Sscanf(path+" end", format+" %s", id, &rest)
+	return n == 2 && rest == "end"
+}
+
+// newTestClient returns a client with a token of the API's athlete 7.
+func newTestClient(t *testing.T, api http.Handler) *strava.Client {
+	srv := httptest.NewTLSServer(api)
+	t.Cleanup(srv.Close)
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+
+	ts := &inmemory.TokenStorage{}
+	err := ts.Save(context.Background(), &strava.Token{
+		Token:     &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)},
+		AthleteID: 7,
+	})
+	assert.NoErr(t, err)
+
+	return strava.NewClient("client_id", "client_secret", "", ts, strava.WithTransport(transport))
+}
+
+func readParquet[T any](t *testing.T, data []byte) []T {
+	r := parquet.NewGenericReader[T](bytes.NewReader(data))
+	defer r.Close()
+
+	records := make([]T, r.NumRows())
+	n, err := r.Read(records)
+	if err != nil && n != len(records) {
+		t.Fatal(err)
+	}
+
+	return records
+}
+
+func TestExporter_Export(t *testing.T) {
+	// arrange
+	c := newTestClient(t, &testAPI{n: 150})
+
+	var activities, laps, samples bytes.Buffer
+	aw := NewParquetWriter[Activity](&activities)
+	lw := NewParquetWriter[Lap](&laps)
+	sw := NewParquetWriter[Sample](&samples)
+
+	e := New(c, aw, WithLaps(lw), WithSamples(sw))
+
+	// act
+	n, err := e.Export(context.Background(), 7, testStart, testStart.Add(365*24*time.Hour))
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 150, n)
+	assert.NoErr(t, aw.Close())
+	assert.NoErr(t, lw.Close())
+	assert.NoErr(t, sw.Close())
+
+	a := readParquet[Activity](t, activities.Bytes())
+	assert.Len(t, a, 150)
+	assert.Eq(t, int64(7), a[0].AthleteID)
+	assert.Eq(t, int64(150), a[149].ID)
+	assert.Eq(t, "Europe/Berlin", a[0].Timezone)
+	assert.Eq(t, int32(7200), a[0].UTCOffset)
+	assert.True(t, a[0].StartDate.Equal(testStart.Add(time.Hour)))
+	assert.Eq(t, 52.5, *a[0].StartLat)
+	assert.Nil(t, a[0].EndLat)
+
+	assert.Len(t, readParquet[Lap](t, laps.Bytes()), 300)
+
+	s := readParquet[Sample](t, samples.Bytes())
+	assert.Len(t, s, 225)
+	assert.Eq(t, int64(2), s[0].ActivityID)
+	assert.True(t, s[1].Time.Equal(testStart.Add(2*time.Hour+time.Second)))
+	assert.Eq(t, int32(121), *s[1].Heartrate)
+	assert.Eq(t, 13.5, *s[1].Lng)
+	assert.Nil(t, s[1].Watts)
+}
+
+func TestExporter_ExportSplits(t *testing.T) {
+	// arrange
+	c := newTestClient(t, &testAPI{n: 2})
+
+	var activities, splits, laps bytes.Buffer
+	e := New(c, NewCSVWriter[Activity](&activities),
+		WithSplits(NewCSVWriter[Split](&splits)),
+		WithLaps(NewCSVWriter[Lap](&laps)),
+	)
+
+	// act
+	n, err := e.Export(context.Background(), 7, testStart, testStart.Add(24*time.Hour))
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 2, n)
+	assert.NoErr(t, e.activities.Close())
+	assert.NoErr(t, e.splits.Close())
+	assert.NoErr(t, e.laps.Close())
+
+	lines := strings.Split(strings.TrimSpace(activities.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[0], "athlete_id,id,external_id,"))
+	assert.StrContains(t, lines[1], ",2024-05-31T07:00:00Z,2024-05-31T09:00:00,Europe/Berlin,7200,")
+	// The end coordinates are null.
+	assert.StrContains(t, lines[1], ",52.5,13.4,,,")
+
+	lines = strings.Split(strings.TrimSpace(splits.String()), "\n")
+	assert.Len(t, lines, 7)
+	assert.True(t, strings.HasPrefix(lines[3], "7,1,standard,1,"))
+
+	// Laps are taken from the details.
+	lines = strings.Split(strings.TrimSpace(laps.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.True(t, strings.HasPrefix(lines[1], "7,1,11,"))
+}
+
+func TestExporter_ExportDeleted(t *testing.T) {
+	for _, withSplits := range []bool{false, true} {
+		t.Run(fmt.Sprintf("splits=%t", withSplits), func(t *testing.T) {
+			// arrange
+			c := newTestClient(t, &testAPI{n: 4, deleted: map[int]bool{2: true}})
+
+			var activities, splits, laps, samples bytes.Buffer
+			opts := []Option{WithLaps(NewCSVWriter[Lap](&laps)), WithSamples(NewCSVWriter[Sample](&samples))}
+			if withSplits {
+				opts = append(opts, WithSplits(NewCSVWriter[Split](&splits)))
+			}
+			e := New(c, NewCSVWriter[Activity](&activities), opts...)
+
+			// act
+			n, err := e.Export(context.Background(), 7, testStart, testStart.Add(24*time.Hour))
+
+			// assert
+			assert.NoErr(t, err)
+			assert.Eq(t, 3, n)
+			assert.NoErr(t, e.activities.Close())
+			assert.NoErr(t, e.laps.Close())
+			assert.NoErr(t, e.samples.Close())
+
+			lines := strings.Split(strings.TrimSpace(activities.String()), "\n")
+			assert.Len(t, lines, 4)
+			assert.True(t, strings.HasPrefix(lines[1], "7,1,"))
+			assert.True(t, strings.HasPrefix(lines[2], "7,3,"))
+
+			// Only activity 4 has samples.
+			lines = strings.Split(strings.TrimSpace(samples.String()), "\n")
+			assert.Len(t, lines, 4)
+			for _, line := range lines[1:] {
+				assert.True(t, strings.HasPrefix(line, "7,4,"))
+			}
+
+			for _, line := range strings.Split(strings.TrimSpace(laps.String()), "\n")[1:] {
+				assert.False(t, strings.HasPrefix(line, "7,2,"))
+			}
+			if withSplits {
+				assert.NoErr(t, e.splits.Close())
+				assert.NotContains(t, splits.String(), "\n7,2,")
+			}
+		})
+	}
+}
+
+func TestParquetSchema(t *testing.T) {
+	// act
+	schema := parquet.SchemaOf(Activity{}).String()
+
+	// assert
+	assert.StrContains(t, schema, "int64 start_date (TIMESTAMP(isAdjustedToUTC=true,unit=MILLIS))")
+	assert.StrContains(t, schema, "int64 start_date_local (TIMESTAMP(isAdjustedToUTC=false,unit=MILLIS))")
+	assert.StrContains(t, schema, "optional double start_lat")
+}
+
+func TestNewWriter(t *testing.T) {
+	// act
+	_, err := NewWriter[Activity]("xlsx", &bytes.Buffer{})
+
+	// assert
+	assert.Err(t, err)
+
+	// act
+	var buf bytes.Buffer
+	w, err := NewWriter[Sample](FormatCSV, &buf)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.NoErr(t, w.Close())
+	assert.Eq(t, "athlete_id,activity_id,time,elapsed,distance,lat,lng,altitude,velocity_smooth,heartrate,cadence,watts,temp,moving,grade_smooth\n", buf.String())
+}
@@ -0,0 +1,319 @@
+package export
+
+import (
+	"strings"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// The records below are the schema of the exported files. Columns are only ever appended, so notebooks
+// reading older files keep working. Instants are UTC timestamps, local dates are timestamps without a
+// time zone, i.e. the wall clock time of the activity's timezone. The Record functions convert API
+// values to records, for callers writing records of activities they fetched themselves.
+
+// Activity is a record of an activity summary.
+type Activity struct {
+	AthleteID      int64     `parquet:"athlete_id"`
+	ID             int64     `parquet:"id"`
+	ExternalID     string    `parquet:"external_id"`
+	UploadID       int64     `parquet:"upload_id"`
+	Name           string    `parquet:"name"`
+	Type           string    `parquet:"type"`
+	SportType      string    `parquet:"sport_type"`
+	WorkoutType    int32     `parquet:"workout_type"`
+	StartDate      time.Time `parquet:"start_date,timestamp(millisecond:utc)"`
+	StartDateLocal time.Time `parquet:"start_date_local,timestamp(millisecond:local)"`
+	// Timezone is the IANA name of the activity's time zone, e.g. "Europe/Berlin".
+	Timezone string `parquet:"timezone"`
+	// UTCOffset is the offset of the local start date from UTC in seconds.
+	UTCOffset            int32    `parquet:"utc_offset"`
+	Distance             float64  `parquet:"distance"`
+	MovingTime           int32    `parquet:"moving_time"`
+	ElapsedTime          int32    `parquet:"elapsed_time"`
+	TotalElevationGain   float64  `parquet:"total_elevation_gain"`
+	ElevHigh             float64  `parquet:"elev_high"`
+	ElevLow              float64  `parquet:"elev_low"`
+	StartLat             *float64 `parquet:"start_lat"`
+	StartLng             *float64 `parquet:"start_lng"`
+	EndLat               *float64 `parquet:"end_lat"`
+	EndLng               *float64 `parquet:"end_lng"`
+	AchievementCount     int32    `parquet:"achievement_count"`
+	KudosCount           int32    `parquet:"kudos_count"`
+	CommentCount         int32    `parquet:"comment_count"`
+	AthleteCount         int32    `parquet:"athlete_count"`
+	PhotoCount           int32    `parquet:"photo_count"`
+	Trainer              bool     `parquet:"trainer"`
+	Commute              bool     `parquet:"commute"`
+	Manual               bool     `parquet:"manual"`
+	Private              bool     `parquet:"private"`
+	Flagged              bool     `parquet:"flagged"`
+	GearID               string   `parquet:"gear_id"`
+	AverageSpeed         float64  `parquet:"average_speed"`
+	MaxSpeed             float64  `parquet:"max_speed"`
+	AverageCadence       float64  `parquet:"average_cadence"`
+	AverageTemp          float64  `parquet:"average_temp"`
+	AverageWatts         float64  `parquet:"average_watts"`
+	WeightedAverageWatts int32    `parquet:"weighted_average_watts"`
+	MaxWatts             int32    `parquet:"max_watts"`
+	Kilojoules           float64  `parquet:"kilojoules"`
+	DeviceWatts          bool     `parquet:"device_watts"`
+	HasHeartrate         bool     `parquet:"has_heartrate"`
+	AverageHeartrate     float64  `parquet:"average_heartrate"`
+	MaxHeartrate         float64  `parquet:"max_heartrate"`
+	SufferScore          int32    `parquet:"suffer_score"`
+	MapSummaryPolyline   string   `parquet:"map_summary_polyline"`
+}
+
+// Lap is a record of an activity lap.
+type Lap struct {
+	AthleteID          int64     `parquet:"athlete_id"`
+	ActivityID         int64     `parquet:"activity_id"`
+	ID                 int64     `parquet:"id"`
+	LapIndex           int32     `parquet:"lap_index"`
+	Split              int32     `parquet:"split"`
+	Name               string    `parquet:"name"`
+	StartDate          time.Time `parquet:"start_date,timestamp(millisecond:utc)"`
+	StartDateLocal     time.Time `parquet:"start_date_local,timestamp(millisecond:local)"`
+	ElapsedTime        int32     `parquet:"elapsed_time"`
+	MovingTime         int32     `parquet:"moving_time"`
+	Distance           float64   `parquet:"distance"`
+	StartIndex         int32     `parquet:"start_index"`
+	EndIndex           int32     `parquet:"end_index"`
+	TotalElevationGain float64   `parquet:"total_elevation_gain"`
+	AverageSpeed       float64   `parquet:"average_speed"`
+	MaxSpeed           float64   `parquet:"max_speed"`
+	AverageCadence     float64   `parquet:"average_cadence"`
+	AverageWatts       float64   `parquet:"average_watts"`
+	DeviceWatts        bool      `parquet:"device_watts"`
+	AverageHeartrate   float64   `parquet:"average_heartrate"`
+}
+
+// Split is a record of a kilometer or mile split of an activity.
+type Split struct {
+	AthleteID  int64 `parquet:"athlete_id"`
+	ActivityID int64 `parquet:"activity_id"`
+	// Units is "metric" for kilometer splits and "standard" for mile splits.
+	Units                     string  `parquet:"units"`
+	Split                     int32   `parquet:"split"`
+	Distance                  float64 `parquet:"distance"`
+	ElapsedTime               int32   `parquet:"elapsed_time"`
+	MovingTime                int32   `parquet:"moving_time"`
+	ElevationDifference       float64 `parquet:"elevation_difference"`
+	AverageSpeed              float64 `parquet:"average_speed"`
+	AverageGradeAdjustedSpeed float64 `parquet:"average_grade_adjusted_speed"`
+	AverageHeartrate          float64 `parquet:"average_heartrate"`
+	PaceZone                  int32   `parquet:"pace_zone"`
+}
+
+// Sample is a record of the activity streams at one point in time. Values of streams the activity
+// doesn't have, or that weren't exported, are null.
+type Sample struct {
+	AthleteID  int64     `parquet:"athlete_id"`
+	ActivityID int64     `parquet:"activity_id"`
+	Time       time.Time `parquet:"time,timestamp(millisecond:utc)"`
+	// Elapsed is the number of seconds since the start of the activity.
+	Elapsed        int32    `parquet:"elapsed"`
+	Distance       *float64 `parquet:"distance"`
+	Lat            *float64 `parquet:"lat"`
+	Lng            *float64 `parquet:"lng"`
+	Altitude       *float64 `parquet:"altitude"`
+	VelocitySmooth *float64 `parquet:"velocity_smooth"`
+	Heartrate      *int32   `parquet:"heartrate"`
+	Cadence        *int32   `parquet:"cadence"`
+	Watts          *int32   `parquet:"watts"`
+	Temp           *int32   `parquet:"temp"`
+	Moving         *bool    `parquet:"moving"`
+	GradeSmooth    *float64 `parquet:"grade_smooth"`
+}
+
+// ActivityRecord returns the record of the athlete's activity.
+func ActivityRecord(athleteID uint, a *strava.SummaryActivity) Activity {
+	r := Activity{
+		AthleteID:            int64(athleteID),
+		ID:                   int64(a.ID),
+		ExternalID:           a.ExternalID,
+		UploadID:             int64(a.UploadID),
+		Name:                 a.Name,
+		Type:                 string(a.Type),
+		SportType:            string(a.SportType),
+		WorkoutType:          int32(a.WorkoutType),
+		StartDate:            a.StartDate.UTC(),
+		StartDateLocal:       a.StartDateLocal,
+		Timezone:             timezone(a.Timezone),
+		UTCOffset:            int32(utcOffset(a).Seconds()),
+		Distance:             a.Distance,
+		MovingTime:           int32(a.MovingTime),
+		ElapsedTime:          int32(a.ElapsedTime),
+		TotalElevationGain:   a.TotalElevationGain,
+		ElevHigh:             a.ElevHigh,
+		ElevLow:              a.ElevLow,
+		AchievementCount:     int32(a.AchievementCount),
+		KudosCount:           int32(a.KudosCount),
+		CommentCount:         int32(a.CommentCount),
+		AthleteCount:         int32(a.AthleteCount),
+		PhotoCount:           int32(a.PhotoCount),
+		Trainer:              a.Trainer,
+		Commute:              a.Commute,
+		Manual:               a.Manual,
+		Private:              a.Private,
+		Flagged:              a.Flagged,
+		GearID:               a.GearID,
+		AverageSpeed:         a.AverageSpeed,
+		MaxSpeed:             a.MaxSpeed,
+		AverageCadence:       a.AverageCadence,
+		AverageTemp:          a.AverageTemp,
+		AverageWatts:         a.AverageWatts,
+		WeightedAverageWatts: int32(a.WeightedAverageWatts),
+		MaxWatts:             int32(a.MaxWatts),
+		Kilojoules:           a.Kilojoules,
+		DeviceWatts:          a.DeviceWatts,
+		HasHeartrate:         a.HasHeartrate,
+		AverageHeartrate:     a.AverageHeartrate,
+		MaxHeartrate:         a.MaxHeartrate,
+		SufferScore:          int32(a.SufferScore),
+	}
+
+	if len(a.StartLatLng) == 2 {
+		r.StartLat, r.StartLng = &a.StartLatLng[0], &a.StartLatLng[1]
+	}
+	if len(a.EndLatLng) == 2 {
+		r.EndLat, r.EndLng = &a.EndLatLng[0], &a.EndLatLng[1]
+	}
+	if a.Map != nil {
+		r.MapSummaryPolyline = a.Map.SummaryPolyline
+	}
+
+	return r
+}
+
+// timezone returns the IANA name of a Strava timezone like "(GMT+01:00) Europe/Berlin".
+func timezone(v string) string {
+	if i := strings.LastIndexByte(v, ' '); i >= 0 {
+		return v[i+1:]
+	}
+
+	return v
+}
+
+// utcOffset returns the offset of the activity's local start date from UTC. Strava encodes local dates
+// as UTC timestamps of the wall clock time.
+func utcOffset(a *strava.SummaryActivity) time.Duration {
+	if a.StartDateLocal.IsZero() {
+		return 0
+	}
+
+	return a.StartDateLocal.Sub(a.StartDate)
+}
+
+// LapRecords returns the records of the activity's laps.
+func LapRecords(athleteID, activityID uint, laps []*strava.Lap) []Lap {
+	records := make([]Lap, 0, len(laps))
+	for _, l := range laps {
+		records = append(records, Lap{
+			AthleteID:          int64(athleteID),
+			ActivityID:         int64(activityID),
+			ID:                 int64(l.ID),
+			LapIndex:           int32(l.LapIndex),
+			Split:              int32(l.Split),
+			Name:               l.Name,
+			StartDate:          l.StartDate.UTC(),
+			StartDateLocal:     l.StartDateLocal,
+			ElapsedTime:        int32(l.ElapsedTime),
+			MovingTime:         int32(l.MovingTime),
+			Distance:           l.Distance,
+			StartIndex:         int32(l.StartIndex),
+			EndIndex:           int32(l.EndIndex),
+			TotalElevationGain: l.TotalElevationGain,
+			AverageSpeed:       l.AverageSpeed,
+			MaxSpeed:           l.MaxSpeed,
+			AverageCadence:     l.AverageCadence,
+			AverageWatts:       l.AverageWatts,
+			DeviceWatts:        l.DeviceWatts,
+			AverageHeartrate:   l.AverageHeartrate,
+		})
+	}
+
+	return records
+}
+
+// SplitRecords returns the records of the activity's metric and standard splits.
+func SplitRecords(athleteID uint, d *strava.DetailedActivity) []Split {
+	records := make([]Split, 0, len(d.SplitsMetric)+len(d.SplitsStandard))
+	for _, units := range []struct {
+		name   string
+		splits []*strava.Split
+	}{{"metric", d.SplitsMetric}, {"standard", d.SplitsStandard}} {
+		for _, s := range units.splits {
+			records = append(records, Split{
+				AthleteID:                 int64(athleteID),
+				ActivityID:                int64(d.ID),
+				Units:                     units.name,
+				Split:                     int32(s.Split),
+				Distance:                  s.Distance,
+				ElapsedTime:               int32(s.ElapsedTime),
+				MovingTime:                int32(s.MovingTime),
+				ElevationDifference:       s.ElevationDifference,
+				AverageSpeed:              s.AverageSpeed,
+				AverageGradeAdjustedSpeed: s.AverageGradeAdjustedSpeed,
+				AverageHeartrate:          s.AverageHeartrate,
+				PaceZone:                  int32(s.PaceZone),
+			})
+		}
+	}
+
+	return records
+}
+
+// SampleRecords zips the streams of the activity into samples along the time stream, nil without it.
+func SampleRecords(athleteID uint, a *strava.SummaryActivity, streams *strava.StreamSet) []Sample {
+	if streams.Time == nil {
+		return nil
+	}
+
+	records := make([]Sample, 0, len(streams.Time.Data))
+	for i, elapsed := range streams.Time.Data {
+		r := Sample{
+			AthleteID:      int64(athleteID),
+			ActivityID:     int64(a.ID),
+			Time:           a.StartDate.UTC().Add(time.Duration(elapsed) * time.Second),
+			Elapsed:        int32(elapsed),
+			Distance:       at(streams.Distance, i),
+			Altitude:       at(streams.Altitude, i),
+			VelocitySmooth: at(streams.VelocitySmooth, i),
+			Heartrate:      atInt32(streams.Heartrate, i),
+			Cadence:        atInt32(streams.Cadence, i),
+			Watts:          atInt32(streams.Watts, i),
+			Temp:           atInt32(streams.Temp, i),
+			Moving:         at(streams.Moving, i),
+			GradeSmooth:    at(streams.GradeSmooth, i),
+		}
+
+		if latlng := at(streams.LatLng, i); latlng != nil {
+			r.Lat, r.Lng = &latlng[0], &latlng[1]
+		}
+
+		records = append(records, r)
+	}
+
+	return records
+}
+
+// at returns the i-th sample of the stream, nil if there's none.
+func at[T any](s *strava.Stream[T], i int) *T {
+	if s == nil || i >= len(s.Data) {
+		return nil
+	}
+
+	return &s.Data[i]
+}
+
+func atInt32(s *strava.Stream[int], i int) *int32 {
+	v := at(s, i)
+	if v == nil {
+		return nil
+	}
+
+	n := int32(*v)
+	return &n
+}
@@ -0,0 +1,163 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// DefaultRowGroupSize is the number of records the Parquet writer buffers before writing them as a row group.
+const DefaultRowGroupSize = 64 * 1024
+
+// Writer writes records to a file. Close flushes the buffered records and finishes the file, the
+// underlying io.Writer is left open.
+type Writer[T any] interface {
+	Write(records []T) (int, error)
+	Close() error
+}
+
+// Format is a file format of the exported records.
+type Format string
+
+const (
+	FormatParquet Format = "parquet"
+	FormatCSV     Format = "csv"
+)
+
+// NewWriter returns a writer of records of type T in the format.
+func NewWriter[T any](format Format, w io.Writer) (Writer[T], error) {
+	switch format {
+	case FormatParquet:
+		return NewParquetWriter[T](w), nil
+	case FormatCSV:
+		return NewCSVWriter[T](w), nil
+	default:
+		return nil, fmt.Errorf("unknown format: %q", format)
+	}
+}
+
+// NewParquetWriter returns a writer of records of type T as a Parquet file with the schema of T. Records are
+// buffered up to DefaultRowGroupSize, opts can override it and the other writer options.
+func NewParquetWriter[T any](w io.Writer, opts ...parquet.WriterOption) Writer[T] {
+	opts = append([]parquet.WriterOption{parquet.MaxRowsPerRowGroup(DefaultRowGroupSize)}, opts...)
+	return parquet.NewGenericWriter[T](w, opts...)
+}
+
+// CSVWriter writes records as CSV, with a header of the column names of the Parquet schema.
+// Instants are formatted as RFC 3339 in UTC, local dates without an offset, and nulls as empty values.
+type CSVWriter[T any] struct {
+	w       *csv.Writer
+	columns []csvColumn
+	header  bool
+}
+
+type csvColumn struct {
+	name  string
+	index int
+	local bool
+}
+
+var _ Writer[Activity] = (*CSVWriter[Activity])(nil)
+
+// NewCSVWriter returns a writer of records of type T as CSV. T must be a struct, like the records of this package.
+func NewCSVWriter[T any](w io.Writer) *CSVWriter[T] {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+
+	var columns []csvColumn
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(f.Tag.Get("parquet"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+
+		columns = append(columns, csvColumn{name: name, index: i, local: strings.Contains(options, ":local")})
+	}
+
+	return &CSVWriter[T]{w: csv.NewWriter(w), columns: columns}
+}
+
+func (w *CSVWriter[T]) Write(records []T) (int, error) {
+	if !w.header {
+		header := make([]string, 0, len(w.columns))
+		for _, c := range w.columns {
+			header = append(header, c.name)
+		}
+
+		if err := w.w.Write(header); err != nil {
+			return 0, err
+		}
+		w.header = true
+	}
+
+	row := make([]string, len(w.columns))
+	for n, record := range records {
+		v := reflect.ValueOf(record)
+		for i, c := range w.columns {
+			row[i] = formatCSV(v.Field(c.index), c.local)
+		}
+
+		if err := w.w.Write(row); err != nil {
+			return n, err
+		}
+	}
+
+	return len(records), w.w.Error()
+}
+
+// Close writes the header if no records were written and flushes the buffered rows.
+func (w *CSVWriter[T]) Close() error {
+	if !w.header {
+		if _, err := w.Write(nil); err != nil {
+			return err
+		}
+	}
+
+	w.w.Flush()
+	return w.w.Error()
+}
+
+func formatCSV(v reflect.Value, local bool) string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	if t, ok := v.Interface().(time.Time); ok {
+		if t.IsZero() {
+			return ""
+		}
+		if local {
+			return t.Format("2006-01-02T15:04:05")
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
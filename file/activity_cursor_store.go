@@ -0,0 +1,84 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/marvell/strava-go"
+)
+
+// ActivityCursorStore keeps the activity cursor of each athlete in a JSON file. The directory can be
+// shared with a TokenStorage.
+type ActivityCursorStore struct {
+	dir string
+}
+
+var _ strava.ActivityCursorStore = (*ActivityCursorStore)(nil)
+
+func NewActivityCursorStore(dir string) (*ActivityCursorStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create storage directory: %w", err)
+	}
+	return &ActivityCursorStore{dir: dir}, nil
+}
+
+func (s *ActivityCursorStore) GetActivityCursor(ctx context.Context, athleteID uint) (*strava.ActivityCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.filename(athleteID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, strava.ErrActivityCursorNotFound
+		}
+		return nil, fmt.Errorf("read activity cursor file: %w", err)
+	}
+
+	var cursor strava.ActivityCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("unmarshal activity cursor: %w", err)
+	}
+
+	return &cursor, nil
+}
+
+func (s *ActivityCursorStore) SaveActivityCursor(ctx context.Context, cursor *strava.ActivityCursor) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return fmt.Errorf("marshal activity cursor: %w", err)
+	}
+
+	// Write to a temporary file first, so readers never see a partial cursor.
+	f, err := os.CreateTemp(s.dir, ".cursor-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary activity cursor file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write activity cursor file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write activity cursor file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), s.filename(cursor.AthleteID)); err != nil {
+		return fmt.Errorf("rename activity cursor file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ActivityCursorStore) filename(athleteID uint) string {
+	return filepath.Join(s.dir, fmt.Sprintf("cursor-%d.json", athleteID))
+}
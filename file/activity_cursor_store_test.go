@@ -0,0 +1,18 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestActivityCursorStore(t *testing.T) {
+	storagetest.RunActivityCursorStoreSuite(t, func(t *testing.T) strava.ActivityCursorStore {
+		s, err := NewActivityCursorStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
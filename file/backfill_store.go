@@ -0,0 +1,117 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/marvell/strava-go"
+)
+
+// BackfillStore keeps the backfill of each athlete in a JSON file. The directory can be shared
+// with a TokenStorage.
+type BackfillStore struct {
+	dir string
+}
+
+var _ strava.BackfillStore = (*BackfillStore)(nil)
+
+func NewBackfillStore(dir string) (*BackfillStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create storage directory: %w", err)
+	}
+	return &BackfillStore{dir: dir}, nil
+}
+
+func (s *BackfillStore) GetBackfill(ctx context.Context, athleteID uint) (*strava.Backfill, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.filename(athleteID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, strava.ErrBackfillNotFound
+		}
+		return nil, fmt.Errorf("read backfill file: %w", err)
+	}
+
+	var b strava.Backfill
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("unmarshal backfill: %w", err)
+	}
+
+	return &b, nil
+}
+
+func (s *BackfillStore) SaveBackfill(ctx context.Context, backfill *strava.Backfill) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(backfill)
+	if err != nil {
+		return fmt.Errorf("marshal backfill: %w", err)
+	}
+
+	// Write to a temporary file first, so readers never see a partial backfill.
+	f, err := os.CreateTemp(s.dir, ".backfill-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary backfill file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write backfill file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write backfill file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), s.filename(backfill.AthleteID)); err != nil {
+		return fmt.Errorf("rename backfill file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BackfillStore) ListBackfills(ctx context.Context) ([]*strava.Backfill, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read storage directory: %w", err)
+	}
+
+	var backfills []*strava.Backfill
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "backfill-") || filepath.Ext(name) != ".json" {
+			continue
+		}
+
+		athleteID, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(name, "backfill-"), ".json"), 10, 0)
+		if err != nil {
+			continue
+		}
+
+		b, err := s.GetBackfill(ctx, uint(athleteID))
+		if err != nil {
+			return nil, err
+		}
+		backfills = append(backfills, b)
+	}
+
+	return backfills, nil
+}
+
+func (s *BackfillStore) filename(athleteID uint) string {
+	return filepath.Join(s.dir, fmt.Sprintf("backfill-%d.json", athleteID))
+}
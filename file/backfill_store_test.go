@@ -0,0 +1,18 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestBackfillStore(t *testing.T) {
+	storagetest.RunBackfillStoreSuite(t, func(t *testing.T) strava.BackfillStore {
+		s, err := NewBackfillStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
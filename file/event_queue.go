@@ -0,0 +1,417 @@
+package file
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// compactMinRecords is the log size below which the log isn't compacted while the queue is open.
+const compactMinRecords = 1024
+
+const (
+	opSeq     = "seq"
+	opPending = "pending"
+	opDead    = "dead"
+	opAck     = "ack"
+)
+
+// logRecord is a line of the log. Records hold the full state of the event, so the last record of an event wins.
+type logRecord struct {
+	Op    string              `json:"op"`
+	ID    uint64              `json:"id"`
+	Event *strava.QueuedEvent `json:"event,omitempty"`
+}
+
+// logFile is the open log, an *os.File.
+type logFile interface {
+	io.WriteCloser
+	Sync() error
+	Truncate(size int64) error
+}
+
+// EventQueue keeps webhook events in an append-only log file. Each change is appended and synced
+// before the method returns. The log is compacted when it's opened and when it outgrows the events it holds.
+type EventQueue struct {
+	path string
+
+	mu      sync.Mutex
+	f       logFile
+	size    int64
+	records int
+	lastID  uint64
+	pending map[uint64]*strava.QueuedEvent
+	dead    map[uint64]*strava.QueuedEvent
+}
+
+var _ strava.EventQueue = (*EventQueue)(nil)
+
+// NewEventQueue opens the log at path, creating it if missing. Close the queue when done.
+func NewEventQueue(path string) (*EventQueue, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create queue directory: %w", err)
+	}
+
+	q := &EventQueue{
+		path:    path,
+		pending: make(map[uint64]*strava.QueuedEvent),
+		dead:    make(map[uint64]*strava.QueuedEvent),
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+
+	if err := q.compact(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+// load replays the log. A truncated last line, left by a crash during a write, is ignored.
+func (q *EventQueue) load() error {
+	data, err := os.ReadFile(q.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("read queue log: %w", err)
+	}
+
+	r := bufio.NewReader(bytes.NewReader(data))
+	for line := 1; ; line++ {
+		b, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// Complete records end with a newline.
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read queue log: %w", err)
+		}
+
+		var rec logRecord
+		if err := json.Unmarshal(b, &rec); err != nil {
+			return fmt.Errorf("parse queue log line %d: %w", line, err)
+		}
+
+		q.apply(rec)
+	}
+}
+
+func (q *EventQueue) apply(rec logRecord) {
+	q.lastID = max(q.lastID, rec.ID)
+
+	switch rec.Op {
+	case opPending:
+		delete(q.dead, rec.ID)
+		q.pending[rec.ID] = rec.Event
+	case opDead:
+		delete(q.pending, rec.ID)
+		q.dead[rec.ID] = rec.Event
+	case opAck:
+		delete(q.pending, rec.ID)
+	}
+}
+
+// rename replaces the log with the compacted one, replaced in tests.
+var rename = os.Rename
+
+// compact rewrites the log with the current events and appends to it from then on. If that fails,
+// the queue keeps appending to the old log.
+func (q *EventQueue) compact() error {
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("create queue log: %w", err)
+	}
+
+	recs := []logRecord{{Op: opSeq, ID: q.lastID}}
+	for _, e := range q.pending {
+		recs = append(recs, logRecord{Op: opPending, ID: e.ID, Event: e})
+	}
+	for _, e := range q.dead {
+		recs = append(recs, logRecord{Op: opDead, ID: e.ID, Event: e})
+	}
+
+	size, err := writeLog(tmp, recs)
+	if cerr := tmp.Close(); err == nil && cerr != nil {
+		err = fmt.Errorf("close queue log: %w", cerr)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	// The new log is opened before it replaces the old one, so a failure leaves the queue on the old log.
+	f, err := os.OpenFile(tmp.Name(), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("open queue log: %w", err)
+	}
+
+	if err := rename(tmp.Name(), q.path); err != nil {
+		f.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("replace queue log: %w", err)
+	}
+
+	if q.f != nil {
+		q.f.Close()
+	}
+	q.f = f
+	q.size = size
+	q.records = len(recs)
+	return nil
+}
+
+// writeLog writes and syncs the records, and returns the size of the file.
+func writeLog(f *os.File, recs []logRecord) (int64, error) {
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+	for _, rec := range recs {
+		if err := enc.Encode(rec); err != nil {
+			return 0, fmt.Errorf("write queue log: %w", err)
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return 0, fmt.Errorf("write queue log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return 0, fmt.Errorf("sync queue log: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("stat queue log: %w", err)
+	}
+
+	return info.Size(), nil
+}
+
+// append writes and syncs the record, then applies it.
+func (q *EventQueue) append(rec logRecord) error {
+	if q.f == nil {
+		return errors.New("event queue is closed")
+	}
+
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return fmt.Errorf("marshal queue record: %w", err)
+	}
+
+	n, err := q.f.Write(append(b, '\n'))
+	if err == nil {
+		err = q.f.Sync()
+	}
+	if err != nil {
+		return q.rollback(fmt.Errorf("write queue log: %w", err))
+	}
+	q.size += int64(n)
+
+	q.apply(rec)
+	q.records++
+
+	// The record is durable, so a failed compaction is retried with the next record instead of failing this one.
+	if q.records > compactMinRecords && q.records > 4*(len(q.pending)+len(q.dead)) {
+		if err := q.compact(); err != nil {
+			slog.Error("compact event queue log", "path", q.path, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// rollback truncates a partially written record, so the next record starts on a new line. If that
+// fails too, the queue is closed, as appending would corrupt the log.
+func (q *EventQueue) rollback(err error) error {
+	if terr := q.f.Truncate(q.size); terr != nil {
+		q.f.Close()
+		q.f = nil
+		return errors.Join(err, fmt.Errorf("truncate queue log, queue closed: %w", terr))
+	}
+
+	return err
+}
+
+func (q *EventQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.f == nil {
+		return nil
+	}
+
+	err := q.f.Close()
+	q.f = nil
+	return err
+}
+
+func (q *EventQueue) Enqueue(ctx context.Context, event strava.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	id := q.lastID + 1
+
+	return q.append(logRecord{Op: opPending, ID: id, Event: &strava.QueuedEvent{
+		ID:          id,
+		Event:       event,
+		NextAttempt: now,
+		EnqueuedAt:  now,
+	}})
+}
+
+func (q *EventQueue) Claim(ctx context.Context, timeout time.Duration) (*strava.QueuedEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+
+	var next *strava.QueuedEvent
+	for _, e := range q.pending {
+		if e.NextAttempt.After(now) {
+			continue
+		}
+		if next == nil || e.NextAttempt.Before(next.NextAttempt) || e.NextAttempt.Equal(next.NextAttempt) && e.ID < next.ID {
+			next = e
+		}
+	}
+
+	if next == nil {
+		return nil, strava.ErrNoEventDue
+	}
+
+	e := *next
+	e.Attempts++
+	e.NextAttempt = now.Add(timeout)
+
+	if err := q.append(logRecord{Op: opPending, ID: e.ID, Event: &e}); err != nil {
+		return nil, err
+	}
+
+	c := e
+	return &c, nil
+}
+
+// claimed returns the pending event if it's still claimed with the given attempts.
+func (q *EventQueue) claimed(id uint64, attempts int) (*strava.QueuedEvent, bool) {
+	e, ok := q.pending[id]
+	if !ok || e.Attempts != attempts {
+		return nil, false
+	}
+
+	return e, true
+}
+
+func (q *EventQueue) Ack(ctx context.Context, id uint64, attempts int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.claimed(id, attempts); !ok {
+		return strava.ErrEventNotFound
+	}
+
+	return q.append(logRecord{Op: opAck, ID: id})
+}
+
+func (q *EventQueue) Retry(ctx context.Context, id uint64, attempts int, next time.Time, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.claimed(id, attempts)
+	if !ok {
+		return strava.ErrEventNotFound
+	}
+
+	u := *e
+	u.NextAttempt = next
+	u.LastError = reason
+
+	return q.append(logRecord{Op: opPending, ID: id, Event: &u})
+}
+
+func (q *EventQueue) DeadLetter(ctx context.Context, id uint64, attempts int, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.claimed(id, attempts)
+	if !ok {
+		return strava.ErrEventNotFound
+	}
+
+	u := *e
+	u.LastError = reason
+
+	return q.append(logRecord{Op: opDead, ID: id, Event: &u})
+}
+
+func (q *EventQueue) DeadLetters(ctx context.Context) ([]*strava.QueuedEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	events := make([]*strava.QueuedEvent, 0, len(q.dead))
+	for _, e := range q.dead {
+		c := *e
+		events = append(events, &c)
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (q *EventQueue) Replay(ctx context.Context, id uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.dead[id]
+	if !ok {
+		return strava.ErrEventNotFound
+	}
+
+	u := *e
+	u.Attempts = 0
+	u.NextAttempt = time.Now()
+
+	return q.append(logRecord{Op: opPending, ID: id, Event: &u})
+}
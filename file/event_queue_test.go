@@ -0,0 +1,171 @@
+package file
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestEventQueue(t *testing.T) {
+	storagetest.RunEventQueueSuite(t, func(t *testing.T) strava.EventQueue {
+		q, err := NewEventQueue(filepath.Join(t.TempDir(), "events.log"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { q.Close() })
+		return q
+	})
+}
+
+func TestEventQueue_Reopen(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	q, err := NewEventQueue(path)
+	assert.NoErr(t, err)
+
+	for i := uint(1); i <= 3; i++ {
+		assert.NoErr(t, q.Enqueue(ctx, storagetest.NewEvent(i)))
+	}
+
+	acked, err := q.Claim(ctx, time.Minute)
+	assert.NoErr(t, err)
+	assert.NoErr(t, q.Ack(ctx, acked.ID, acked.Attempts))
+
+	dead, err := q.Claim(ctx, time.Minute)
+	assert.NoErr(t, err)
+	assert.NoErr(t, q.DeadLetter(ctx, dead.ID, dead.Attempts, "gave up"))
+	assert.NoErr(t, q.Close())
+
+	// A crash while appending leaves a truncated line.
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+	assert.NoErr(t, err)
+	_, err = f.WriteString(`{"op":"ack","id":`)
+	assert.NoErr(t, err)
+	assert.NoErr(t, f.Close())
+
+	// act
+	q, err = NewEventQueue(path)
+	assert.NoErr(t, err)
+	defer q.Close()
+
+	// assert
+	pending, err := q.Claim(ctx, time.Minute)
+	assert.NoErr(t, err)
+	assert.Eq(t, uint(3), pending.Event.ObjectID)
+	assert.Eq(t, 1, pending.Attempts)
+
+	letters, err := q.DeadLetters(ctx)
+	assert.NoErr(t, err)
+	assert.Len(t, letters, 1)
+	assert.Eq(t, "gave up", letters[0].LastError)
+
+	// IDs aren't reused after the log is compacted.
+	assert.NoErr(t, q.Enqueue(ctx, storagetest.NewEvent(4)))
+	assert.NoErr(t, q.Ack(ctx, pending.ID, pending.Attempts))
+	next, err := q.Claim(ctx, time.Minute)
+	assert.NoErr(t, err)
+	assert.Eq(t, uint64(4), next.ID)
+}
+
+// tornFile writes half of the next record and fails.
+type tornFile struct {
+	logFile
+	torn bool
+}
+
+func (f *tornFile) Write(b []byte) (int, error) {
+	if f.torn {
+		return f.logFile.Write(b)
+	}
+
+	f.torn = true
+	n, _ := f.logFile.Write(b[:len(b)/2])
+	return n, errors.New("no space left on device")
+}
+
+func TestEventQueue_TornWrite(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	q, err := NewEventQueue(path)
+	assert.NoErr(t, err)
+	assert.NoErr(t, q.Enqueue(ctx, storagetest.NewEvent(1)))
+	q.f = &tornFile{logFile: q.f}
+
+	// act
+	errTorn := q.Enqueue(ctx, storagetest.NewEvent(2))
+	errNext := q.Enqueue(ctx, storagetest.NewEvent(3))
+	assert.NoErr(t, q.Close())
+
+	// assert
+	assert.Err(t, errTorn)
+	assert.NoErr(t, errNext)
+
+	q, err = NewEventQueue(path)
+	assert.NoErr(t, err)
+	defer q.Close()
+
+	var objects []uint
+	for {
+		e, err := q.Claim(ctx, time.Minute)
+		if errors.Is(err, strava.ErrNoEventDue) {
+			break
+		}
+		assert.NoErr(t, err)
+		objects = append(objects, e.Event.ObjectID)
+	}
+	assert.Eq(t, []uint{1, 3}, objects)
+}
+
+func TestEventQueue_CompactionFailure(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "events.log")
+
+	q, err := NewEventQueue(path)
+	assert.NoErr(t, err)
+	assert.NoErr(t, q.Enqueue(ctx, storagetest.NewEvent(1)))
+
+	rename = func(string, string) error { return errors.New("rename failed") }
+	t.Cleanup(func() { rename = os.Rename })
+	// The next record makes the log outgrow its events.
+	q.records = compactMinRecords
+
+	// act
+	errCompact := q.Enqueue(ctx, storagetest.NewEvent(2))
+	errNext := q.Enqueue(ctx, storagetest.NewEvent(3))
+	rename = os.Rename
+	errCompacted := q.Enqueue(ctx, storagetest.NewEvent(4))
+	assert.NoErr(t, q.Close())
+
+	// assert: the records were persisted despite the failed compactions
+	assert.NoErr(t, errCompact)
+	assert.NoErr(t, errNext)
+	assert.NoErr(t, errCompacted)
+
+	q, err = NewEventQueue(path)
+	assert.NoErr(t, err)
+	defer q.Close()
+
+	var objects []uint
+	for {
+		e, err := q.Claim(ctx, time.Minute)
+		if errors.Is(err, strava.ErrNoEventDue) {
+			break
+		}
+		assert.NoErr(t, err)
+		objects = append(objects, e.Event.ObjectID)
+	}
+	assert.Eq(t, []uint{1, 2, 3, 4}, objects)
+}
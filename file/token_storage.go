@@ -5,28 +5,51 @@ import (
 	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/marvell/strava-go"
 )
 
 type TokenStorage struct {
+	// rootDir holds the tokens saved without namespace, and a directory per namespace.
+	rootDir    string
 	storageDir string
 }
 
-var _ strava.TokenStorage = (*TokenStorage)(nil)
+var (
+	_ strava.TokenStorage    = (*TokenStorage)(nil)
+	_ strava.TokenLister     = (*TokenStorage)(nil)
+	_ strava.TokenPager      = (*TokenStorage)(nil)
+	_ strava.TokenDeleter    = (*TokenStorage)(nil)
+	_ strava.TokenNamespacer = (*TokenStorage)(nil)
+)
 
 func NewTokenStorage(storageDir string) (*TokenStorage, error) {
 	if err := os.MkdirAll(storageDir, 0755); err != nil {
 		return nil, fmt.Errorf("create storage directory: %w", err)
 	}
-	return &TokenStorage{storageDir: storageDir}, nil
+	return &TokenStorage{rootDir: storageDir, storageDir: storageDir}, nil
+}
+
+func (ts *TokenStorage) ForClient(clientID string) strava.TokenStorage {
+	return &TokenStorage{
+		rootDir:    ts.rootDir,
+		storageDir: filepath.Join(ts.rootDir, "client-"+url.PathEscape(clientID)),
+	}
 }
 
-func (ts *TokenStorage) Get(_ context.Context, athleteID uint) (*strava.Token, error) {
+func (ts *TokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token, error) {
 	slog.Debug("get token", "athleteID", athleteID)
 
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	data, err := os.ReadFile(ts.filename(athleteID))
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -43,21 +66,124 @@ func (ts *TokenStorage) Get(_ context.Context, athleteID uint) (*strava.Token, e
 	return &token, nil
 }
 
-func (ts *TokenStorage) Save(_ context.Context, token *strava.Token) error {
+func (ts *TokenStorage) Save(ctx context.Context, token *strava.Token) error {
 	slog.Debug("save token", "athleteID", token.AthleteID)
 
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
 	data, err := json.Marshal(token)
 	if err != nil {
 		return fmt.Errorf("marshal token: %w", err)
 	}
 
-	if err := os.WriteFile(ts.filename(token.AthleteID), data, 0600); err != nil {
+	if err := os.MkdirAll(ts.storageDir, 0755); err != nil {
+		return fmt.Errorf("create storage directory: %w", err)
+	}
+
+	// Write to a temporary file first, so readers and concurrent writers never see a partial token.
+	f, err := os.CreateTemp(ts.storageDir, fmt.Sprintf(".%d-*.tmp", token.AthleteID))
+	if err != nil {
+		return fmt.Errorf("create temporary token file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
 		return fmt.Errorf("write token file: %w", err)
 	}
 
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write token file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), ts.filename(token.AthleteID)); err != nil {
+		return fmt.Errorf("rename token file: %w", err)
+	}
+
 	return nil
 }
 
+func (ts *TokenStorage) Delete(ctx context.Context, athleteID uint) error {
+	slog.Debug("delete token", "athleteID", athleteID)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(ts.filename(athleteID)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("remove token file: %w", err)
+	}
+
+	return nil
+}
+
+func (ts *TokenStorage) List(ctx context.Context) ([]*strava.Token, error) {
+	ids, err := ts.athleteIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	return ts.getAll(ctx, ids)
+}
+
+// ListAfter only reads the token files of the page.
+func (ts *TokenStorage) ListAfter(ctx context.Context, afterID uint, limit int) ([]*strava.Token, error) {
+	ids, err := ts.athleteIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Sort(ids)
+	i, _ := slices.BinarySearch(ids, afterID+1)
+	ids = ids[i:]
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	return ts.getAll(ctx, ids)
+}
+
+// athleteIDs returns the athletes having a token file.
+func (ts *TokenStorage) athleteIDs() ([]uint, error) {
+	entries, err := os.ReadDir(ts.storageDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read storage directory: %w", err)
+	}
+
+	var ids []uint
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != ".json" {
+			continue
+		}
+
+		athleteID, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), ".json"), 10, 0)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(athleteID))
+	}
+
+	return ids, nil
+}
+
+func (ts *TokenStorage) getAll(ctx context.Context, ids []uint) ([]*strava.Token, error) {
+	tokens := make([]*strava.Token, 0, len(ids))
+	for _, athleteID := range ids {
+		token, err := ts.Get(ctx, athleteID)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
 func (ts *TokenStorage) filename(athleteID uint) string {
 	return filepath.Join(ts.storageDir, fmt.Sprintf("%d.json", athleteID))
 }
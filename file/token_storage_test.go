@@ -0,0 +1,18 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestTokenStorage(t *testing.T) {
+	storagetest.RunTokenStorageSuite(t, func(t *testing.T) strava.TokenStorage {
+		ts, err := NewTokenStorage(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ts
+	})
+}
@@ -0,0 +1,85 @@
+package file
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/marvell/strava-go"
+)
+
+// WebhookStateStore keeps the webhook state of each client in a JSON file. The directory can be
+// shared with a TokenStorage.
+type WebhookStateStore struct {
+	dir string
+}
+
+var _ strava.WebhookStateStore = (*WebhookStateStore)(nil)
+
+func NewWebhookStateStore(dir string) (*WebhookStateStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create storage directory: %w", err)
+	}
+	return &WebhookStateStore{dir: dir}, nil
+}
+
+func (s *WebhookStateStore) GetWebhookState(ctx context.Context, clientID string) (*strava.WebhookState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(s.filename(clientID))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, strava.ErrWebhookStateNotFound
+		}
+		return nil, fmt.Errorf("read webhook state file: %w", err)
+	}
+
+	var state strava.WebhookState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("unmarshal webhook state: %w", err)
+	}
+
+	return &state, nil
+}
+
+func (s *WebhookStateStore) SaveWebhookState(ctx context.Context, clientID string, state *strava.WebhookState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("marshal webhook state: %w", err)
+	}
+
+	// Write to a temporary file first, so readers never see a partial state.
+	f, err := os.CreateTemp(s.dir, ".webhook-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temporary webhook state file: %w", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write webhook state file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write webhook state file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), s.filename(clientID)); err != nil {
+		return fmt.Errorf("rename webhook state file: %w", err)
+	}
+
+	return nil
+}
+
+func (s *WebhookStateStore) filename(clientID string) string {
+	return filepath.Join(s.dir, "webhook-"+url.PathEscape(clientID)+".json")
+}
@@ -0,0 +1,18 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestWebhookStateStore(t *testing.T) {
+	storagetest.RunWebhookStateStoreSuite(t, func(t *testing.T) strava.WebhookStateStore {
+		s, err := NewWebhookStateStore(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	})
+}
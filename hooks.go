@@ -0,0 +1,81 @@
+package strava
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// TokenEvent describes a change in the lifecycle of an athlete's token.
+// It never contains the token values themselves.
+type TokenEvent struct {
+	AthleteID uint
+	OldExpiry time.Time
+	NewExpiry time.Time
+	Scope     string
+	// Err is set for OnRefreshFailed and OnTokenRevoked events.
+	Err error
+}
+
+// TokenHooks are called on token lifecycle events, e.g. to keep an audit trail.
+// Any of the hooks may be nil.
+type TokenHooks struct {
+	// OnTokenCreated is called after a token was obtained in AuthExchange.
+	OnTokenCreated func(ctx context.Context, e TokenEvent)
+	// OnTokenRefreshed is called after a token was refreshed and saved.
+	OnTokenRefreshed func(ctx context.Context, e TokenEvent)
+	// OnRefreshFailed is called when a token couldn't be refreshed for reasons other than revocation.
+	OnRefreshFailed func(ctx context.Context, e TokenEvent)
+	// OnTokenRevoked is called when Strava rejects the refresh token.
+	OnTokenRevoked func(ctx context.Context, e TokenEvent)
+}
+
+// NewSlogTokenHooks returns hooks that log token lifecycle events with the given logger.
+func NewSlogTokenHooks(l *slog.Logger) TokenHooks {
+	log := func(level slog.Level, msg string) func(context.Context, TokenEvent) {
+		return func(ctx context.Context, e TokenEvent) {
+			attrs := []slog.Attr{
+				slog.Uint64("athleteID", uint64(e.AthleteID)),
+				slog.String("scope", e.Scope),
+				slog.Time("oldExpiry", e.OldExpiry),
+				slog.Time("newExpiry", e.NewExpiry),
+			}
+			if e.Err != nil {
+				attrs = append(attrs, slog.Any("error", e.Err))
+			}
+
+			l.LogAttrs(ctx, level, msg, attrs...)
+		}
+	}
+
+	return TokenHooks{
+		OnTokenCreated:   log(slog.LevelInfo, "strava token created"),
+		OnTokenRefreshed: log(slog.LevelInfo, "strava token refreshed"),
+		OnRefreshFailed:  log(slog.LevelError, "strava token refresh failed"),
+		OnTokenRevoked:   log(slog.LevelWarn, "strava token revoked"),
+	}
+}
+
+func (h TokenHooks) tokenCreated(ctx context.Context, e TokenEvent) {
+	if h.OnTokenCreated != nil {
+		h.OnTokenCreated(ctx, e)
+	}
+}
+
+func (h TokenHooks) tokenRefreshed(ctx context.Context, e TokenEvent) {
+	if h.OnTokenRefreshed != nil {
+		h.OnTokenRefreshed(ctx, e)
+	}
+}
+
+func (h TokenHooks) refreshFailed(ctx context.Context, e TokenEvent) {
+	if h.OnRefreshFailed != nil {
+		h.OnRefreshFailed(ctx, e)
+	}
+}
+
+func (h TokenHooks) tokenRevoked(ctx context.Context, e TokenEvent) {
+	if h.OnTokenRevoked != nil {
+		h.OnTokenRevoked(ctx, e)
+	}
+}
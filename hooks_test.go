@@ -0,0 +1,145 @@
+package strava
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+)
+
+// testTokenHooks records the token events by hook.
+type testTokenHooks struct {
+	mu     sync.Mutex
+	events map[string][]TokenEvent
+}
+
+func (h *testTokenHooks) hooks() TokenHooks {
+	record := func(name string) func(context.Context, TokenEvent) {
+		return func(_ context.Context, e TokenEvent) {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+
+			if h.events == nil {
+				h.events = make(map[string][]TokenEvent)
+			}
+			h.events[name] = append(h.events[name], e)
+		}
+	}
+
+	return TokenHooks{
+		OnTokenCreated:   record("created"),
+		OnTokenRefreshed: record("refreshed"),
+		OnRefreshFailed:  record("failed"),
+		OnTokenRevoked:   record("revoked"),
+	}
+}
+
+func TestTokenHooks_AuthExchange(t *testing.T) {
+	// arrange
+	ts := newTestTokenStorage()
+	h := &testTokenHooks{}
+	c := newTestClient(newTestTokenServer(t), ts, WithTokenHooks(h.hooks()))
+
+	// act
+	athleteID, err := c.AuthExchange(context.Background(), "code", "read,activity:read_all", OAuthStaticState)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, uint(5), athleteID)
+	assert.Len(t, h.events, 1)
+	assert.Len(t, h.events["created"], 1)
+
+	e := h.events["created"][0]
+	assert.Eq(t, uint(5), e.AthleteID)
+	assert.Eq(t, "read,activity:read_all", e.Scope)
+	assert.True(t, e.OldExpiry.IsZero())
+	assert.True(t, e.NewExpiry.After(time.Now().Add(5*time.Hour)))
+	assert.Nil(t, e.Err)
+}
+
+func TestTokenHooks_Refresh(t *testing.T) {
+	oldExpiry := time.Now().Add(-time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name         string
+		refreshToken string
+		wantHook     string
+		wantErr      error
+		wantNew      bool
+	}{
+		{name: "refreshed", refreshToken: "r1", wantHook: "refreshed", wantNew: true},
+		{name: "revoked", refreshToken: "revoked", wantHook: "revoked", wantErr: ErrTokenRevoked},
+		{name: "failed", refreshToken: "unavailable", wantHook: "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ts := newTestTokenStorage(&Token{
+				AthleteID: 1,
+				Scope:     "read",
+				Token:     &oauth2.Token{AccessToken: "a1", RefreshToken: tt.refreshToken, Expiry: oldExpiry},
+			})
+			h := &testTokenHooks{}
+			c := newTestClient(newTestTokenServer(t), ts, WithTokenHooks(h.hooks()))
+
+			// act
+			_, err := c.RefreshToken(context.Background(), 1)
+
+			// assert
+			if tt.wantHook == "refreshed" {
+				assert.NoErr(t, err)
+			} else {
+				assert.Err(t, err)
+			}
+			if tt.wantErr != nil {
+				assert.ErrIs(t, err, tt.wantErr)
+			}
+
+			assert.Len(t, h.events, 1)
+			assert.Len(t, h.events[tt.wantHook], 1)
+
+			e := h.events[tt.wantHook][0]
+			assert.Eq(t, uint(1), e.AthleteID)
+			assert.Eq(t, "read", e.Scope)
+			assert.Eq(t, oldExpiry, e.OldExpiry)
+			assert.Eq(t, tt.wantNew, e.NewExpiry.After(time.Now().Add(5*time.Hour)))
+			assert.Eq(t, tt.wantNew, e.Err == nil)
+		})
+	}
+}
+
+func TestNewSlogTokenHooks(t *testing.T) {
+	// arrange
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	ts := newTestTokenStorage(&Token{
+		AthleteID: 1,
+		Token:     &oauth2.Token{AccessToken: "a1", RefreshToken: "r1", Expiry: time.Now().Add(-time.Minute)},
+	})
+	// The slog hooks are the default, with the client's logger.
+	c := newTestClient(newTestTokenServer(t), ts, WithLogger(logger))
+
+	// act
+	_, errExchange := c.AuthExchange(context.Background(), "code", "read", OAuthStaticState)
+	_, errRefresh := c.RefreshToken(context.Background(), 1)
+
+	// assert
+	assert.NoErr(t, errExchange)
+	assert.NoErr(t, errRefresh)
+
+	out := buf.String()
+	assert.StrContains(t, out, "strava token created")
+	assert.StrContains(t, out, "strava token refreshed")
+	assert.StrContains(t, out, `"athleteID":5`)
+	assert.StrContains(t, out, `"athleteID":1`)
+	for _, secret := range []string{"a1", "r1", "access-code", "refresh-code", "new-access-r1"} {
+		assert.NotContains(t, out, `"`+secret+`"`)
+	}
+}
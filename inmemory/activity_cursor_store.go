@@ -0,0 +1,38 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/marvell/strava-go"
+)
+
+type ActivityCursorStore struct {
+	m sync.Map
+}
+
+var _ strava.ActivityCursorStore = (*ActivityCursorStore)(nil)
+
+func (s *ActivityCursorStore) GetActivityCursor(ctx context.Context, athleteID uint) (*strava.ActivityCursor, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	v, ok := s.m.Load(athleteID)
+	if !ok {
+		return nil, strava.ErrActivityCursorNotFound
+	}
+
+	cursor := *v.(*strava.ActivityCursor)
+	return &cursor, nil
+}
+
+func (s *ActivityCursorStore) SaveActivityCursor(ctx context.Context, cursor *strava.ActivityCursor) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c := *cursor
+	s.m.Store(cursor.AthleteID, &c)
+	return nil
+}
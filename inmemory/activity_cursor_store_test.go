@@ -0,0 +1,14 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestActivityCursorStore(t *testing.T) {
+	storagetest.RunActivityCursorStoreSuite(t, func(t *testing.T) strava.ActivityCursorStore {
+		return &ActivityCursorStore{}
+	})
+}
@@ -0,0 +1,53 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/marvell/strava-go"
+)
+
+type BackfillStore struct {
+	m sync.Map
+}
+
+var _ strava.BackfillStore = (*BackfillStore)(nil)
+
+func (s *BackfillStore) GetBackfill(ctx context.Context, athleteID uint) (*strava.Backfill, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	v, ok := s.m.Load(athleteID)
+	if !ok {
+		return nil, strava.ErrBackfillNotFound
+	}
+
+	b := *v.(*strava.Backfill)
+	return &b, nil
+}
+
+func (s *BackfillStore) SaveBackfill(ctx context.Context, backfill *strava.Backfill) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b := *backfill
+	s.m.Store(backfill.AthleteID, &b)
+	return nil
+}
+
+func (s *BackfillStore) ListBackfills(ctx context.Context) ([]*strava.Backfill, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var backfills []*strava.Backfill
+	s.m.Range(func(_, v any) bool {
+		b := *v.(*strava.Backfill)
+		backfills = append(backfills, &b)
+		return true
+	})
+
+	return backfills, nil
+}
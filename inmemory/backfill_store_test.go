@@ -0,0 +1,14 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestBackfillStore(t *testing.T) {
+	storagetest.RunBackfillStoreSuite(t, func(t *testing.T) strava.BackfillStore {
+		return &BackfillStore{}
+	})
+}
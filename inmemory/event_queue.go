@@ -0,0 +1,180 @@
+package inmemory
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// EventQueue keeps webhook events in memory. Events are lost when the process exits,
+// use it for tests or when retries alone are enough.
+type EventQueue struct {
+	mu      sync.Mutex
+	lastID  uint64
+	pending map[uint64]*strava.QueuedEvent
+	dead    map[uint64]*strava.QueuedEvent
+}
+
+var _ strava.EventQueue = (*EventQueue)(nil)
+
+func NewEventQueue() *EventQueue {
+	return &EventQueue{
+		pending: make(map[uint64]*strava.QueuedEvent),
+		dead:    make(map[uint64]*strava.QueuedEvent),
+	}
+}
+
+func (q *EventQueue) Enqueue(ctx context.Context, event strava.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	q.lastID++
+	q.pending[q.lastID] = &strava.QueuedEvent{
+		ID:          q.lastID,
+		Event:       event,
+		NextAttempt: now,
+		EnqueuedAt:  now,
+	}
+
+	return nil
+}
+
+func (q *EventQueue) Claim(ctx context.Context, timeout time.Duration) (*strava.QueuedEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+
+	var next *strava.QueuedEvent
+	for _, e := range q.pending {
+		if e.NextAttempt.After(now) {
+			continue
+		}
+		if next == nil || e.NextAttempt.Before(next.NextAttempt) || e.NextAttempt.Equal(next.NextAttempt) && e.ID < next.ID {
+			next = e
+		}
+	}
+
+	if next == nil {
+		return nil, strava.ErrNoEventDue
+	}
+
+	next.Attempts++
+	next.NextAttempt = now.Add(timeout)
+
+	c := *next
+	return &c, nil
+}
+
+// claimed returns the pending event if it's still claimed with the given attempts.
+func (q *EventQueue) claimed(id uint64, attempts int) (*strava.QueuedEvent, bool) {
+	e, ok := q.pending[id]
+	if !ok || e.Attempts != attempts {
+		return nil, false
+	}
+
+	return e, true
+}
+
+func (q *EventQueue) Ack(ctx context.Context, id uint64, attempts int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.claimed(id, attempts); !ok {
+		return strava.ErrEventNotFound
+	}
+
+	delete(q.pending, id)
+	return nil
+}
+
+func (q *EventQueue) Retry(ctx context.Context, id uint64, attempts int, next time.Time, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.claimed(id, attempts)
+	if !ok {
+		return strava.ErrEventNotFound
+	}
+
+	e.NextAttempt = next
+	e.LastError = reason
+	return nil
+}
+
+func (q *EventQueue) DeadLetter(ctx context.Context, id uint64, attempts int, reason string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.claimed(id, attempts)
+	if !ok {
+		return strava.ErrEventNotFound
+	}
+
+	e.LastError = reason
+	delete(q.pending, id)
+	q.dead[id] = e
+	return nil
+}
+
+func (q *EventQueue) DeadLetters(ctx context.Context) ([]*strava.QueuedEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	events := make([]*strava.QueuedEvent, 0, len(q.dead))
+	for _, e := range q.dead {
+		c := *e
+		events = append(events, &c)
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	return events, nil
+}
+
+func (q *EventQueue) Replay(ctx context.Context, id uint64) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	e, ok := q.dead[id]
+	if !ok {
+		return strava.ErrEventNotFound
+	}
+
+	e.Attempts = 0
+	e.NextAttempt = time.Now()
+	delete(q.dead, id)
+	q.pending[id] = e
+	return nil
+}
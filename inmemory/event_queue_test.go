@@ -0,0 +1,14 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestEventQueue(t *testing.T) {
+	storagetest.RunEventQueueSuite(t, func(t *testing.T) strava.EventQueue {
+		return NewEventQueue()
+	})
+}
@@ -0,0 +1,99 @@
+package inmemory
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// DefaultSeenStoreSize is the number of event keys kept by default.
+const DefaultSeenStoreSize = 10_000
+
+// SeenStore remembers webhook event keys in memory. When full, the least recently seen key is evicted.
+type SeenStore struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+var _ strava.SeenStore = (*SeenStore)(nil)
+
+type seenEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+// NewSeenStore creates a store keeping up to size keys, DefaultSeenStoreSize if size isn't positive.
+func NewSeenStore(size int) *SeenStore {
+	if size <= 0 {
+		size = DefaultSeenStoreSize
+	}
+
+	return &SeenStore{
+		size:    size,
+		entries: make(map[string]*list.Element),
+		lru:     list.New(),
+	}
+}
+
+func (s *SeenStore) MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+
+	if el, ok := s.entries[key]; ok {
+		e := el.Value.(*seenEntry)
+		if now.Before(e.expiresAt) {
+			s.lru.MoveToFront(el)
+			return true, nil
+		}
+
+		e.expiresAt = now.Add(ttl)
+		s.lru.MoveToFront(el)
+		return false, nil
+	}
+
+	s.entries[key] = s.lru.PushFront(&seenEntry{key: key, expiresAt: now.Add(ttl)})
+
+	for s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.entries, oldest.Value.(*seenEntry).key)
+	}
+
+	return false, nil
+}
+
+func (s *SeenStore) Forget(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.entries[key]; ok {
+		s.lru.Remove(el)
+		delete(s.entries, key)
+	}
+
+	return nil
+}
+
+// Len returns the number of remembered keys, including expired ones not evicted yet.
+func (s *SeenStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.lru.Len()
+}
@@ -0,0 +1,40 @@
+package inmemory
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestSeenStore(t *testing.T) {
+	storagetest.RunSeenStoreSuite(t, func(t *testing.T) strava.SeenStore {
+		return NewSeenStore(0)
+	})
+}
+
+func TestSeenStore_Eviction(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	s := NewSeenStore(2)
+
+	for _, key := range []string{"a", "b", "a", "c"} {
+		_, err := s.MarkSeen(ctx, key, time.Hour)
+		assert.NoErr(t, err)
+	}
+
+	// act
+	seenA, errA := s.MarkSeen(ctx, "a", time.Hour)
+	seenB, errB := s.MarkSeen(ctx, "b", time.Hour)
+
+	// assert
+	assert.NoErr(t, errA)
+	assert.NoErr(t, errB)
+	assert.True(t, seenA)
+	assert.False(t, seenB)
+	assert.Eq(t, 2, s.Len())
+}
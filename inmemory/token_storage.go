@@ -10,14 +10,36 @@ import (
 
 type TokenStorage struct {
 	m sync.Map
+
+	// root is the storage holding the tokens of all namespaces, nil for the root itself.
+	root      *TokenStorage
+	namespace string
 }
 
-var _ strava.TokenStorage = (*TokenStorage)(nil)
+var (
+	_ strava.TokenStorage    = (*TokenStorage)(nil)
+	_ strava.TokenLister     = (*TokenStorage)(nil)
+	_ strava.TokenDeleter    = (*TokenStorage)(nil)
+	_ strava.TokenNamespacer = (*TokenStorage)(nil)
+)
+
+type key struct {
+	namespace string
+	athleteID uint
+}
 
-func (ts *TokenStorage) Get(_ context.Context, athleteID uint) (*strava.Token, error) {
+func (ts *TokenStorage) ForClient(clientID string) strava.TokenStorage {
+	return &TokenStorage{root: ts.store(), namespace: clientID}
+}
+
+func (ts *TokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token, error) {
 	slog.Debug("get token", "athleteID", athleteID)
 
-	v, ok := ts.m.Load(athleteID)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	v, ok := ts.store().m.Load(ts.key(athleteID))
 	if !ok {
 		return nil, strava.ErrTokenNotFound
 	}
@@ -27,12 +49,58 @@ func (ts *TokenStorage) Get(_ context.Context, athleteID uint) (*strava.Token, e
 		return nil, strava.ErrTokenNotFound
 	}
 
-	return t, nil
+	return t.Clone(), nil
 }
 
-func (ts *TokenStorage) Save(_ context.Context, token *strava.Token) error {
+func (ts *TokenStorage) Save(ctx context.Context, token *strava.Token) error {
 	slog.Debug("save token", "athleteID", token.AthleteID)
 
-	ts.m.Store(token.AthleteID, token)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ts.store().m.Store(ts.key(token.AthleteID), token.Clone())
 	return nil
 }
+
+func (ts *TokenStorage) Delete(ctx context.Context, athleteID uint) error {
+	slog.Debug("delete token", "athleteID", athleteID)
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	ts.store().m.Delete(ts.key(athleteID))
+	return nil
+}
+
+func (ts *TokenStorage) List(ctx context.Context) ([]*strava.Token, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var tokens []*strava.Token
+	ts.store().m.Range(func(k, v any) bool {
+		if k.(key).namespace != ts.namespace {
+			return true
+		}
+
+		if t, ok := v.(*strava.Token); ok {
+			tokens = append(tokens, t.Clone())
+		}
+		return true
+	})
+
+	return tokens, nil
+}
+
+func (ts *TokenStorage) store() *TokenStorage {
+	if ts.root != nil {
+		return ts.root
+	}
+	return ts
+}
+
+func (ts *TokenStorage) key(athleteID uint) key {
+	return key{namespace: ts.namespace, athleteID: athleteID}
+}
@@ -0,0 +1,14 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestTokenStorage(t *testing.T) {
+	storagetest.RunTokenStorageSuite(t, func(t *testing.T) strava.TokenStorage {
+		return &TokenStorage{}
+	})
+}
@@ -0,0 +1,38 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/marvell/strava-go"
+)
+
+type WebhookStateStore struct {
+	m sync.Map
+}
+
+var _ strava.WebhookStateStore = (*WebhookStateStore)(nil)
+
+func (s *WebhookStateStore) GetWebhookState(ctx context.Context, clientID string) (*strava.WebhookState, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	v, ok := s.m.Load(clientID)
+	if !ok {
+		return nil, strava.ErrWebhookStateNotFound
+	}
+
+	state := *v.(*strava.WebhookState)
+	return &state, nil
+}
+
+func (s *WebhookStateStore) SaveWebhookState(ctx context.Context, clientID string, state *strava.WebhookState) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	c := *state
+	s.m.Store(clientID, &c)
+	return nil
+}
@@ -0,0 +1,14 @@
+package inmemory
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestWebhookStateStore(t *testing.T) {
+	storagetest.RunWebhookStateStoreSuite(t, func(t *testing.T) strava.WebhookStateStore {
+		return &WebhookStateStore{}
+	})
+}
@@ -0,0 +1,96 @@
+// Package storageurl opens the bundled token storages from URLs, for the command-line tools.
+package storageurl
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	pgdriver "gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/file"
+	"github.com/marvell/strava-go/postgres"
+	"github.com/marvell/strava-go/redis"
+	"github.com/marvell/strava-go/sqlite"
+)
+
+// Usage describes the supported URLs.
+const Usage = `file://<dir>, sqlite://<path>, postgres://<user>:<password>@<host>/<db>, redis://[:<password>@]<host>:<port>[/<db>]`
+
+// Storage is a token storage with the optional capabilities of the bundled ones.
+type Storage interface {
+	strava.TokenStorage
+	strava.TokenLister
+	strava.TokenDeleter
+}
+
+// Open opens the storage described by rawURL. The returned function releases its resources.
+func Open(rawURL string) (Storage, func() error, error) {
+	scheme, rest, ok := strings.Cut(rawURL, "://")
+	if !ok {
+		return nil, nil, fmt.Errorf("invalid storage URL %q, expected one of %s", rawURL, Usage)
+	}
+
+	noop := func() error { return nil }
+
+	switch scheme {
+	case "file":
+		ts, err := file.NewTokenStorage(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ts, noop, nil
+	case "sqlite":
+		db, err := sqlite.Open(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		ts, err := sqlite.NewTokenStorage(db)
+		if err != nil {
+			_ = db.Close()
+			return nil, nil, err
+		}
+		return ts, db.Close, nil
+	case "postgres", "postgresql":
+		db, err := gorm.Open(pgdriver.Open(rawURL), &gorm.Config{Logger: logger.Discard})
+		if err != nil {
+			return nil, nil, fmt.Errorf("open database: %w", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, nil, err
+		}
+		ts, err := postgres.NewTokenStorage(db)
+		if err != nil {
+			_ = sqlDB.Close()
+			return nil, nil, err
+		}
+		return ts, sqlDB.Close, nil
+	case "redis":
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parse URL: %w", err)
+		}
+
+		var opts []redis.Option
+		if password, ok := u.User.Password(); ok {
+			opts = append(opts, redis.WithPassword(password))
+		}
+		if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+			n, err := strconv.Atoi(db)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid redis database %q", db)
+			}
+			opts = append(opts, redis.WithDB(n))
+		}
+
+		ts := redis.NewTokenStorage(u.Host, opts...)
+		return ts, ts.Close, nil
+	default:
+		return nil, nil, fmt.Errorf("unsupported storage %q, expected one of %s", scheme, Usage)
+	}
+}
@@ -0,0 +1,173 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// DefaultMigratePageSize is the number of tokens MigrateTokens reads at once from a TokenPager.
+const DefaultMigratePageSize = 500
+
+// ConflictPolicy decides what MigrateTokens does with tokens already present in the destination.
+type ConflictPolicy string
+
+const (
+	// ConflictSkip keeps the destination's token.
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the destination's token.
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictNewerExpiry keeps the token expiring later.
+	ConflictNewerExpiry ConflictPolicy = "newer-expiry"
+)
+
+func ParseConflictPolicy(s string) (ConflictPolicy, error) {
+	switch p := ConflictPolicy(s); p {
+	case ConflictSkip, ConflictOverwrite, ConflictNewerExpiry:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown conflict policy %q", s)
+	}
+}
+
+type MigrateOptions struct {
+	// Conflict is the policy for tokens present in both storages, ConflictSkip by default.
+	Conflict ConflictPolicy
+	// DryRun only reports what would be copied.
+	DryRun bool
+	// Verify reads every copied token back from the destination and compares it with the source.
+	Verify bool
+	// PageSize is the number of tokens read at once if the source implements TokenPager,
+	// DefaultMigratePageSize by default.
+	PageSize int
+}
+
+type MigrateReport struct {
+	Total   int
+	Copied  int
+	Skipped int
+	// Mismatched lists athletes whose tokens didn't match the source in the verification pass.
+	Mismatched []uint
+}
+
+// MigrateTokens copies all tokens from src to dst. If src implements TokenPager, tokens are read
+// and copied page by page, otherwise all of them are listed at once.
+func MigrateTokens(ctx context.Context, src TokenLister, dst TokenStorage, opts MigrateOptions) (*MigrateReport, error) {
+	if opts.Conflict == "" {
+		opts.Conflict = ConflictSkip
+	}
+	if opts.PageSize <= 0 {
+		opts.PageSize = DefaultMigratePageSize
+	}
+
+	report := &MigrateReport{}
+
+	err := eachTokenPage(ctx, src, opts.PageSize, func(tokens []*Token) error {
+		report.Total += len(tokens)
+
+		var copied []*Token
+		for _, token := range tokens {
+			existing, err := dst.Get(ctx, token.AthleteID)
+			if err != nil && !errors.Is(err, ErrTokenNotFound) {
+				return fmt.Errorf("get athlete %d token from %T: %w", token.AthleteID, dst, err)
+			}
+
+			if existing != nil && !replaces(opts.Conflict, existing, token) {
+				report.Skipped++
+				continue
+			}
+
+			if !opts.DryRun {
+				if err := dst.Save(ctx, token); err != nil {
+					return fmt.Errorf("save athlete %d token to %T: %w", token.AthleteID, dst, err)
+				}
+			}
+
+			report.Copied++
+			copied = append(copied, token)
+		}
+
+		if opts.Verify && !opts.DryRun {
+			for _, want := range copied {
+				got, err := dst.Get(ctx, want.AthleteID)
+				if err != nil || !sameToken(want, got) {
+					report.Mismatched = append(report.Mismatched, want.AthleteID)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+// eachTokenPage calls fn with the tokens of src, page by page if src implements TokenPager.
+func eachTokenPage(ctx context.Context, src TokenLister, pageSize int, fn func([]*Token) error) error {
+	pager, ok := src.(TokenPager)
+	if !ok {
+		tokens, err := src.List(ctx)
+		if err != nil {
+			return fmt.Errorf("list tokens from %T: %w", src, err)
+		}
+		return fn(tokens)
+	}
+
+	var afterID uint
+	for {
+		tokens, err := pager.ListAfter(ctx, afterID, pageSize)
+		if err != nil {
+			return fmt.Errorf("list tokens from %T: %w", src, err)
+		}
+		if len(tokens) == 0 {
+			return nil
+		}
+
+		if err := fn(tokens); err != nil {
+			return err
+		}
+
+		if len(tokens) < pageSize {
+			return nil
+		}
+		afterID = tokens[len(tokens)-1].AthleteID
+	}
+}
+
+func replaces(policy ConflictPolicy, existing, token *Token) bool {
+	switch policy {
+	case ConflictOverwrite:
+		return true
+	case ConflictNewerExpiry:
+		return existing.Token == nil || (token.Token != nil && token.Expiry.After(existing.Expiry))
+	default:
+		return false
+	}
+}
+
+// sameToken reports whether the tokens have equal fields. Expiries may differ by less than a
+// microsecond, the least precision a storage has to keep.
+func sameToken(a, b *Token) bool {
+	if a.AthleteID != b.AthleteID || a.Scope != b.Scope || a.Revoked != b.Revoked {
+		return false
+	}
+
+	if (len(a.Raw) > 0 || len(b.Raw) > 0) && !reflect.DeepEqual(a.Raw, b.Raw) {
+		return false
+	}
+
+	if a.Token == nil || b.Token == nil {
+		return a.Token == nil && b.Token == nil
+	}
+
+	d := a.Expiry.Sub(b.Expiry)
+	return a.AccessToken == b.AccessToken &&
+		a.TokenType == b.TokenType &&
+		a.RefreshToken == b.RefreshToken &&
+		d > -time.Microsecond && d < time.Microsecond
+}
@@ -0,0 +1,219 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+)
+
+// testTokenPager lists the tokens of a testTokenStorage page by page and records the pages.
+type testTokenPager struct {
+	*testTokenStorage
+	pages [][]uint
+}
+
+func (p *testTokenPager) ListAfter(ctx context.Context, afterID uint, limit int) ([]*Token, error) {
+	tokens, _ := p.List(ctx)
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].AthleteID < tokens[j].AthleteID })
+
+	var page []*Token
+	var ids []uint
+	for _, token := range tokens {
+		if token.AthleteID > afterID && len(page) < limit {
+			page = append(page, token)
+			ids = append(ids, token.AthleteID)
+		}
+	}
+
+	p.pages = append(p.pages, ids)
+	return page, nil
+}
+
+// mangledTokenStorage saves tokens with the "read" scope.
+type mangledTokenStorage struct {
+	*testTokenStorage
+}
+
+func (s mangledTokenStorage) Save(ctx context.Context, token *Token) error {
+	mangled := token.Clone()
+	mangled.Scope = "read"
+	return s.testTokenStorage.Save(ctx, mangled)
+}
+
+func testMigrateTokens() (src, dst *testTokenStorage) {
+	now := time.Now()
+	src = newTestTokenStorage(
+		&Token{AthleteID: 1, Token: &oauth2.Token{AccessToken: "src1", Expiry: now.Add(time.Hour)}},
+		&Token{AthleteID: 2, Token: &oauth2.Token{AccessToken: "src2", Expiry: now.Add(time.Hour)}},
+		&Token{AthleteID: 3, Token: &oauth2.Token{AccessToken: "src3", Expiry: now.Add(time.Hour)}},
+	)
+	dst = newTestTokenStorage(
+		&Token{AthleteID: 1, Token: &oauth2.Token{AccessToken: "dst1", Expiry: now.Add(2 * time.Hour)}},
+		&Token{AthleteID: 2, Token: &oauth2.Token{AccessToken: "dst2", Expiry: now}},
+	)
+	return src, dst
+}
+
+func TestMigrateTokens(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       MigrateOptions
+		wantCopied int
+		want       map[uint]string
+	}{
+		{
+			name:       "newer expiry",
+			opts:       MigrateOptions{Conflict: ConflictNewerExpiry, Verify: true},
+			wantCopied: 2,
+			want:       map[uint]string{1: "dst1", 2: "src2", 3: "src3"},
+		},
+		{
+			name:       "skip",
+			opts:       MigrateOptions{Verify: true},
+			wantCopied: 1,
+			want:       map[uint]string{1: "dst1", 2: "dst2", 3: "src3"},
+		},
+		{
+			name:       "overwrite",
+			opts:       MigrateOptions{Conflict: ConflictOverwrite, Verify: true},
+			wantCopied: 3,
+			want:       map[uint]string{1: "src1", 2: "src2", 3: "src3"},
+		},
+		{
+			name:       "dry run",
+			opts:       MigrateOptions{Conflict: ConflictOverwrite, DryRun: true, Verify: true},
+			wantCopied: 3,
+			want:       map[uint]string{1: "dst1", 2: "dst2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			ctx := context.Background()
+			src, dst := testMigrateTokens()
+
+			// act
+			report, err := MigrateTokens(ctx, src, dst, tt.opts)
+
+			// assert
+			assert.NoErr(t, err)
+			assert.Eq(t, 3, report.Total)
+			assert.Eq(t, tt.wantCopied, report.Copied)
+			assert.Eq(t, 3-tt.wantCopied, report.Skipped)
+			assert.Empty(t, report.Mismatched)
+
+			tokens, _ := dst.List(ctx)
+			assert.Len(t, tokens, len(tt.want))
+			for athleteID, want := range tt.want {
+				got, err := dst.Get(ctx, athleteID)
+				assert.NoErr(t, err)
+				assert.Eq(t, want, got.AccessToken)
+			}
+		})
+	}
+}
+
+func TestMigrateTokens_Pages(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	src, dst := testMigrateTokens()
+	pager := &testTokenPager{testTokenStorage: src}
+
+	// act
+	report, err := MigrateTokens(ctx, pager, dst, MigrateOptions{Conflict: ConflictOverwrite, PageSize: 2})
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 3, report.Total)
+	assert.Eq(t, 3, report.Copied)
+	assert.Eq(t, [][]uint{{1, 2}, {3}}, pager.pages)
+}
+
+func TestMigrateTokens_VerifyMismatch(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	src, _ := testMigrateTokens()
+	dst := mangledTokenStorage{newTestTokenStorage()}
+
+	// act
+	report, err := MigrateTokens(ctx, src, dst, MigrateOptions{Verify: true})
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 3, report.Copied)
+	sort.Slice(report.Mismatched, func(i, j int) bool { return report.Mismatched[i] < report.Mismatched[j] })
+	assert.Eq(t, []uint{1, 2, 3}, report.Mismatched)
+}
+
+func TestMigrateTokens_SaveError(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	src, _ := testMigrateTokens()
+
+	// act
+	_, err := MigrateTokens(ctx, src, failingTokenStorage{}, MigrateOptions{})
+
+	// assert
+	assert.ErrIs(t, err, errSaveFailed)
+}
+
+var errSaveFailed = errors.New("save failed")
+
+type failingTokenStorage struct{}
+
+func (failingTokenStorage) Get(context.Context, uint) (*Token, error) { return nil, ErrTokenNotFound }
+func (failingTokenStorage) Save(context.Context, *Token) error        { return errSaveFailed }
+
+func TestSameToken(t *testing.T) {
+	expiry := time.Date(2024, 5, 17, 10, 30, 15, 123456789, time.UTC)
+	base := func() *Token {
+		return &Token{
+			Token:     &oauth2.Token{AccessToken: "a", TokenType: "Bearer", RefreshToken: "r", Expiry: expiry},
+			AthleteID: 1,
+			Scope:     "read",
+			Raw:       map[string]any{"athlete": map[string]any{"id": float64(1)}},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		mutate func(*Token)
+		want   bool
+	}{
+		{name: "equal", mutate: func(*Token) {}, want: true},
+		{name: "expiry rounded to microseconds", mutate: func(t *Token) { t.Expiry = expiry.Round(time.Microsecond).In(time.Local) }, want: true},
+		{name: "expiry", mutate: func(t *Token) { t.Expiry = expiry.Add(time.Second) }, want: false},
+		{name: "athlete", mutate: func(t *Token) { t.AthleteID = 2 }, want: false},
+		{name: "scope", mutate: func(t *Token) { t.Scope = "read_all" }, want: false},
+		{name: "revoked", mutate: func(t *Token) { t.Revoked = true }, want: false},
+		{name: "raw", mutate: func(t *Token) { t.Raw = nil }, want: false},
+		{name: "token type", mutate: func(t *Token) { t.TokenType = "" }, want: false},
+		{name: "no oauth token", mutate: func(t *Token) { t.Token = nil }, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			b := base()
+			tt.mutate(b)
+
+			// act
+			got := sameToken(base(), b)
+
+			// assert
+			assert.Eq(t, tt.want, got)
+		})
+	}
+
+	// Tokens without oauth2 tokens still differ by their other fields.
+	a, b := base(), base()
+	a.Token, b.Token = nil, nil
+	b.AthleteID = 2
+	assert.False(t, sameToken(a, b))
+}
@@ -1,6 +1,9 @@
 package strava
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Fault represents a Strava API error response
 type Fault struct {
@@ -136,6 +139,9 @@ type SummaryActivity struct {
 	MaxHeartrate         float64      `json:"max_heartrate"`
 	MaxWatts             int          `json:"max_watts"`
 	SufferScore          int          `json:"suffer_score"`
+
+	// Raw is the activity's JSON returned by the API, including fields the struct doesn't cover.
+	Raw json.RawMessage `json:"-"`
 }
 
 // DetailedActivity represents a detailed activity on Strava
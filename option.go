@@ -3,6 +3,7 @@ package strava
 import (
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"time"
 
 	"golang.org/x/time/rate"
@@ -33,7 +34,9 @@ func WithDebug() Option {
 	}
 }
 
-func WithTransport(t *http.Transport) Option {
+// WithTransport sets the round tripper of API and token requests, e.g. an *http.Transport or a
+// recorder.Recorder. OAuth adds the Authorization header before the request reaches it.
+func WithTransport(t http.RoundTripper) Option {
 	return func(c *Client) {
 		c.transport = t
 	}
@@ -45,8 +48,146 @@ func WithWebhookCallbackURL(url string) Option {
 	}
 }
 
+// WithWebhookStateStore persists the push subscription, so it's known to the client's replicas
+// and after restarts. See LoadWebhookState.
+func WithWebhookStateStore(s WebhookStateStore) Option {
+	return func(c *Client) {
+		c.webhookState = s
+	}
+}
+
+// WithWebhookSubscriptionID sets the ID of a push subscription managed outside the client, e.g. the one
+// of a webhooksim.Simulator in local tests. Events of other subscriptions are rejected.
+func WithWebhookSubscriptionID(id uint) Option {
+	return func(c *Client) {
+		c.subscriptionID.Store(uint64(id))
+	}
+}
+
+// WithWebhookVerifyToken sets the token Strava echoes when validating the callback URL.
+// By default a random token is generated and saved in the WebhookStateStore before the subscription
+// is created. Replicas behind the callback URL need either this option or a shared WebhookStateStore,
+// otherwise each generates its own token and the validation fails on all but one.
+func WithWebhookVerifyToken(token string) Option {
+	return func(c *Client) {
+		c.verifyToken = token
+	}
+}
+
+// WithWebhookAllowedIPs rejects webhook requests from addresses outside the prefixes.
+// Behind a proxy, use WithWebhookClientIPHeader to take the address from the header it sets.
+func WithWebhookAllowedIPs(prefixes ...netip.Prefix) Option {
+	return func(c *Client) {
+		c.webhookAllowedIPs = prefixes
+	}
+}
+
+// WithWebhookClientIPHeader takes the source address of webhook requests from the last value of header,
+// e.g. X-Forwarded-For. Only use it behind a proxy that sets the header.
+func WithWebhookClientIPHeader(header string) Option {
+	return func(c *Client) {
+		c.webhookClientIPHeader = header
+	}
+}
+
+// WithWebhookMaxBodySize limits the size of webhook request bodies, DefaultWebhookMaxBodySize by default.
+func WithWebhookMaxBodySize(n int64) Option {
+	return func(c *Client) {
+		if n > 0 {
+			c.webhookMaxBodySize = n
+		}
+	}
+}
+
+// WithWebhookWorkers sets the number of workers handling webhook events and the number of events
+// waiting for a worker. Events received while the queue is full are rejected, so Strava retries them.
+// The queue size doesn't apply to events persisted with WithEventQueue.
+func WithWebhookWorkers(workers, queueSize int) Option {
+	return func(c *Client) {
+		if workers > 0 {
+			c.dispatcher.workers = workers
+		}
+		if queueSize >= 0 {
+			c.dispatcher.queueSize = queueSize
+		}
+	}
+}
+
+// WithEventQueue persists webhook events in q before acknowledging them. Events are removed from
+// the queue once all handlers succeed, failed events are retried as configured by WithEventRetry.
+// Handlers must be idempotent, as an event is handled again if any of them fails.
+// Clients of different applications must not share a queue.
+func WithEventQueue(q EventQueue) Option {
+	return func(c *Client) {
+		c.dispatcher.store = q
+	}
+}
+
+// WithEventRetry sets how often events of the EventQueue are attempted before they are dead-lettered,
+// and the bounds of the exponential backoff between attempts.
+func WithEventRetry(maxAttempts int, minBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		if maxAttempts > 0 {
+			c.dispatcher.maxAttempts = maxAttempts
+		}
+		if minBackoff > 0 {
+			c.dispatcher.minBackoff = minBackoff
+		}
+		if maxBackoff > 0 {
+			c.dispatcher.maxBackoff = maxBackoff
+		}
+	}
+}
+
+// WithEventDeduplication drops webhook events whose IdempotencyKey was seen within the ttl,
+// DefaultEventDedupTTL if ttl is zero. Dropped duplicates are counted in EventStats.
+func WithEventDeduplication(store SeenStore, ttl time.Duration) Option {
+	return func(c *Client) {
+		c.seen = store
+		c.seenTTL = ttl
+		if ttl <= 0 {
+			c.seenTTL = DefaultEventDedupTTL
+		}
+	}
+}
+
+// WithDeauthorizedTokenDeletion deletes the token of an athlete who revoked the application's access,
+// when the deauthorization webhook event is received. Storages that can't delete tokens keep it marked as revoked.
+func WithDeauthorizedTokenDeletion() Option {
+	return func(c *Client) {
+		c.deleteDeauthorizedTokens = true
+	}
+}
+
+// WithOnDeauthorized sets the handler called when an athlete revokes the application's access.
+// It's called after the token is deleted, and before the event handlers receive the event.
+func WithOnDeauthorized(h DeauthorizedHandler) Option {
+	return func(c *Client) {
+		c.onDeauthorized = h
+	}
+}
+
 func WithScopes(scopes ...string) Option {
 	return func(c *Client) {
 		c.oacfg.Scopes = scopes
 	}
 }
+
+// WithTokenNamespace keeps the client's tokens apart from tokens of other Strava applications
+// sharing the token storage. The storage must implement TokenNamespacer, see Client.Err.
+//
+// Tokens saved without the option aren't visible to clients using it.
+func WithTokenNamespace() Option {
+	return func(c *Client) {
+		c.namespaceTokens = true
+	}
+}
+
+// WithTokenHooks sets hooks called on token lifecycle events, replacing the default
+// NewSlogTokenHooks with the client's logger. Pass TokenHooks{} to disable them.
+func WithTokenHooks(h TokenHooks) Option {
+	return func(c *Client) {
+		c.hooks = h
+		c.hooksSet = true
+	}
+}
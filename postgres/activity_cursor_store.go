@@ -0,0 +1,78 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/marvell/strava-go"
+)
+
+// ActivityCursor is a row of the strava_activity_cursors table.
+type ActivityCursor struct {
+	AthleteID  uint `gorm:"primaryKey;autoIncrement:false"`
+	StartDate  time.Time
+	ActivityID uint
+	UpdatedAt  time.Time
+}
+
+func (c ActivityCursor) TableName() string {
+	return "strava_activity_cursors"
+}
+
+// NewActivityCursorStore creates a GORM based activity cursor store and migrates the database schema.
+func NewActivityCursorStore(db *gorm.DB) (*ActivityCursorStore, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return migrate(context.Background(), gormMigrator{tx}, activityCursorMigrations)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not migrate: %w", err)
+	}
+
+	return &ActivityCursorStore{db: db}, nil
+}
+
+type ActivityCursorStore struct {
+	db *gorm.DB
+}
+
+var _ strava.ActivityCursorStore = (*ActivityCursorStore)(nil)
+
+func (s *ActivityCursorStore) GetActivityCursor(ctx context.Context, athleteID uint) (*strava.ActivityCursor, error) {
+	var row ActivityCursor
+	if err := s.db.WithContext(ctx).Where("athlete_id = ?", athleteID).Take(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, strava.ErrActivityCursorNotFound
+		}
+
+		return nil, fmt.Errorf("could not get activity cursor: %w", err)
+	}
+
+	return &strava.ActivityCursor{
+		AthleteID:  row.AthleteID,
+		StartDate:  row.StartDate,
+		ActivityID: row.ActivityID,
+	}, nil
+}
+
+func (s *ActivityCursorStore) SaveActivityCursor(ctx context.Context, cursor *strava.ActivityCursor) error {
+	row := &ActivityCursor{
+		AthleteID:  cursor.AthleteID,
+		StartDate:  cursor.StartDate,
+		ActivityID: cursor.ActivityID,
+	}
+
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "athlete_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"start_date", "activity_id", "updated_at"}),
+	}).Create(row).Error
+	if err != nil {
+		return fmt.Errorf("could not save activity cursor: %w", err)
+	}
+
+	return nil
+}
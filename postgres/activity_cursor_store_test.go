@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestActivityCursorStore(t *testing.T) {
+	storagetest.RunActivityCursorStoreSuite(t, func(t *testing.T) strava.ActivityCursorStore {
+		db := newTestDB(t)
+
+		s, err := NewActivityCursorStore(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.Exec("TRUNCATE strava_activity_cursors").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	})
+}
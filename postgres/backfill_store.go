@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/marvell/strava-go"
+)
+
+// Backfill is a row of the strava_backfills table.
+type Backfill struct {
+	AthleteID  uint          `gorm:"primaryKey;autoIncrement:false"`
+	From       time.Time     `gorm:"column:history_from"`
+	To         time.Time     `gorm:"column:history_to"`
+	Window     time.Duration `gorm:"column:window_size"`
+	Before     time.Time
+	Activities int
+	Requests   int
+	LastError  string
+	Abandoned  bool
+	UpdatedAt  time.Time `gorm:"autoUpdateTime:false"`
+}
+
+func (b Backfill) TableName() string {
+	return "strava_backfills"
+}
+
+func (b *Backfill) backfill() *strava.Backfill {
+	return &strava.Backfill{
+		AthleteID:  b.AthleteID,
+		From:       b.From,
+		To:         b.To,
+		Window:     b.Window,
+		Before:     b.Before,
+		Activities: b.Activities,
+		Requests:   b.Requests,
+		LastError:  b.LastError,
+		Abandoned:  b.Abandoned,
+		UpdatedAt:  b.UpdatedAt,
+	}
+}
+
+// NewBackfillStore creates a GORM based backfill store and migrates the database schema.
+func NewBackfillStore(db *gorm.DB) (*BackfillStore, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return migrate(context.Background(), gormMigrator{tx}, backfillMigrations)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not migrate: %w", err)
+	}
+
+	return &BackfillStore{db: db}, nil
+}
+
+type BackfillStore struct {
+	db *gorm.DB
+}
+
+var _ strava.BackfillStore = (*BackfillStore)(nil)
+
+func (s *BackfillStore) GetBackfill(ctx context.Context, athleteID uint) (*strava.Backfill, error) {
+	var row Backfill
+	if err := s.db.WithContext(ctx).Where("athlete_id = ?", athleteID).Take(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, strava.ErrBackfillNotFound
+		}
+
+		return nil, fmt.Errorf("could not get backfill: %w", err)
+	}
+
+	return row.backfill(), nil
+}
+
+func (s *BackfillStore) SaveBackfill(ctx context.Context, backfill *strava.Backfill) error {
+	row := &Backfill{
+		AthleteID:  backfill.AthleteID,
+		From:       backfill.From,
+		To:         backfill.To,
+		Window:     backfill.Window,
+		Before:     backfill.Before,
+		Activities: backfill.Activities,
+		Requests:   backfill.Requests,
+		LastError:  backfill.LastError,
+		Abandoned:  backfill.Abandoned,
+		UpdatedAt:  backfill.UpdatedAt,
+	}
+
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "athlete_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"history_from", "history_to", "window_size", "before", "activities", "requests", "last_error", "abandoned", "updated_at",
+		}),
+	}).Create(row).Error
+	if err != nil {
+		return fmt.Errorf("could not save backfill: %w", err)
+	}
+
+	return nil
+}
+
+func (s *BackfillStore) ListBackfills(ctx context.Context) ([]*strava.Backfill, error) {
+	var rows []Backfill
+	if err := s.db.WithContext(ctx).Order("athlete_id").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("could not list backfills: %w", err)
+	}
+
+	backfills := make([]*strava.Backfill, 0, len(rows))
+	for i := range rows {
+		backfills = append(backfills, rows[i].backfill())
+	}
+
+	return backfills, nil
+}
@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestBackfillStore(t *testing.T) {
+	storagetest.RunBackfillStoreSuite(t, func(t *testing.T) strava.BackfillStore {
+		db := newTestDB(t)
+
+		s, err := NewBackfillStore(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.Exec("TRUNCATE strava_backfills").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	})
+}
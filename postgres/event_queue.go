@@ -0,0 +1,223 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/marvell/strava-go"
+)
+
+// QueuedEvent is a row of the strava_webhook_events table.
+type QueuedEvent struct {
+	ID          uint64 `gorm:"primaryKey"`
+	Event       []byte `gorm:"type:jsonb"`
+	Attempts    int
+	NextAttempt time.Time
+	LastError   string
+	EnqueuedAt  time.Time
+}
+
+func (e QueuedEvent) TableName() string {
+	return "strava_webhook_events"
+}
+
+// DeadLetter is a row of the strava_webhook_dead_letters table.
+type DeadLetter struct {
+	ID         uint64 `gorm:"primaryKey;autoIncrement:false"`
+	Event      []byte `gorm:"type:jsonb"`
+	Attempts   int
+	LastError  string
+	EnqueuedAt time.Time
+	FailedAt   time.Time
+}
+
+func (d DeadLetter) TableName() string {
+	return "strava_webhook_dead_letters"
+}
+
+func (e QueuedEvent) stravaEvent() (*strava.QueuedEvent, error) {
+	qe := &strava.QueuedEvent{
+		ID:          e.ID,
+		Attempts:    e.Attempts,
+		NextAttempt: e.NextAttempt,
+		LastError:   e.LastError,
+		EnqueuedAt:  e.EnqueuedAt,
+	}
+
+	if err := json.Unmarshal(e.Event, &qe.Event); err != nil {
+		return nil, fmt.Errorf("unmarshal event %d: %w", e.ID, err)
+	}
+
+	return qe, nil
+}
+
+// NewEventQueue creates a GORM based webhook event queue and migrates the database schema.
+// Workers of several processes can share the queue, a claimed event is locked for the others.
+func NewEventQueue(db *gorm.DB) (*EventQueue, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return migrate(context.Background(), gormMigrator{tx}, eventQueueMigrations)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not migrate: %w", err)
+	}
+
+	return &EventQueue{db: db}, nil
+}
+
+type EventQueue struct {
+	db *gorm.DB
+}
+
+var _ strava.EventQueue = (*EventQueue)(nil)
+
+func (q *EventQueue) Enqueue(ctx context.Context, event strava.Event) error {
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	now := time.Now()
+	if err := q.db.WithContext(ctx).Create(&QueuedEvent{Event: b, NextAttempt: now, EnqueuedAt: now}).Error; err != nil {
+		return fmt.Errorf("could not enqueue event: %w", err)
+	}
+
+	return nil
+}
+
+func (q *EventQueue) Claim(ctx context.Context, timeout time.Duration) (*strava.QueuedEvent, error) {
+	now := time.Now()
+
+	var rows []QueuedEvent
+	err := q.db.WithContext(ctx).Raw(`UPDATE strava_webhook_events
+		SET attempts = attempts + 1, next_attempt = ?
+		WHERE id = (
+			SELECT id FROM strava_webhook_events
+			WHERE next_attempt <= ?
+			ORDER BY next_attempt, id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, now.Add(timeout), now).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("could not claim event: %w", err)
+	}
+
+	if len(rows) == 0 {
+		return nil, strava.ErrNoEventDue
+	}
+
+	return rows[0].stravaEvent()
+}
+
+func (q *EventQueue) Ack(ctx context.Context, id uint64, attempts int) error {
+	res := q.db.WithContext(ctx).Where("id = ? AND attempts = ?", id, attempts).Delete(&QueuedEvent{})
+	if res.Error != nil {
+		return fmt.Errorf("could not ack event: %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return strava.ErrEventNotFound
+	}
+
+	return nil
+}
+
+func (q *EventQueue) Retry(ctx context.Context, id uint64, attempts int, next time.Time, reason string) error {
+	res := q.db.WithContext(ctx).Model(&QueuedEvent{}).Where("id = ? AND attempts = ?", id, attempts).Updates(map[string]any{
+		"next_attempt": next,
+		"last_error":   reason,
+	})
+	if res.Error != nil {
+		return fmt.Errorf("could not retry event: %w", res.Error)
+	}
+
+	if res.RowsAffected == 0 {
+		return strava.ErrEventNotFound
+	}
+
+	return nil
+}
+
+func (q *EventQueue) DeadLetter(ctx context.Context, id uint64, attempts int, reason string) error {
+	return q.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []QueuedEvent
+		if err := tx.Raw(`DELETE FROM strava_webhook_events WHERE id = ? AND attempts = ? RETURNING *`, id, attempts).Scan(&rows).Error; err != nil {
+			return fmt.Errorf("could not dead-letter event: %w", err)
+		}
+
+		if len(rows) == 0 {
+			return strava.ErrEventNotFound
+		}
+
+		e := rows[0]
+		err := tx.Create(&DeadLetter{
+			ID:         e.ID,
+			Event:      e.Event,
+			Attempts:   e.Attempts,
+			LastError:  reason,
+			EnqueuedAt: e.EnqueuedAt,
+			FailedAt:   time.Now(),
+		}).Error
+		if err != nil {
+			return fmt.Errorf("could not dead-letter event: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func (q *EventQueue) DeadLetters(ctx context.Context) ([]*strava.QueuedEvent, error) {
+	var rows []DeadLetter
+	if err := q.db.WithContext(ctx).Order("id").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("could not list dead letters: %w", err)
+	}
+
+	events := make([]*strava.QueuedEvent, 0, len(rows))
+	for _, d := range rows {
+		e, err := QueuedEvent{
+			ID:          d.ID,
+			Event:       d.Event,
+			Attempts:    d.Attempts,
+			NextAttempt: d.FailedAt,
+			LastError:   d.LastError,
+			EnqueuedAt:  d.EnqueuedAt,
+		}.stravaEvent()
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+func (q *EventQueue) Replay(ctx context.Context, id uint64) error {
+	return q.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var rows []DeadLetter
+		if err := tx.Raw(`DELETE FROM strava_webhook_dead_letters WHERE id = ? RETURNING *`, id).Scan(&rows).Error; err != nil {
+			return fmt.Errorf("could not replay event: %w", err)
+		}
+
+		if len(rows) == 0 {
+			return strava.ErrEventNotFound
+		}
+
+		d := rows[0]
+		err := tx.Create(&QueuedEvent{
+			ID:          d.ID,
+			Event:       d.Event,
+			NextAttempt: time.Now(),
+			LastError:   d.LastError,
+			EnqueuedAt:  d.EnqueuedAt,
+		}).Error
+		if err != nil {
+			return fmt.Errorf("could not replay event: %w", err)
+		}
+
+		return nil
+	})
+}
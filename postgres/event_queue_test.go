@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestEventQueue(t *testing.T) {
+	storagetest.RunEventQueueSuite(t, func(t *testing.T) strava.EventQueue {
+		db := newTestDB(t)
+
+		q, err := NewEventQueue(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.Exec("TRUNCATE strava_webhook_events, strava_webhook_dead_letters").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		return q
+	})
+}
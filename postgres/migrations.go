@@ -0,0 +1,413 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// migrationsLockID is the advisory lock serializing migrations of concurrently starting processes.
+const migrationsLockID = 7_385_116_011
+
+// migrationSet is the schema of one store, so a store only creates its own tables. Migrations of a set
+// are applied in order, strava_schema_migrations holds the applied versions of each set.
+type migrationSet struct {
+	name       string
+	migrations []string
+}
+
+// tokenMigrations migrate the schema of the tokens, see TokenStorage and PgxTokenStorage.
+var tokenMigrations = migrationSet{
+	name: "tokens",
+	migrations: []string{
+		// 1: explicit token columns instead of the gorm.Model based table of earlier versions,
+		// which is kept as strava_tokens_gorm after its tokens are copied.
+		`DO $$
+		BEGIN
+			IF EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'strava_tokens' AND column_name = 'id'
+			) THEN
+				ALTER TABLE strava_tokens RENAME TO strava_tokens_gorm;
+			END IF;
+		END $$;
+
+		CREATE TABLE strava_tokens (
+			athlete_id    bigint      PRIMARY KEY,
+			access_token  text        NOT NULL,
+			token_type    text        NOT NULL DEFAULT '',
+			refresh_token text        NOT NULL DEFAULT '',
+			expiry        timestamptz,
+			scope         text        NOT NULL DEFAULT '',
+			revoked       boolean     NOT NULL DEFAULT false,
+			extra         jsonb,
+			created_at    timestamptz NOT NULL DEFAULT now(),
+			updated_at    timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE INDEX strava_tokens_expiry_idx ON strava_tokens (expiry);
+
+		-- Only tables of later versions have the revoked and raw columns.
+		DO $$
+		DECLARE
+			revoked text := 'false';
+			extra   text := 'NULL';
+		BEGIN
+			IF to_regclass('strava_tokens_gorm') IS NULL THEN
+				RETURN;
+			END IF;
+
+			IF EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'strava_tokens_gorm' AND column_name = 'revoked'
+			) THEN
+				revoked := 'coalesce(revoked, false)';
+			END IF;
+
+			IF EXISTS (
+				SELECT 1 FROM information_schema.columns
+				WHERE table_schema = current_schema() AND table_name = 'strava_tokens_gorm' AND column_name = 'raw'
+			) THEN
+				extra := 'nullif(nullif(raw::text, ''''), ''null'')::jsonb';
+			END IF;
+
+			EXECUTE format(
+				'INSERT INTO strava_tokens (athlete_id, access_token, token_type, refresh_token, expiry, scope, revoked, extra, created_at, updated_at)
+				SELECT id, coalesce(access_token, ''''), coalesce(token_type, ''''), coalesce(refresh_token, ''''), expiry, coalesce(scope, ''''), %s, %s, created_at, updated_at
+				FROM strava_tokens_gorm
+				WHERE deleted_at IS NULL
+				ON CONFLICT (athlete_id) DO NOTHING',
+				revoked, extra);
+		END $$;`,
+
+		// 2: tokens are namespaced by client ID.
+		`ALTER TABLE strava_tokens ADD COLUMN client_id text NOT NULL DEFAULT '';
+		ALTER TABLE strava_tokens DROP CONSTRAINT strava_tokens_pkey;
+		ALTER TABLE strava_tokens ADD PRIMARY KEY (client_id, athlete_id);`,
+	},
+}
+
+// eventQueueMigrations migrate the schema of the webhook event queue, see EventQueue.
+var eventQueueMigrations = migrationSet{
+	name: "webhook_events",
+	migrations: []string{
+		// 1: webhook event queue and its dead letters.
+		`CREATE TABLE strava_webhook_events (
+			id           bigserial   PRIMARY KEY,
+			event        jsonb       NOT NULL,
+			attempts     integer     NOT NULL DEFAULT 0,
+			next_attempt timestamptz NOT NULL,
+			last_error   text        NOT NULL DEFAULT '',
+			enqueued_at  timestamptz NOT NULL
+		);
+
+		CREATE INDEX strava_webhook_events_next_attempt_idx ON strava_webhook_events (next_attempt, id);
+
+		CREATE TABLE strava_webhook_dead_letters (
+			id          bigint      PRIMARY KEY,
+			event       jsonb       NOT NULL,
+			attempts    integer     NOT NULL,
+			last_error  text        NOT NULL DEFAULT '',
+			enqueued_at timestamptz NOT NULL,
+			failed_at   timestamptz NOT NULL
+		);`,
+	},
+}
+
+// seenStoreMigrations migrate the schema of the webhook event keys, see SeenStore.
+var seenStoreMigrations = migrationSet{
+	name: "webhook_seen_events",
+	migrations: []string{
+		// 1: keys of received webhook events, for deduplication.
+		`CREATE TABLE strava_webhook_seen_events (
+			key        text        PRIMARY KEY,
+			expires_at timestamptz NOT NULL
+		);
+
+		CREATE INDEX strava_webhook_seen_events_expires_at_idx ON strava_webhook_seen_events (expires_at);`,
+	},
+}
+
+// webhookStateMigrations migrate the schema of the webhook subscriptions, see WebhookStateStore.
+var webhookStateMigrations = migrationSet{
+	name: "webhook_states",
+	migrations: []string{
+		// 1: push subscription of each client.
+		`CREATE TABLE strava_webhook_states (
+			client_id       text        PRIMARY KEY,
+			subscription_id bigint      NOT NULL DEFAULT 0,
+			callback_url    text        NOT NULL DEFAULT '',
+			updated_at      timestamptz NOT NULL DEFAULT now()
+		);`,
+
+		// 2: random verify token of the subscription.
+		`ALTER TABLE strava_webhook_states ADD COLUMN verify_token text NOT NULL DEFAULT '';`,
+	},
+}
+
+// activityCursorMigrations migrate the schema of the activity cursors, see ActivityCursorStore.
+var activityCursorMigrations = migrationSet{
+	name: "activity_cursors",
+	migrations: []string{
+		// 1: last synced activity of each athlete.
+		`CREATE TABLE strava_activity_cursors (
+			athlete_id  bigint      PRIMARY KEY,
+			start_date  timestamptz NOT NULL,
+			activity_id bigint      NOT NULL,
+			updated_at  timestamptz NOT NULL DEFAULT now()
+		);`,
+	},
+}
+
+// backfillMigrations migrate the schema of the backfills, see BackfillStore.
+var backfillMigrations = migrationSet{
+	name: "backfills",
+	migrations: []string{
+		// 1: history backfill of each athlete.
+		`CREATE TABLE strava_backfills (
+			athlete_id   bigint      PRIMARY KEY,
+			history_from timestamptz NOT NULL,
+			history_to   timestamptz NOT NULL,
+			window_size  bigint      NOT NULL,
+			before       timestamptz NOT NULL,
+			activities   integer     NOT NULL DEFAULT 0,
+			requests     integer     NOT NULL DEFAULT 0,
+			last_error   text        NOT NULL DEFAULT '',
+			abandoned    boolean     NOT NULL DEFAULT false,
+			updated_at   timestamptz NOT NULL DEFAULT now()
+		);`,
+	},
+}
+
+// mirrorMigrations migrate the schema of the activity mirror, see Mirror.
+var mirrorMigrations = migrationSet{
+	name: "mirror",
+	migrations: []string{
+		// 1: activity mirror, see Mirror.
+		`CREATE TABLE strava_athletes (
+			id                     bigint      PRIMARY KEY,
+			firstname              text        NOT NULL DEFAULT '',
+			lastname               text        NOT NULL DEFAULT '',
+			city                   text        NOT NULL DEFAULT '',
+			state                  text        NOT NULL DEFAULT '',
+			country                text        NOT NULL DEFAULT '',
+			sex                    text        NOT NULL DEFAULT '',
+			profile                text        NOT NULL DEFAULT '',
+			profile_medium         text        NOT NULL DEFAULT '',
+			premium                boolean     NOT NULL DEFAULT false,
+			summit                 boolean     NOT NULL DEFAULT false,
+			measurement_preference text        NOT NULL DEFAULT '',
+			ftp                    integer     NOT NULL DEFAULT 0,
+			weight                 double precision NOT NULL DEFAULT 0,
+			follower_count         integer     NOT NULL DEFAULT 0,
+			friend_count           integer     NOT NULL DEFAULT 0,
+			strava_created_at      timestamptz,
+			strava_updated_at      timestamptz,
+			created_at             timestamptz NOT NULL DEFAULT now(),
+			updated_at             timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE strava_gear (
+			id          text        PRIMARY KEY,
+			athlete_id  bigint      NOT NULL REFERENCES strava_athletes (id),
+			"primary"   boolean     NOT NULL DEFAULT false,
+			name        text        NOT NULL DEFAULT '',
+			distance    double precision NOT NULL DEFAULT 0,
+			brand_name  text        NOT NULL DEFAULT '',
+			model_name  text        NOT NULL DEFAULT '',
+			frame_type  integer     NOT NULL DEFAULT 0,
+			description text        NOT NULL DEFAULT '',
+			created_at  timestamptz NOT NULL DEFAULT now(),
+			updated_at  timestamptz NOT NULL DEFAULT now()
+		);
+
+		CREATE TABLE strava_activities (
+			id                     bigint      PRIMARY KEY,
+			athlete_id             bigint      NOT NULL REFERENCES strava_athletes (id),
+			external_id            text        NOT NULL DEFAULT '',
+			upload_id              bigint      NOT NULL DEFAULT 0,
+			name                   text        NOT NULL DEFAULT '',
+			distance               double precision NOT NULL DEFAULT 0,
+			moving_time            integer     NOT NULL DEFAULT 0,
+			elapsed_time           integer     NOT NULL DEFAULT 0,
+			total_elevation_gain   double precision NOT NULL DEFAULT 0,
+			elev_high              double precision NOT NULL DEFAULT 0,
+			elev_low               double precision NOT NULL DEFAULT 0,
+			type                   text        NOT NULL DEFAULT '',
+			sport_type             text        NOT NULL DEFAULT '',
+			start_date             timestamptz NOT NULL,
+			start_date_local       timestamptz NOT NULL,
+			timezone               text        NOT NULL DEFAULT '',
+			start_lat              double precision,
+			start_lng              double precision,
+			end_lat                double precision,
+			end_lng                double precision,
+			achievement_count      integer     NOT NULL DEFAULT 0,
+			kudos_count            integer     NOT NULL DEFAULT 0,
+			comment_count          integer     NOT NULL DEFAULT 0,
+			athlete_count          integer     NOT NULL DEFAULT 0,
+			photo_count            integer     NOT NULL DEFAULT 0,
+			total_photo_count      integer     NOT NULL DEFAULT 0,
+			map_id                 text        NOT NULL DEFAULT '',
+			map_polyline           text        NOT NULL DEFAULT '',
+			map_summary_polyline   text        NOT NULL DEFAULT '',
+			trainer                boolean     NOT NULL DEFAULT false,
+			commute                boolean     NOT NULL DEFAULT false,
+			manual                 boolean     NOT NULL DEFAULT false,
+			private                boolean     NOT NULL DEFAULT false,
+			flagged                boolean     NOT NULL DEFAULT false,
+			workout_type           integer     NOT NULL DEFAULT 0,
+			gear_id                text        NOT NULL DEFAULT '',
+			average_speed          double precision NOT NULL DEFAULT 0,
+			max_speed              double precision NOT NULL DEFAULT 0,
+			average_cadence        double precision NOT NULL DEFAULT 0,
+			average_temp           double precision NOT NULL DEFAULT 0,
+			average_watts          double precision NOT NULL DEFAULT 0,
+			weighted_average_watts integer     NOT NULL DEFAULT 0,
+			kilojoules             double precision NOT NULL DEFAULT 0,
+			device_watts           boolean     NOT NULL DEFAULT false,
+			has_heartrate          boolean     NOT NULL DEFAULT false,
+			average_heartrate      double precision NOT NULL DEFAULT 0,
+			max_heartrate          double precision NOT NULL DEFAULT 0,
+			max_watts              integer     NOT NULL DEFAULT 0,
+			suffer_score           integer     NOT NULL DEFAULT 0,
+			description            text        NOT NULL DEFAULT '',
+			calories               double precision NOT NULL DEFAULT 0,
+			device_name            text        NOT NULL DEFAULT '',
+			embed_token            text        NOT NULL DEFAULT '',
+			detailed_at            timestamptz,
+			raw                    jsonb,
+			created_at             timestamptz NOT NULL DEFAULT now(),
+			updated_at             timestamptz NOT NULL DEFAULT now(),
+			deleted_at             timestamptz
+		);
+
+		CREATE INDEX strava_activities_athlete_id_start_date_idx ON strava_activities (athlete_id, start_date);
+		CREATE INDEX strava_activities_deleted_at_idx ON strava_activities (deleted_at);
+
+		CREATE TABLE strava_laps (
+			id                   bigint      PRIMARY KEY,
+			activity_id          bigint      NOT NULL REFERENCES strava_activities (id) ON DELETE CASCADE,
+			athlete_id           bigint      NOT NULL,
+			name                 text        NOT NULL DEFAULT '',
+			elapsed_time         integer     NOT NULL DEFAULT 0,
+			moving_time          integer     NOT NULL DEFAULT 0,
+			start_date           timestamptz NOT NULL,
+			start_date_local     timestamptz NOT NULL,
+			distance             double precision NOT NULL DEFAULT 0,
+			start_index          integer     NOT NULL DEFAULT 0,
+			end_index            integer     NOT NULL DEFAULT 0,
+			total_elevation_gain double precision NOT NULL DEFAULT 0,
+			average_speed        double precision NOT NULL DEFAULT 0,
+			max_speed            double precision NOT NULL DEFAULT 0,
+			average_cadence      double precision NOT NULL DEFAULT 0,
+			device_watts         boolean     NOT NULL DEFAULT false,
+			average_watts        double precision NOT NULL DEFAULT 0,
+			lap_index            integer     NOT NULL DEFAULT 0,
+			split                integer     NOT NULL DEFAULT 0,
+			average_heartrate    double precision NOT NULL DEFAULT 0
+		);
+
+		CREATE INDEX strava_laps_activity_id_idx ON strava_laps (activity_id);
+
+		CREATE TABLE strava_splits (
+			activity_id                  bigint      NOT NULL REFERENCES strava_activities (id) ON DELETE CASCADE,
+			units                        text        NOT NULL,
+			split                        integer     NOT NULL,
+			distance                     double precision NOT NULL DEFAULT 0,
+			elapsed_time                 integer     NOT NULL DEFAULT 0,
+			elevation_difference         double precision NOT NULL DEFAULT 0,
+			moving_time                  integer     NOT NULL DEFAULT 0,
+			average_speed                double precision NOT NULL DEFAULT 0,
+			average_grade_adjusted_speed double precision NOT NULL DEFAULT 0,
+			average_heartrate            double precision NOT NULL DEFAULT 0,
+			pace_zone                    integer     NOT NULL DEFAULT 0,
+			PRIMARY KEY (activity_id, units, split)
+		);
+
+		CREATE TABLE strava_segment_efforts (
+			id                bigint      PRIMARY KEY,
+			activity_id       bigint      NOT NULL REFERENCES strava_activities (id) ON DELETE CASCADE,
+			athlete_id        bigint      NOT NULL,
+			segment_id        bigint      NOT NULL DEFAULT 0,
+			segment_name      text        NOT NULL DEFAULT '',
+			name              text        NOT NULL DEFAULT '',
+			elapsed_time      integer     NOT NULL DEFAULT 0,
+			moving_time       integer     NOT NULL DEFAULT 0,
+			start_date        timestamptz NOT NULL,
+			start_date_local  timestamptz NOT NULL,
+			distance          double precision NOT NULL DEFAULT 0,
+			start_index       integer     NOT NULL DEFAULT 0,
+			end_index         integer     NOT NULL DEFAULT 0,
+			average_cadence   double precision NOT NULL DEFAULT 0,
+			average_watts     double precision NOT NULL DEFAULT 0,
+			device_watts      boolean     NOT NULL DEFAULT false,
+			average_heartrate double precision NOT NULL DEFAULT 0,
+			max_heartrate     double precision NOT NULL DEFAULT 0,
+			kom_rank          integer     NOT NULL DEFAULT 0,
+			pr_rank           integer     NOT NULL DEFAULT 0,
+			hidden            boolean     NOT NULL DEFAULT false
+		);
+
+		CREATE INDEX strava_segment_efforts_activity_id_idx ON strava_segment_efforts (activity_id);
+		CREATE INDEX strava_segment_efforts_segment_id_idx ON strava_segment_efforts (segment_id);
+
+		CREATE TABLE strava_best_efforts (LIKE strava_segment_efforts INCLUDING DEFAULTS INCLUDING CONSTRAINTS);
+		ALTER TABLE strava_best_efforts ADD PRIMARY KEY (id);
+		ALTER TABLE strava_best_efforts ADD FOREIGN KEY (activity_id) REFERENCES strava_activities (id) ON DELETE CASCADE;
+		CREATE INDEX strava_best_efforts_activity_id_idx ON strava_best_efforts (activity_id);
+
+		CREATE TABLE strava_photos (
+			activity_id bigint      NOT NULL REFERENCES strava_activities (id) ON DELETE CASCADE,
+			unique_id   text        NOT NULL,
+			id          bigint      NOT NULL DEFAULT 0,
+			source      integer     NOT NULL DEFAULT 0,
+			urls        jsonb,
+			"primary"   boolean     NOT NULL DEFAULT false,
+			PRIMARY KEY (activity_id, unique_id)
+		);`,
+	},
+}
+
+// migrator runs statements in a transaction.
+type migrator interface {
+	exec(ctx context.Context, sql string) error
+	queryInt(ctx context.Context, sql string) (int, error)
+}
+
+func migrate(ctx context.Context, m migrator, set migrationSet) error {
+	// Statements have no parameters, as GORM and pgx use different placeholders. Set names are constants.
+	if err := m.exec(ctx, fmt.Sprintf(`SELECT pg_advisory_xact_lock(%d)`, migrationsLockID)); err != nil {
+		return fmt.Errorf("lock migrations: %w", err)
+	}
+
+	err := m.exec(ctx, `CREATE TABLE IF NOT EXISTS strava_schema_migrations (
+		name       text        NOT NULL,
+		version    integer     NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now(),
+		PRIMARY KEY (name, version)
+	)`)
+	if err != nil {
+		return fmt.Errorf("create migrations table: %w", err)
+	}
+
+	version, err := m.queryInt(ctx, fmt.Sprintf(`SELECT coalesce(max(version), 0) FROM strava_schema_migrations WHERE name = '%s'`, set.name))
+	if err != nil {
+		return fmt.Errorf("get %s schema version: %w", set.name, err)
+	}
+
+	if version > len(set.migrations) {
+		return fmt.Errorf("%s schema version %d is newer than supported %d", set.name, version, len(set.migrations))
+	}
+
+	for i := version; i < len(set.migrations); i++ {
+		if err := m.exec(ctx, set.migrations[i]); err != nil {
+			return fmt.Errorf("apply %s migration %d: %w", set.name, i+1, err)
+		}
+
+		if err := m.exec(ctx, fmt.Sprintf(`INSERT INTO strava_schema_migrations (name, version) VALUES ('%s', %d)`, set.name, i+1)); err != nil {
+			return fmt.Errorf("record %s migration %d: %w", set.name, i+1, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// recordingMigrator records the statements, the database having the migrations of versions.
+type recordingMigrator struct {
+	versions   map[string]int
+	statements []string
+}
+
+func (m *recordingMigrator) exec(_ context.Context, sql string) error {
+	m.statements = append(m.statements, sql)
+	return nil
+}
+
+func (m *recordingMigrator) queryInt(_ context.Context, sql string) (int, error) {
+	for name, version := range m.versions {
+		if strings.Contains(sql, fmt.Sprintf("name = '%s'", name)) {
+			return version, nil
+		}
+	}
+	return 0, nil
+}
+
+func TestMigrate(t *testing.T) {
+	// arrange
+	m := &recordingMigrator{versions: map[string]int{"webhook_states": 1}}
+
+	// act
+	errTokens := migrate(context.Background(), m, tokenMigrations)
+	errStates := migrate(context.Background(), m, webhookStateMigrations)
+
+	// assert
+	assert.NoErr(t, errTokens)
+	assert.NoErr(t, errStates)
+
+	all := strings.Join(m.statements, "\n")
+	assert.StrContains(t, all, "CREATE TABLE strava_tokens")
+	assert.StrContains(t, all, "ALTER TABLE strava_webhook_states ADD COLUMN verify_token")
+	assert.StrContains(t, all, "VALUES ('tokens', 2)")
+	assert.StrContains(t, all, "VALUES ('webhook_states', 2)")
+	assert.NotContains(t, all, "CREATE TABLE strava_webhook_states")
+	for _, table := range []string{"strava_webhook_events", "strava_webhook_seen_events", "strava_activity_cursors", "strava_backfills", "strava_activities"} {
+		assert.NotContains(t, all, "CREATE TABLE "+table)
+	}
+}
+
+func TestMigrate_NewerVersion(t *testing.T) {
+	// arrange
+	m := &recordingMigrator{versions: map[string]int{"backfills": 2}}
+
+	// act
+	err := migrate(context.Background(), m, backfillMigrations)
+
+	// assert
+	assert.ErrSubMsg(t, err, "backfills schema version 2 is newer than supported 1")
+}
+
+func TestTokenStorage_LegacyTable(t *testing.T) {
+	// arrange: a schema of its own, holding a token table of an earlier version
+	dsn := testDSN(t)
+	db := newTestDB(t)
+
+	schema := fmt.Sprintf("strava_test_%d", time.Now().UnixNano())
+	assert.NoErr(t, db.Exec("CREATE SCHEMA "+schema).Error)
+	t.Cleanup(func() { db.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	sep := " "
+	if strings.Contains(dsn, "://") {
+		sep = "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+	}
+	schemaDB, err := gorm.Open(postgres.Open(dsn + sep + "search_path=" + schema))
+	assert.NoErr(t, err)
+
+	err = schemaDB.Exec(`CREATE TABLE strava_tokens (
+		id            bigserial PRIMARY KEY,
+		created_at    timestamptz,
+		updated_at    timestamptz,
+		deleted_at    timestamptz,
+		access_token  text,
+		token_type    text,
+		refresh_token text,
+		expiry        timestamptz,
+		scope         text,
+		revoked       boolean,
+		raw           text
+	);
+	INSERT INTO strava_tokens (id, created_at, updated_at, access_token, refresh_token, scope, revoked, raw) VALUES
+		(1, now(), now(), 'a1', 'r1', 'read', true, '{"athlete":{"id":1}}'),
+		(2, now(), now(), 'a2', 'r2', 'read', NULL, 'null'),
+		(3, now(), now(), 'a3', 'r3', 'read', false, NULL);`).Error
+	assert.NoErr(t, err)
+
+	// act
+	ts, err := NewTokenStorage(schemaDB)
+
+	// assert
+	assert.NoErr(t, err)
+
+	token, err := ts.Get(context.Background(), 1)
+	assert.NoErr(t, err)
+	assert.Eq(t, "a1", token.AccessToken)
+	assert.True(t, token.Revoked)
+	assert.Eq(t, map[string]any{"athlete": map[string]any{"id": float64(1)}}, token.Raw)
+
+	for _, athleteID := range []uint{2, 3} {
+		token, err := ts.Get(context.Background(), athleteID)
+		assert.NoErr(t, err)
+		assert.False(t, token.Revoked)
+		assert.Nil(t, token.Raw)
+	}
+
+	var tables []string
+	err = schemaDB.Raw(`SELECT table_name FROM information_schema.tables WHERE table_schema = ? ORDER BY table_name`, schema).Scan(&tables).Error
+	assert.NoErr(t, err)
+	assert.Eq(t, []string{"strava_schema_migrations", "strava_tokens", "strava_tokens_gorm"}, tables)
+}
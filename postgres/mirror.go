@@ -0,0 +1,655 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/marvell/strava-go"
+	stravasync "github.com/marvell/strava-go/sync"
+)
+
+// Athlete is a row of the strava_athletes table.
+type Athlete struct {
+	ID                    uint   `gorm:"primaryKey;autoIncrement:false"`
+	FirstName             string `gorm:"column:firstname"`
+	LastName              string `gorm:"column:lastname"`
+	City                  string
+	State                 string
+	Country               string
+	Sex                   string
+	Profile               string
+	ProfileMedium         string
+	Premium               bool
+	Summit                bool
+	MeasurementPreference string
+	FTP                   int `gorm:"column:ftp"`
+	Weight                float64
+	FollowerCount         int
+	FriendCount           int
+	StravaCreatedAt       *time.Time
+	StravaUpdatedAt       *time.Time
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+}
+
+func (a Athlete) TableName() string {
+	return "strava_athletes"
+}
+
+// Gear is a row of the strava_gear table.
+type Gear struct {
+	ID          string `gorm:"primaryKey"`
+	AthleteID   uint
+	Primary     bool
+	Name        string
+	Distance    float64
+	BrandName   string
+	ModelName   string
+	FrameType   int
+	Description string
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (g Gear) TableName() string {
+	return "strava_gear"
+}
+
+// Activity is a row of the strava_activities table. Deleted activities are soft-deleted.
+type Activity struct {
+	ID                   uint `gorm:"primaryKey;autoIncrement:false"`
+	AthleteID            uint
+	ExternalID           string
+	UploadID             uint
+	Name                 string
+	Distance             float64
+	MovingTime           int
+	ElapsedTime          int
+	TotalElevationGain   float64
+	ElevHigh             float64
+	ElevLow              float64
+	Type                 string
+	SportType            string
+	StartDate            time.Time
+	StartDateLocal       time.Time
+	Timezone             string
+	StartLat             *float64
+	StartLng             *float64
+	EndLat               *float64
+	EndLng               *float64
+	AchievementCount     int
+	KudosCount           int
+	CommentCount         int
+	AthleteCount         int
+	PhotoCount           int
+	TotalPhotoCount      int
+	MapID                string `gorm:"column:map_id"`
+	MapPolyline          string
+	MapSummaryPolyline   string
+	Trainer              bool
+	Commute              bool
+	Manual               bool
+	Private              bool
+	Flagged              bool
+	WorkoutType          int
+	GearID               string `gorm:"column:gear_id"`
+	AverageSpeed         float64
+	MaxSpeed             float64
+	AverageCadence       float64
+	AverageTemp          float64
+	AverageWatts         float64
+	WeightedAverageWatts int
+	Kilojoules           float64
+	DeviceWatts          bool
+	HasHeartrate         bool
+	AverageHeartrate     float64
+	MaxHeartrate         float64
+	MaxWatts             int
+	SufferScore          int
+
+	// The columns of the detailed activity, set once it was fetched.
+	Description string
+	Calories    float64
+	DeviceName  string
+	EmbedToken  string
+	DetailedAt  *time.Time
+
+	// Raw is the activity's JSON as last returned by the API, the detailed one once it was fetched.
+	Raw []byte `gorm:"type:jsonb"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+func (a Activity) TableName() string {
+	return "strava_activities"
+}
+
+// Lap is a row of the strava_laps table.
+type Lap struct {
+	ID                 uint `gorm:"primaryKey;autoIncrement:false"`
+	ActivityID         uint
+	AthleteID          uint
+	Name               string
+	ElapsedTime        int
+	MovingTime         int
+	StartDate          time.Time
+	StartDateLocal     time.Time
+	Distance           float64
+	StartIndex         int
+	EndIndex           int
+	TotalElevationGain float64
+	AverageSpeed       float64
+	MaxSpeed           float64
+	AverageCadence     float64
+	DeviceWatts        bool
+	AverageWatts       float64
+	LapIndex           int
+	Split              int
+	AverageHeartrate   float64
+}
+
+func (l Lap) TableName() string {
+	return "strava_laps"
+}
+
+// Split is a row of the strava_splits table. Units is "metric" or "standard".
+type Split struct {
+	ActivityID                uint   `gorm:"primaryKey;autoIncrement:false"`
+	Units                     string `gorm:"primaryKey"`
+	Split                     int    `gorm:"primaryKey;autoIncrement:false"`
+	Distance                  float64
+	ElapsedTime               int
+	ElevationDifference       float64
+	MovingTime                int
+	AverageSpeed              float64
+	AverageGradeAdjustedSpeed float64
+	AverageHeartrate          float64
+	PaceZone                  int
+}
+
+func (s Split) TableName() string {
+	return "strava_splits"
+}
+
+// SegmentEffort is a row of the strava_segment_efforts table.
+type SegmentEffort struct {
+	ID               uint `gorm:"primaryKey;autoIncrement:false"`
+	ActivityID       uint
+	AthleteID        uint
+	SegmentID        uint `gorm:"column:segment_id"`
+	SegmentName      string
+	Name             string
+	ElapsedTime      int
+	MovingTime       int
+	StartDate        time.Time
+	StartDateLocal   time.Time
+	Distance         float64
+	StartIndex       int
+	EndIndex         int
+	AverageCadence   float64
+	AverageWatts     float64
+	DeviceWatts      bool
+	AverageHeartrate float64
+	MaxHeartrate     float64
+	KOMRank          int `gorm:"column:kom_rank"`
+	PRRank           int `gorm:"column:pr_rank"`
+	Hidden           bool
+}
+
+func (e SegmentEffort) TableName() string {
+	return "strava_segment_efforts"
+}
+
+// BestEffort is a row of the strava_best_efforts table.
+type BestEffort SegmentEffort
+
+func (e BestEffort) TableName() string {
+	return "strava_best_efforts"
+}
+
+// Photo is a row of the strava_photos table.
+type Photo struct {
+	ActivityID uint   `gorm:"primaryKey;autoIncrement:false"`
+	UniqueID   string `gorm:"primaryKey;column:unique_id"`
+	ID         uint
+	Source     int
+	URLs       []byte `gorm:"column:urls;type:jsonb"`
+	Primary    bool
+}
+
+func (p Photo) TableName() string {
+	return "strava_photos"
+}
+
+// summaryColumns are updated by every upsert of an activity, detailedColumns only by
+// upserts of detailed activities.
+var (
+	summaryColumns = []string{
+		"athlete_id", "external_id", "upload_id", "name", "distance", "moving_time", "elapsed_time",
+		"total_elevation_gain", "elev_high", "elev_low", "type", "sport_type", "start_date", "start_date_local",
+		"timezone", "start_lat", "start_lng", "end_lat", "end_lng", "achievement_count", "kudos_count",
+		"comment_count", "athlete_count", "photo_count", "total_photo_count", "map_id", "map_summary_polyline", "trainer", "commute", "manual", "private", "flagged", "workout_type", "gear_id",
+		"average_speed", "max_speed", "average_cadence", "average_temp", "average_watts", "weighted_average_watts",
+		"kilojoules", "device_watts", "has_heartrate", "average_heartrate", "max_heartrate", "max_watts",
+		"suffer_score", "updated_at", "deleted_at",
+	}
+	detailedColumns = []string{"description", "calories", "device_name", "embed_token", "map_polyline", "raw", "detailed_at"}
+)
+
+// summaryRaw updates the raw JSON by upserts of summaries as long as the activity has no details,
+// which a summary's JSON would otherwise replace.
+var summaryRaw = clause.Assignment{
+	Column: clause.Column{Name: "raw"},
+	Value:  gorm.Expr("CASE WHEN strava_activities.detailed_at IS NULL THEN excluded.raw ELSE strava_activities.raw END"),
+}
+
+// NewMirror creates a GORM based activity mirror and migrates the database schema.
+func NewMirror(db *gorm.DB) (*Mirror, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return migrate(context.Background(), gormMigrator{tx}, mirrorMigrations)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not migrate: %w", err)
+	}
+
+	return &Mirror{db: db}, nil
+}
+
+// Mirror keeps synced activities in a normalized schema: athletes, gear, activities and their laps,
+// splits, segment efforts, best efforts and photos, upserted by their Strava IDs. Fields the models
+// don't cover are kept in the raw JSON of the activity.
+type Mirror struct {
+	db *gorm.DB
+}
+
+var _ stravasync.Sink = (*Mirror)(nil)
+
+// Upsert inserts or replaces the activity. The laps and the rows of the detailed activity are replaced
+// only if the activity has them, so upserting a summary keeps them. A deleted activity is restored.
+func (m *Mirror) Upsert(ctx context.Context, activity *stravasync.Activity) error {
+	row, err := activityRow(activity)
+	if err != nil {
+		return err
+	}
+
+	updates := clause.AssignmentColumns(summaryColumns)
+	if activity.Detailed != nil {
+		updates = append(updates, clause.AssignmentColumns(detailedColumns)...)
+	} else {
+		updates = append(updates, summaryRaw)
+	}
+
+	err = m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Athlete{ID: activity.AthleteID}).Error; err != nil {
+			return fmt.Errorf("insert athlete: %w", err)
+		}
+
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: updates,
+		}).Create(row).Error
+		if err != nil {
+			return fmt.Errorf("upsert activity: %w", err)
+		}
+
+		if activity.Laps != nil {
+			if err := replace(tx, row.ID, lapRows(activity)); err != nil {
+				return fmt.Errorf("replace laps: %w", err)
+			}
+		}
+
+		if activity.Detailed == nil {
+			return nil
+		}
+
+		return m.upsertDetails(tx, activity)
+	})
+	if err != nil {
+		return fmt.Errorf("could not upsert activity %d: %w", activity.ID(), err)
+	}
+
+	return nil
+}
+
+func (m *Mirror) upsertDetails(tx *gorm.DB, activity *stravasync.Activity) error {
+	d := activity.Detailed
+
+	if d.Gear != nil && d.Gear.ID != "" {
+		gear := &Gear{ID: d.Gear.ID, AthleteID: activity.AthleteID, Primary: d.Gear.Primary, Name: d.Gear.Name, Distance: d.Gear.Distance}
+		err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"primary", "name", "distance", "updated_at"}),
+		}).Create(gear).Error
+		if err != nil {
+			return fmt.Errorf("upsert gear: %w", err)
+		}
+	}
+
+	if err := replace(tx, d.ID, splitRows(d)); err != nil {
+		return fmt.Errorf("replace splits: %w", err)
+	}
+
+	if err := replace(tx, d.ID, effortRows[SegmentEffort](d.ID, d.SegmentEfforts)); err != nil {
+		return fmt.Errorf("replace segment efforts: %w", err)
+	}
+
+	if err := replace(tx, d.ID, effortRows[BestEffort](d.ID, d.BestEfforts)); err != nil {
+		return fmt.Errorf("replace best efforts: %w", err)
+	}
+
+	photos, err := photoRows(d)
+	if err != nil {
+		return err
+	}
+	if err := replace(tx, d.ID, photos); err != nil {
+		return fmt.Errorf("replace photos: %w", err)
+	}
+
+	return nil
+}
+
+// replace replaces the rows of the activity in the table of T.
+func replace[T any](tx *gorm.DB, activityID uint, rows []T) error {
+	var zero T
+	if err := tx.Where("activity_id = ?", activityID).Delete(&zero).Error; err != nil {
+		return err
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return tx.Create(&rows).Error
+}
+
+// Delete soft-deletes the activity, its rows are kept.
+func (m *Mirror) Delete(ctx context.Context, _, activityID uint) error {
+	if err := m.db.WithContext(ctx).Delete(&Activity{ID: activityID}).Error; err != nil {
+		return fmt.Errorf("could not delete activity %d: %w", activityID, err)
+	}
+
+	return nil
+}
+
+// UpsertAthlete inserts or replaces the athlete and their bikes and shoes.
+func (m *Mirror) UpsertAthlete(ctx context.Context, athlete *strava.DetailedAthlete) error {
+	row := &Athlete{
+		ID:                    athlete.ID,
+		FirstName:             athlete.FirstName,
+		LastName:              athlete.LastName,
+		City:                  athlete.City,
+		State:                 athlete.State,
+		Country:               athlete.Country,
+		Sex:                   athlete.Sex,
+		Profile:               athlete.Profile,
+		ProfileMedium:         athlete.ProfileMedium,
+		Premium:               athlete.Premium,
+		Summit:                athlete.Summit,
+		MeasurementPreference: athlete.MeasurementPreference,
+		FTP:                   athlete.FTP,
+		Weight:                athlete.Weight,
+		FollowerCount:         athlete.FollowerCount,
+		FriendCount:           athlete.FriendCount,
+		StravaCreatedAt:       parseTime(athlete.CreatedAt),
+		StravaUpdatedAt:       parseTime(athlete.UpdatedAt),
+	}
+
+	var gear []*Gear
+	for _, g := range append(append([]strava.Gear{}, athlete.Bikes...), athlete.Shoes...) {
+		gear = append(gear, &Gear{
+			ID:          g.ID,
+			AthleteID:   athlete.ID,
+			Primary:     g.Primary,
+			Name:        g.Name,
+			Distance:    g.Distance,
+			BrandName:   g.BrandName,
+			ModelName:   g.ModelName,
+			FrameType:   g.FrameType,
+			Description: g.Description,
+		})
+	}
+
+	err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"firstname", "lastname", "city", "state", "country", "sex", "profile", "profile_medium", "premium",
+				"summit", "measurement_preference", "ftp", "weight", "follower_count", "friend_count",
+				"strava_created_at", "strava_updated_at", "updated_at",
+			}),
+		}).Create(row).Error
+		if err != nil {
+			return fmt.Errorf("upsert athlete: %w", err)
+		}
+
+		if len(gear) == 0 {
+			return nil
+		}
+
+		err = tx.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"athlete_id", "primary", "name", "distance", "brand_name", "model_name", "frame_type", "description", "updated_at",
+			}),
+		}).Create(&gear).Error
+		if err != nil {
+			return fmt.Errorf("upsert gear: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("could not upsert athlete %d: %w", athlete.ID, err)
+	}
+
+	return nil
+}
+
+func activityRow(activity *stravasync.Activity) (*Activity, error) {
+	s := activity.Summary
+	if activity.Detailed != nil {
+		s = &activity.Detailed.SummaryActivity
+	}
+
+	row := &Activity{
+		ID:                   s.ID,
+		AthleteID:            activity.AthleteID,
+		ExternalID:           s.ExternalID,
+		UploadID:             s.UploadID,
+		Name:                 s.Name,
+		Distance:             s.Distance,
+		MovingTime:           s.MovingTime,
+		ElapsedTime:          s.ElapsedTime,
+		TotalElevationGain:   s.TotalElevationGain,
+		ElevHigh:             s.ElevHigh,
+		ElevLow:              s.ElevLow,
+		Type:                 string(s.Type),
+		SportType:            string(s.SportType),
+		StartDate:            s.StartDate,
+		StartDateLocal:       s.StartDateLocal,
+		Timezone:             s.Timezone,
+		AchievementCount:     s.AchievementCount,
+		KudosCount:           s.KudosCount,
+		CommentCount:         s.CommentCount,
+		AthleteCount:         s.AthleteCount,
+		PhotoCount:           s.PhotoCount,
+		TotalPhotoCount:      s.TotalPhotoCount,
+		Trainer:              s.Trainer,
+		Commute:              s.Commute,
+		Manual:               s.Manual,
+		Private:              s.Private,
+		Flagged:              s.Flagged,
+		WorkoutType:          s.WorkoutType,
+		GearID:               s.GearID,
+		AverageSpeed:         s.AverageSpeed,
+		MaxSpeed:             s.MaxSpeed,
+		AverageCadence:       s.AverageCadence,
+		AverageTemp:          s.AverageTemp,
+		AverageWatts:         s.AverageWatts,
+		WeightedAverageWatts: s.WeightedAverageWatts,
+		Kilojoules:           s.Kilojoules,
+		DeviceWatts:          s.DeviceWatts,
+		HasHeartrate:         s.HasHeartrate,
+		AverageHeartrate:     s.AverageHeartrate,
+		MaxHeartrate:         s.MaxHeartrate,
+		MaxWatts:             s.MaxWatts,
+		SufferScore:          s.SufferScore,
+		Raw:                  s.Raw,
+	}
+
+	row.StartLat, row.StartLng = latLng(s.StartLatLng)
+	row.EndLat, row.EndLng = latLng(s.EndLatLng)
+
+	if s.Map != nil {
+		row.MapID = s.Map.ID
+		row.MapPolyline = s.Map.Polyline
+		row.MapSummaryPolyline = s.Map.SummaryPolyline
+	}
+
+	// Keep the column NULL rather than storing the JSON null of activities built without the API.
+	if len(row.Raw) == 0 {
+		row.Raw = nil
+	} else if !json.Valid(row.Raw) {
+		return nil, fmt.Errorf("invalid raw JSON of activity %d", s.ID)
+	}
+
+	if d := activity.Detailed; d != nil {
+		now := time.Now()
+		row.Description = d.Description
+		row.Calories = d.Calories
+		row.DeviceName = d.DeviceName
+		row.EmbedToken = d.EmbedToken
+		row.DetailedAt = &now
+	}
+
+	return row, nil
+}
+
+func latLng(v []float64) (*float64, *float64) {
+	if len(v) != 2 {
+		return nil, nil
+	}
+
+	return &v[0], &v[1]
+}
+
+func parseTime(v string) *time.Time {
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil
+	}
+
+	return &t
+}
+
+func lapRows(activity *stravasync.Activity) []*Lap {
+	rows := make([]*Lap, 0, len(activity.Laps))
+	for _, l := range activity.Laps {
+		rows = append(rows, &Lap{
+			ID:                 l.ID,
+			ActivityID:         activity.ID(),
+			AthleteID:          activity.AthleteID,
+			Name:               l.Name,
+			ElapsedTime:        l.ElapsedTime,
+			MovingTime:         l.MovingTime,
+			StartDate:          l.StartDate,
+			StartDateLocal:     l.StartDateLocal,
+			Distance:           l.Distance,
+			StartIndex:         l.StartIndex,
+			EndIndex:           l.EndIndex,
+			TotalElevationGain: l.TotalElevationGain,
+			AverageSpeed:       l.AverageSpeed,
+			MaxSpeed:           l.MaxSpeed,
+			AverageCadence:     l.AverageCadence,
+			DeviceWatts:        l.DeviceWatts,
+			AverageWatts:       l.AverageWatts,
+			LapIndex:           l.LapIndex,
+			Split:              l.Split,
+			AverageHeartrate:   l.AverageHeartrate,
+		})
+	}
+
+	return rows
+}
+
+func splitRows(d *strava.DetailedActivity) []*Split {
+	var rows []*Split
+	for units, splits := range map[string][]*strava.Split{"metric": d.SplitsMetric, "standard": d.SplitsStandard} {
+		for _, s := range splits {
+			rows = append(rows, &Split{
+				ActivityID:                d.ID,
+				Units:                     units,
+				Split:                     s.Split,
+				Distance:                  s.Distance,
+				ElapsedTime:               s.ElapsedTime,
+				ElevationDifference:       s.ElevationDifference,
+				MovingTime:                s.MovingTime,
+				AverageSpeed:              s.AverageSpeed,
+				AverageGradeAdjustedSpeed: s.AverageGradeAdjustedSpeed,
+				AverageHeartrate:          s.AverageHeartrate,
+				PaceZone:                  s.PaceZone,
+			})
+		}
+	}
+
+	return rows
+}
+
+func effortRows[T SegmentEffort | BestEffort](activityID uint, efforts []*strava.DetailedSegmentEffort) []*T {
+	rows := make([]*T, 0, len(efforts))
+	for _, e := range efforts {
+		row := SegmentEffort{
+			ID:               e.ID,
+			ActivityID:       activityID,
+			Name:             e.Name,
+			ElapsedTime:      e.ElapsedTime,
+			MovingTime:       e.MovingTime,
+			StartDate:        e.StartDate,
+			StartDateLocal:   e.StartDateLocal,
+			Distance:         e.Distance,
+			StartIndex:       e.StartIndex,
+			EndIndex:         e.EndIndex,
+			AverageCadence:   e.AverageCadence,
+			AverageWatts:     e.AverageWatts,
+			DeviceWatts:      e.DeviceWatts,
+			AverageHeartrate: e.AverageHeartrate,
+			MaxHeartrate:     e.MaxHeartrate,
+			KOMRank:          e.KOMRank,
+			PRRank:           e.PRRank,
+			Hidden:           e.Hidden,
+		}
+		if e.Athlete != nil {
+			row.AthleteID = e.Athlete.ID
+		}
+		if e.Segment != nil {
+			row.SegmentID = e.Segment.ID
+			row.SegmentName = e.Segment.Name
+		}
+
+		t := T(row)
+		rows = append(rows, &t)
+	}
+
+	return rows
+}
+
+func photoRows(d *strava.DetailedActivity) ([]*Photo, error) {
+	if d.Photos == nil || d.Photos.Primary == nil {
+		return nil, nil
+	}
+
+	p := d.Photos.Primary
+	urls, err := json.Marshal(p.URLs)
+	if err != nil {
+		return nil, fmt.Errorf("marshal photo URLs: %w", err)
+	}
+
+	return []*Photo{{ActivityID: d.ID, UniqueID: p.UniqueID, ID: p.ID, Source: p.Source, URLs: urls, Primary: true}}, nil
+}
@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+
+	"github.com/marvell/strava-go"
+	stravasync "github.com/marvell/strava-go/sync"
+)
+
+func testMirrorActivity() *stravasync.Activity {
+	start := time.Date(2024, 6, 1, 7, 0, 0, 0, time.UTC)
+	detailed := &strava.DetailedActivity{
+		SummaryActivity: strava.SummaryActivity{
+			ID:          42,
+			Name:        "Morning Run",
+			Distance:    10000,
+			StartDate:   start,
+			StartLatLng: []float64{52.5, 13.4},
+			Map:         &strava.Map{ID: "a42", Polyline: "abcdef", SummaryPolyline: "abc"},
+			Raw:         []byte(`{"id":42,"name":"Morning Run","description":"easy"}`),
+		},
+		Description: "easy",
+		Gear:        &strava.SummaryGear{ID: "g1", Name: "Shoes", Distance: 500000},
+		SplitsMetric: []*strava.Split{
+			{Split: 1, Distance: 1000},
+			{Split: 2, Distance: 1000},
+		},
+		SplitsStandard: []*strava.Split{{Split: 1, Distance: 1609}},
+		BestEfforts: []*strava.DetailedSegmentEffort{
+			{ID: 100, Name: "1k", StartDate: start, StartDateLocal: start, PRRank: 1},
+		},
+	}
+
+	return &stravasync.Activity{
+		AthleteID: 7,
+		Summary:   &detailed.SummaryActivity,
+		Detailed:  detailed,
+		Laps:      []*strava.Lap{{ID: 1, Name: "Lap 1", StartDate: start, StartDateLocal: start}},
+	}
+}
+
+func TestActivityRow(t *testing.T) {
+	// arrange
+	activity := testMirrorActivity()
+
+	// act
+	row, err := activityRow(activity)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, uint(42), row.ID)
+	assert.Eq(t, uint(7), row.AthleteID)
+	assert.Eq(t, 52.5, *row.StartLat)
+	assert.Eq(t, 13.4, *row.StartLng)
+	assert.Nil(t, row.EndLat)
+	assert.Eq(t, "a42", row.MapID)
+	assert.Eq(t, "easy", row.Description)
+	assert.NotNil(t, row.DetailedAt)
+	assert.Eq(t, 3, len(splitRows(activity.Detailed)))
+
+	// act
+	activity.Detailed, activity.Summary.Raw = nil, nil
+	row, err = activityRow(activity)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Nil(t, row.Raw)
+	assert.Nil(t, row.DetailedAt)
+}
+
+func TestMirror(t *testing.T) {
+	// arrange
+	db := newTestDB(t)
+
+	m, err := NewMirror(db)
+	assert.NoErr(t, err)
+
+	err = db.Exec("TRUNCATE strava_athletes, strava_gear, strava_activities, strava_laps, strava_splits, strava_segment_efforts, strava_best_efforts, strava_photos").Error
+	assert.NoErr(t, err)
+
+	ctx := context.Background()
+	activity := testMirrorActivity()
+
+	// act
+	err = m.Upsert(ctx, activity)
+
+	// assert
+	assert.NoErr(t, err)
+
+	var row Activity
+	assert.NoErr(t, db.First(&row, 42).Error)
+	assert.Eq(t, "Morning Run", row.Name)
+	assert.Eq(t, "easy", row.Description)
+
+	var count int64
+	assert.NoErr(t, db.Model(&Split{}).Where("activity_id = ?", 42).Count(&count).Error)
+	assert.Eq(t, int64(3), count)
+	assert.NoErr(t, db.Model(&BestEffort{}).Where("activity_id = ?", 42).Count(&count).Error)
+	assert.Eq(t, int64(1), count)
+	assert.NoErr(t, db.Model(&Lap{}).Where("activity_id = ?", 42).Count(&count).Error)
+	assert.Eq(t, int64(1), count)
+
+	// act: a summary keeps the details
+	summary := *activity.Summary
+	summary.Name = "Renamed"
+	summary.Map = &strava.Map{ID: "a42", SummaryPolyline: "abc"}
+	summary.Raw = []byte(`{"id":42,"name":"Renamed"}`)
+	err = m.Upsert(ctx, &stravasync.Activity{AthleteID: 7, Summary: &summary})
+
+	// assert
+	assert.NoErr(t, err)
+	row = Activity{}
+	assert.NoErr(t, db.First(&row, 42).Error)
+	assert.Eq(t, "Renamed", row.Name)
+	assert.Eq(t, "easy", row.Description)
+	assert.Eq(t, "abcdef", row.MapPolyline)
+	assert.StrContains(t, string(row.Raw), `"description"`)
+	assert.NoErr(t, db.Model(&Lap{}).Where("activity_id = ?", 42).Count(&count).Error)
+	assert.Eq(t, int64(1), count)
+
+	// act
+	err = m.Delete(ctx, 7, 42)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Err(t, db.First(&Activity{}, 42).Error)
+	assert.NoErr(t, db.Unscoped().First(&Activity{}, 42).Error)
+
+	// act: upserting restores it
+	err = m.Upsert(ctx, activity)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.NoErr(t, db.First(&Activity{}, 42).Error)
+
+	// act
+	err = m.UpsertAthlete(ctx, &strava.DetailedAthlete{Athlete: strava.Athlete{
+		ID:        7,
+		FirstName: "Jane",
+		Bikes:     []strava.Gear{{ID: "b1", Name: "Bike", Primary: true}},
+	}})
+
+	// assert
+	assert.NoErr(t, err)
+	var athlete Athlete
+	assert.NoErr(t, db.First(&athlete, 7).Error)
+	assert.Eq(t, "Jane", athlete.FirstName)
+	assert.NoErr(t, db.Model(&Gear{}).Where("athlete_id = ?", 7).Count(&count).Error)
+	assert.Eq(t, int64(2), count)
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/marvell/strava-go"
+)
+
+// PgxTokenStorage is the same storage as TokenStorage for applications using pgx instead of GORM.
+type PgxTokenStorage struct {
+	pool      *pgxpool.Pool
+	namespace string
+}
+
+var (
+	_ strava.TokenStorage    = (*PgxTokenStorage)(nil)
+	_ strava.TokenLister     = (*PgxTokenStorage)(nil)
+	_ strava.TokenPager      = (*PgxTokenStorage)(nil)
+	_ strava.TokenDeleter    = (*PgxTokenStorage)(nil)
+	_ strava.TokenNamespacer = (*PgxTokenStorage)(nil)
+)
+
+// NewPgxTokenStorage creates a pgx based storage and migrates the schema of the token tables.
+func NewPgxTokenStorage(ctx context.Context, pool *pgxpool.Pool) (*PgxTokenStorage, error) {
+	err := pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		return migrate(ctx, pgxMigrator{tx}, tokenMigrations)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not migrate: %w", err)
+	}
+
+	return &PgxTokenStorage{pool: pool}, nil
+}
+
+func (ts *PgxTokenStorage) ForClient(clientID string) strava.TokenStorage {
+	return &PgxTokenStorage{pool: ts.pool, namespace: clientID}
+}
+
+const selectTokens = `SELECT client_id, athlete_id, access_token, token_type, refresh_token, expiry, scope, revoked, extra, created_at, updated_at FROM strava_tokens`
+
+func (ts *PgxTokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token, error) {
+	rows, err := ts.pool.Query(ctx, selectTokens+` WHERE client_id = $1 AND athlete_id = $2`, ts.namespace, athleteID)
+	if err != nil {
+		return nil, fmt.Errorf("could not get token: %w", err)
+	}
+
+	t, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByPos[Token])
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, strava.ErrTokenNotFound
+		}
+
+		return nil, fmt.Errorf("could not get token: %w", err)
+	}
+
+	return t.stravaToken()
+}
+
+func (ts *PgxTokenStorage) Save(ctx context.Context, token *strava.Token) error {
+	t, err := newToken(ts.namespace, token)
+	if err != nil {
+		return err
+	}
+
+	updates := make([]string, len(upsertColumns))
+	for i, col := range upsertColumns {
+		updates[i] = col + " = excluded." + col
+	}
+
+	_, err = ts.pool.Exec(ctx, `
+		INSERT INTO strava_tokens (client_id, athlete_id, access_token, token_type, refresh_token, expiry, scope, revoked, extra, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, now(), now())
+		ON CONFLICT (client_id, athlete_id) DO UPDATE SET `+strings.Join(updates, ", "),
+		t.ClientID, t.AthleteID, t.AccessToken, t.TokenType, t.RefreshToken, t.Expiry, t.Scope, t.Revoked, t.Extra,
+	)
+	if err != nil {
+		return fmt.Errorf("could not save token: %w", err)
+	}
+
+	return nil
+}
+
+func (ts *PgxTokenStorage) Delete(ctx context.Context, athleteID uint) error {
+	if _, err := ts.pool.Exec(ctx, `DELETE FROM strava_tokens WHERE client_id = $1 AND athlete_id = $2`, ts.namespace, athleteID); err != nil {
+		return fmt.Errorf("could not delete token: %w", err)
+	}
+
+	return nil
+}
+
+func (ts *PgxTokenStorage) List(ctx context.Context) ([]*strava.Token, error) {
+	return ts.list(ctx, selectTokens+` WHERE client_id = $1 ORDER BY athlete_id`, ts.namespace)
+}
+
+func (ts *PgxTokenStorage) ListAfter(ctx context.Context, afterID uint, limit int) ([]*strava.Token, error) {
+	return ts.list(ctx, selectTokens+` WHERE client_id = $1 AND athlete_id > $2 ORDER BY athlete_id LIMIT $3`, ts.namespace, afterID, limit)
+}
+
+func (ts *PgxTokenStorage) list(ctx context.Context, query string, args ...any) ([]*strava.Token, error) {
+	rows, err := ts.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("could not list tokens: %w", err)
+	}
+
+	rs, err := pgx.CollectRows(rows, pgx.RowToStructByPos[Token])
+	if err != nil {
+		return nil, fmt.Errorf("could not list tokens: %w", err)
+	}
+
+	tokens := make([]*strava.Token, 0, len(rs))
+	for _, t := range rs {
+		token, err := t.stravaToken()
+		if err != nil {
+			return nil, fmt.Errorf("athlete %d: %w", t.AthleteID, err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+type pgxMigrator struct {
+	tx pgx.Tx
+}
+
+func (m pgxMigrator) exec(ctx context.Context, sql string) error {
+	_, err := m.tx.Exec(ctx, sql)
+	return err
+}
+
+func (m pgxMigrator) queryInt(ctx context.Context, sql string) (int, error) {
+	var n int
+	err := m.tx.QueryRow(ctx, sql).Scan(&n)
+	return n, err
+}
@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/marvell/strava-go"
+)
+
+// NewSeenStore creates a GORM based store of webhook event keys and migrates the database schema.
+// Expired keys are replaced when seen again, call DeleteExpired periodically to remove the others.
+func NewSeenStore(db *gorm.DB) (*SeenStore, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return migrate(context.Background(), gormMigrator{tx}, seenStoreMigrations)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not migrate: %w", err)
+	}
+
+	return &SeenStore{db: db}, nil
+}
+
+type SeenStore struct {
+	db *gorm.DB
+}
+
+var _ strava.SeenStore = (*SeenStore)(nil)
+
+func (s *SeenStore) MarkSeen(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+
+	// The row is written if the key is new or expired, otherwise the event is a duplicate.
+	res := s.db.WithContext(ctx).Exec(`INSERT INTO strava_webhook_seen_events (key, expires_at) VALUES (?, ?)
+		ON CONFLICT (key) DO UPDATE SET expires_at = excluded.expires_at
+		WHERE strava_webhook_seen_events.expires_at <= ?`, key, now.Add(ttl), now)
+	if res.Error != nil {
+		return false, fmt.Errorf("could not mark event seen: %w", res.Error)
+	}
+
+	return res.RowsAffected == 0, nil
+}
+
+func (s *SeenStore) Forget(ctx context.Context, key string) error {
+	if err := s.db.WithContext(ctx).Exec(`DELETE FROM strava_webhook_seen_events WHERE key = ?`, key).Error; err != nil {
+		return fmt.Errorf("could not forget event: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteExpired removes the expired keys and returns their number.
+func (s *SeenStore) DeleteExpired(ctx context.Context) (int64, error) {
+	res := s.db.WithContext(ctx).Exec(`DELETE FROM strava_webhook_seen_events WHERE expires_at <= ?`, time.Now())
+	if res.Error != nil {
+		return 0, fmt.Errorf("could not delete expired events: %w", res.Error)
+	}
+
+	return res.RowsAffected, nil
+}
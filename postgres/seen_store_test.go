@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestSeenStore(t *testing.T) {
+	storagetest.RunSeenStoreSuite(t, func(t *testing.T) strava.SeenStore {
+		db := newTestDB(t)
+
+		s, err := NewSeenStore(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.Exec("TRUNCATE strava_webhook_seen_events").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	})
+}
@@ -1,16 +1,86 @@
 package postgres
 
 import (
-	"gorm.io/gorm"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"golang.org/x/oauth2"
 
 	"github.com/marvell/strava-go"
 )
 
+// Token is a row of the strava_tokens table.
 type Token struct {
-	*gorm.Model
-	*strava.Token
+	// ClientID is the token's namespace, empty for tokens saved without one.
+	ClientID     string `gorm:"primaryKey"`
+	AthleteID    uint   `gorm:"primaryKey;autoIncrement:false"`
+	AccessToken  string
+	TokenType    string
+	RefreshToken string
+	Expiry       *time.Time
+	Scope        string
+	Revoked      bool
+	// Extra is the raw JSON of the additional token endpoint fields.
+	Extra     []byte `gorm:"type:jsonb"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func (t Token) TableName() string {
 	return "strava_tokens"
 }
+
+func newToken(namespace string, token *strava.Token) (*Token, error) {
+	t := &Token{
+		ClientID:  namespace,
+		AthleteID: token.AthleteID,
+		Scope:     token.Scope,
+		Revoked:   token.Revoked,
+	}
+
+	if token.Token != nil {
+		t.AccessToken = token.AccessToken
+		t.TokenType = token.TokenType
+		t.RefreshToken = token.RefreshToken
+		if !token.Expiry.IsZero() {
+			expiry := token.Expiry
+			t.Expiry = &expiry
+		}
+	}
+
+	if token.Raw != nil {
+		extra, err := json.Marshal(token.Raw)
+		if err != nil {
+			return nil, fmt.Errorf("marshal extra: %w", err)
+		}
+		t.Extra = extra
+	}
+
+	return t, nil
+}
+
+func (t *Token) stravaToken() (*strava.Token, error) {
+	token := &strava.Token{
+		Token: &oauth2.Token{
+			AccessToken:  t.AccessToken,
+			TokenType:    t.TokenType,
+			RefreshToken: t.RefreshToken,
+		},
+		AthleteID: t.AthleteID,
+		Scope:     t.Scope,
+		Revoked:   t.Revoked,
+	}
+
+	if t.Expiry != nil {
+		token.Expiry = *t.Expiry
+	}
+
+	if len(t.Extra) > 0 {
+		if err := json.Unmarshal(t.Extra, &token.Raw); err != nil {
+			return nil, fmt.Errorf("unmarshal extra: %w", err)
+		}
+	}
+
+	return token, nil
+}
@@ -5,13 +5,19 @@ import (
 	"errors"
 	"fmt"
 
-	"github.com/marvell/strava-go"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/marvell/strava-go"
 )
 
+// NewTokenStorage creates a GORM based storage and migrates the schema of the token tables.
 func NewTokenStorage(db *gorm.DB) (*TokenStorage, error) {
-	if err := db.AutoMigrate(&Token{}); err != nil {
-		return nil, fmt.Errorf("could not auto migrate token: %w", err)
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return migrate(context.Background(), gormMigrator{tx}, tokenMigrations)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not migrate: %w", err)
 	}
 
 	ts := &TokenStorage{
@@ -22,14 +28,25 @@ func NewTokenStorage(db *gorm.DB) (*TokenStorage, error) {
 }
 
 type TokenStorage struct {
-	db *gorm.DB
+	db        *gorm.DB
+	namespace string
 }
 
-var _ strava.TokenStorage = (*TokenStorage)(nil)
+var (
+	_ strava.TokenStorage    = (*TokenStorage)(nil)
+	_ strava.TokenLister     = (*TokenStorage)(nil)
+	_ strava.TokenPager      = (*TokenStorage)(nil)
+	_ strava.TokenDeleter    = (*TokenStorage)(nil)
+	_ strava.TokenNamespacer = (*TokenStorage)(nil)
+)
+
+func (ts *TokenStorage) ForClient(clientID string) strava.TokenStorage {
+	return &TokenStorage{db: ts.db, namespace: clientID}
+}
 
 func (ts *TokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token, error) {
 	var t Token
-	if err := ts.db.WithContext(ctx).First(&t, athleteID).Error; err != nil {
+	if err := ts.db.WithContext(ctx).Where("client_id = ? AND athlete_id = ?", ts.namespace, athleteID).Take(&t).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, strava.ErrTokenNotFound
 		}
@@ -37,27 +54,73 @@ func (ts *TokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token,
 		return nil, fmt.Errorf("could not get token: %w", err)
 	}
 
-	return t.Token, nil
+	return t.stravaToken()
 }
 
 func (ts *TokenStorage) Save(ctx context.Context, token *strava.Token) error {
-	var t Token
-	if err := ts.db.WithContext(ctx).First(&t, token.AthleteID).Error; err != nil {
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("could not get token: %w", err)
-		}
-
-		t = Token{
-			Model: &gorm.Model{
-				ID: token.AthleteID,
-			},
-		}
+	t, err := newToken(ts.namespace, token)
+	if err != nil {
+		return err
 	}
-	t.Token = token
 
-	if err := ts.db.Save(t).Error; err != nil {
+	err = ts.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_id"}, {Name: "athlete_id"}},
+		DoUpdates: clause.AssignmentColumns(upsertColumns),
+	}).Create(t).Error
+	if err != nil {
 		return fmt.Errorf("could not save token: %w", err)
 	}
 
 	return nil
 }
+
+func (ts *TokenStorage) Delete(ctx context.Context, athleteID uint) error {
+	if err := ts.db.WithContext(ctx).Where("client_id = ? AND athlete_id = ?", ts.namespace, athleteID).Delete(&Token{}).Error; err != nil {
+		return fmt.Errorf("could not delete token: %w", err)
+	}
+
+	return nil
+}
+
+func (ts *TokenStorage) List(ctx context.Context) ([]*strava.Token, error) {
+	return ts.list(ctx, ts.db.WithContext(ctx).Where("client_id = ?", ts.namespace))
+}
+
+func (ts *TokenStorage) ListAfter(ctx context.Context, afterID uint, limit int) ([]*strava.Token, error) {
+	return ts.list(ctx, ts.db.WithContext(ctx).Where("client_id = ? AND athlete_id > ?", ts.namespace, afterID).Limit(limit))
+}
+
+func (ts *TokenStorage) list(_ context.Context, q *gorm.DB) ([]*strava.Token, error) {
+	var rows []Token
+	if err := q.Order("athlete_id").Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("could not list tokens: %w", err)
+	}
+
+	tokens := make([]*strava.Token, 0, len(rows))
+	for _, t := range rows {
+		token, err := t.stravaToken()
+		if err != nil {
+			return nil, fmt.Errorf("athlete %d: %w", t.AthleteID, err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// upsertColumns are updated when a token is saved for an athlete having one already.
+var upsertColumns = []string{"access_token", "token_type", "refresh_token", "expiry", "scope", "revoked", "extra", "updated_at"}
+
+type gormMigrator struct {
+	db *gorm.DB
+}
+
+func (m gormMigrator) exec(ctx context.Context, sql string) error {
+	return m.db.WithContext(ctx).Exec(sql).Error
+}
+
+func (m gormMigrator) queryInt(ctx context.Context, sql string) (int, error) {
+	var n int
+	err := m.db.WithContext(ctx).Raw(sql).Scan(&n).Error
+	return n, err
+}
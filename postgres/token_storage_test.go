@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+// testDSN returns the database from STRAVA_TEST_POSTGRES_DSN or skips the test.
+func testDSN(t *testing.T) string {
+	dsn := os.Getenv("STRAVA_TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("STRAVA_TEST_POSTGRES_DSN is not set")
+	}
+
+	return dsn
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(postgres.Open(testDSN(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func TestTokenStorage(t *testing.T) {
+	storagetest.RunTokenStorageSuite(t, func(t *testing.T) strava.TokenStorage {
+		db := newTestDB(t)
+
+		ts, err := NewTokenStorage(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.Exec("TRUNCATE strava_tokens").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		return ts
+	})
+}
+
+func TestPgxTokenStorage(t *testing.T) {
+	storagetest.RunTokenStorageSuite(t, func(t *testing.T) strava.TokenStorage {
+		ctx := context.Background()
+
+		pool, err := pgxpool.New(ctx, testDSN(t))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(pool.Close)
+
+		ts, err := NewPgxTokenStorage(ctx, pool)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := pool.Exec(ctx, "TRUNCATE strava_tokens"); err != nil {
+			t.Fatal(err)
+		}
+
+		return ts
+	})
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/marvell/strava-go"
+)
+
+// WebhookState is a row of the strava_webhook_states table.
+type WebhookState struct {
+	ClientID       string `gorm:"primaryKey"`
+	SubscriptionID uint
+	CallbackURL    string
+	VerifyToken    string
+	UpdatedAt      time.Time
+}
+
+func (s WebhookState) TableName() string {
+	return "strava_webhook_states"
+}
+
+// NewWebhookStateStore creates a GORM based webhook state store and migrates the database schema.
+func NewWebhookStateStore(db *gorm.DB) (*WebhookStateStore, error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return migrate(context.Background(), gormMigrator{tx}, webhookStateMigrations)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("could not migrate: %w", err)
+	}
+
+	return &WebhookStateStore{db: db}, nil
+}
+
+type WebhookStateStore struct {
+	db *gorm.DB
+}
+
+var _ strava.WebhookStateStore = (*WebhookStateStore)(nil)
+
+func (s *WebhookStateStore) GetWebhookState(ctx context.Context, clientID string) (*strava.WebhookState, error) {
+	var row WebhookState
+	if err := s.db.WithContext(ctx).Where("client_id = ?", clientID).Take(&row).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, strava.ErrWebhookStateNotFound
+		}
+
+		return nil, fmt.Errorf("could not get webhook state: %w", err)
+	}
+
+	return &strava.WebhookState{
+		SubscriptionID: row.SubscriptionID,
+		CallbackURL:    row.CallbackURL,
+		VerifyToken:    row.VerifyToken,
+	}, nil
+}
+
+func (s *WebhookStateStore) SaveWebhookState(ctx context.Context, clientID string, state *strava.WebhookState) error {
+	row := &WebhookState{
+		ClientID:       clientID,
+		SubscriptionID: state.SubscriptionID,
+		CallbackURL:    state.CallbackURL,
+		VerifyToken:    state.VerifyToken,
+	}
+
+	err := s.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"subscription_id", "callback_url", "verify_token", "updated_at"}),
+	}).Create(row).Error
+	if err != nil {
+		return fmt.Errorf("could not save webhook state: %w", err)
+	}
+
+	return nil
+}
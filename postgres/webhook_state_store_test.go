@@ -0,0 +1,25 @@
+package postgres
+
+import (
+	"testing"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestWebhookStateStore(t *testing.T) {
+	storagetest.RunWebhookStateStoreSuite(t, func(t *testing.T) strava.WebhookStateStore {
+		db := newTestDB(t)
+
+		s, err := NewWebhookStateStore(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if err := db.Exec("TRUNCATE strava_webhook_states").Error; err != nil {
+			t.Fatal(err)
+		}
+
+		return s
+	})
+}
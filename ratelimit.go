@@ -0,0 +1,98 @@
+package strava
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateLimitWindow is the usage of one of Strava's rate limits.
+type RateLimitWindow struct {
+	Limit int
+	Usage int
+}
+
+// Remaining returns the requests left in the window, 0 if it's exhausted.
+func (w RateLimitWindow) Remaining() int {
+	return max(w.Limit-w.Usage, 0)
+}
+
+// RateLimit is the API usage of the application reported in the last response. Strava limits requests
+// per 15 minutes and per day; read requests have separate limits, zero if the response didn't report them.
+type RateLimit struct {
+	Short     RateLimitWindow
+	Daily     RateLimitWindow
+	ReadShort RateLimitWindow
+	ReadDaily RateLimitWindow
+
+	UpdatedAt time.Time
+}
+
+// ShortReset returns when the 15-minute window of the usage ends. Windows start at quarter hours.
+func (r RateLimit) ShortReset() time.Time {
+	return r.UpdatedAt.Truncate(15 * time.Minute).Add(15 * time.Minute)
+}
+
+// DailyReset returns when the daily window of the usage ends, at midnight UTC.
+func (r RateLimit) DailyReset() time.Time {
+	y, m, d := r.UpdatedAt.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// RateLimit returns the API usage reported in the last response, false if no response reported it yet.
+func (c *Client) RateLimit() (RateLimit, bool) {
+	rl := c.rateLimit.Load()
+	if rl == nil {
+		return RateLimit{}, false
+	}
+
+	return *rl, true
+}
+
+// updateRateLimit records the usage reported in the X-RateLimit-* and X-ReadRateLimit-* headers.
+func (c *Client) updateRateLimit(h http.Header) {
+	rl := RateLimit{UpdatedAt: time.Now()}
+
+	var ok bool
+	if rl.Short, rl.Daily, ok = parseRateLimit(h, "X-RateLimit"); !ok {
+		return
+	}
+	rl.ReadShort, rl.ReadDaily, _ = parseRateLimit(h, "X-ReadRateLimit")
+
+	c.rateLimit.Store(&rl)
+}
+
+// parseRateLimit parses the "15-minute,daily" limit and usage headers with the prefix.
+func parseRateLimit(h http.Header, prefix string) (short, daily RateLimitWindow, ok bool) {
+	limits, ok := parseRateLimitPair(h.Get(prefix + "-Limit"))
+	if !ok {
+		return short, daily, false
+	}
+
+	usages, ok := parseRateLimitPair(h.Get(prefix + "-Usage"))
+	if !ok {
+		return short, daily, false
+	}
+
+	return RateLimitWindow{Limit: limits[0], Usage: usages[0]}, RateLimitWindow{Limit: limits[1], Usage: usages[1]}, true
+}
+
+func parseRateLimitPair(v string) ([2]int, bool) {
+	var pair [2]int
+
+	parts := strings.Split(v, ",")
+	if len(parts) != 2 {
+		return pair, false
+	}
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return pair, false
+		}
+		pair[i] = n
+	}
+
+	return pair, true
+}
@@ -0,0 +1,61 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestClient_RateLimit(t *testing.T) {
+	// arrange
+	status := http.StatusOK
+	c := newTestEnricherClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "200,2000")
+		w.Header().Set("X-RateLimit-Usage", "200,1234")
+		w.Header().Set("X-ReadRateLimit-Limit", "100, 1000")
+		w.Header().Set("X-ReadRateLimit-Usage", "12, 345")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{"id":1}`))
+	}))
+
+	_, ok := c.RateLimit()
+	assert.False(t, ok)
+
+	// act
+	_, err := c.GetDetailedActivity(context.Background(), 7, 1)
+	assert.NoErr(t, err)
+	rl, ok := c.RateLimit()
+
+	// assert
+	assert.True(t, ok)
+	assert.Eq(t, RateLimitWindow{Limit: 200, Usage: 200}, rl.Short)
+	assert.Eq(t, RateLimitWindow{Limit: 2000, Usage: 1234}, rl.Daily)
+	assert.Eq(t, RateLimitWindow{Limit: 100, Usage: 12}, rl.ReadShort)
+	assert.Eq(t, RateLimitWindow{Limit: 1000, Usage: 345}, rl.ReadDaily)
+	assert.Eq(t, 0, rl.Short.Remaining())
+	assert.Eq(t, 766, rl.Daily.Remaining())
+
+	// act
+	status = http.StatusTooManyRequests
+	_, err = c.GetDetailedActivity(context.Background(), 7, 1)
+
+	// assert
+	assert.ErrIs(t, err, ErrRateLimited)
+	assert.False(t, errors.Is(err, ErrNotFound))
+}
+
+func TestRateLimit_Reset(t *testing.T) {
+	// arrange
+	rl := RateLimit{UpdatedAt: time.Date(2024, 5, 31, 23, 52, 10, 0, time.UTC)}
+
+	// act & assert
+	assert.Eq(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), rl.ShortReset())
+	assert.Eq(t, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), rl.DailyReset())
+
+	rl.UpdatedAt = time.Date(2024, 5, 31, 10, 15, 0, 0, time.UTC)
+	assert.Eq(t, time.Date(2024, 5, 31, 10, 30, 0, 0, time.UTC), rl.ShortReset())
+}
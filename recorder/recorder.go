@@ -0,0 +1,292 @@
+// Package recorder records the client's HTTP interactions to cassette files and replays them, for
+// deterministic tests against real Strava responses without network access.
+//
+// Record a cassette once against the API, then replay it in CI:
+//
+//	rec, err := recorder.New("testdata/athlete.json", recorder.ModeAuto)
+//	cl := strava.NewClient(id, secret, redirectURL, ts, strava.WithTransport(rec))
+//	athlete, err := cl.GetAthlete(ctx, athleteID)
+//	err = rec.Stop()
+//
+// Authorization headers, client secrets and tokens are redacted before interactions are recorded.
+package recorder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// ErrNoInteraction is returned when replaying a request that matches no recorded interaction.
+var ErrNoInteraction = errors.New("no recorded interaction matches the request")
+
+type Mode int
+
+const (
+	// ModeReplay replays the cassette and fails requests without a matching interaction.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests to the API and records them, replacing the cassette on Stop.
+	ModeRecord
+	// ModeAuto replays the cassette if it exists and records it otherwise.
+	ModeAuto
+)
+
+// Match selects the parts of requests compared to recorded ones.
+type Match int
+
+const (
+	MatchMethod Match = 1 << iota
+	MatchPath
+	// MatchQuery compares the query parameters regardless of their order. Scrubbed parameters
+	// are compared redacted, so replays don't need the recorded secrets.
+	MatchQuery
+
+	DefaultMatch = MatchMethod | MatchPath | MatchQuery
+)
+
+type Option func(*Recorder)
+
+// WithMatch sets the parts of requests compared to recorded ones, DefaultMatch by default.
+func WithMatch(m Match) Option {
+	return func(r *Recorder) {
+		r.match = m
+	}
+}
+
+// WithRoundTripper sets the round tripper of recorded requests, http.DefaultTransport by default.
+func WithRoundTripper(rt http.RoundTripper) Option {
+	return func(r *Recorder) {
+		r.next = rt
+	}
+}
+
+// WithScrubbedHeaders redacts the headers in addition to DefaultScrubbedHeaders.
+func WithScrubbedHeaders(headers ...string) Option {
+	return func(r *Recorder) {
+		r.headers = append(r.headers, headers...)
+	}
+}
+
+// WithScrubbedParams redacts the query and form parameters and the JSON keys in addition to
+// DefaultScrubbedParams and DefaultScrubbedFields.
+func WithScrubbedParams(params ...string) Option {
+	return func(r *Recorder) {
+		r.params = append(r.params, params...)
+		r.fields = append(r.fields, params...)
+	}
+}
+
+// Recorder is an http.RoundTripper recording or replaying the interactions of a cassette.
+// Pass it to strava.WithTransport.
+type Recorder struct {
+	path  string
+	mode  Mode
+	match Match
+	next  http.RoundTripper
+
+	headers []string
+	params  []string
+	fields  []string
+	scrub   *scrubber
+
+	mu       sync.Mutex
+	cassette *Cassette
+	// replayed marks the interactions already replayed.
+	replayed []bool
+}
+
+var _ http.RoundTripper = (*Recorder)(nil)
+
+// New returns a recorder of the cassette at path. The cassette must exist in ModeReplay.
+func New(path string, mode Mode, opts ...Option) (*Recorder, error) {
+	r := &Recorder{
+		path:    path,
+		mode:    mode,
+		match:   DefaultMatch,
+		next:    http.DefaultTransport,
+		headers: append([]string{}, DefaultScrubbedHeaders...),
+		params:  append([]string{}, DefaultScrubbedParams...),
+		fields:  append([]string{}, DefaultScrubbedFields...),
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.scrub = newScrubber(r.headers, r.params, r.fields)
+
+	if r.mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+
+	if r.mode == ModeRecord {
+		r.cassette = &Cassette{}
+		return r, nil
+	}
+
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		return nil, err
+	}
+	r.cassette = cassette
+	r.replayed = make([]bool, len(cassette.Interactions))
+
+	return r, nil
+}
+
+// Recording reports whether requests are sent to the API and recorded.
+func (r *Recorder) Recording() bool {
+	return r.mode == ModeRecord
+}
+
+// Stop saves the recorded cassette. It's a no-op when replaying.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cassette.Save(r.path)
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	if r.mode == ModeRecord {
+		return r.record(req)
+	}
+
+	return r.replay(req)
+}
+
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	reqBody, err := readBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	interaction := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    r.scrub.url(req.URL.String()),
+			Header: r.scrub.header(req.Header),
+			Body:   r.scrub.body(req.Header, reqBody),
+		},
+		Response: Response{
+			StatusCode: resp.StatusCode,
+			Header:     r.scrub.header(resp.Header),
+			Body:       r.scrub.body(resp.Header, respBody),
+		},
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	return resp, nil
+}
+
+// replay responds with the first matching interaction not replayed yet, or with the last matching one
+// if all were replayed, so repeated requests like token refreshes can be replayed more often than recorded.
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_ = req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	found := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !r.matches(req, &interaction.Request) {
+			continue
+		}
+
+		found = i
+		if !r.replayed[i] {
+			break
+		}
+	}
+
+	if found < 0 {
+		return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, r.scrub.url(req.URL.String()))
+	}
+	r.replayed[found] = true
+
+	recorded := r.cassette.Interactions[found].Response
+	header := recorded.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+func (r *Recorder) matches(req *http.Request, recorded *Request) bool {
+	u, err := url.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+
+	if r.match&MatchMethod != 0 && req.Method != recorded.Method {
+		return false
+	}
+
+	if r.match&MatchPath != 0 && (req.URL.Host != u.Host || req.URL.Path != u.Path) {
+		return false
+	}
+
+	if r.match&MatchQuery != 0 && !reflect.DeepEqual(r.scrub.query(req.URL.Query()), u.Query()) {
+		return false
+	}
+
+	return true
+}
+
+// readBody reads the request body and replaces it, so it can still be sent.
+func readBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+
+	return body, nil
+}
@@ -0,0 +1,156 @@
+package recorder
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/inmemory"
+)
+
+func get(t *testing.T, c *http.Client, rawURL string) (int, string, error) {
+	t.Helper()
+
+	resp, err := c.Get(rawURL)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	assert.NoErr(t, err)
+
+	return resp.StatusCode, string(body), nil
+}
+
+func TestRecorder(t *testing.T) {
+	// arrange
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/oauth/token":
+			_, _ = w.Write([]byte(`{"token_type":"Bearer","access_token":"secret-access","refresh_token":"secret-refresh","expires_at":1717243200,"athlete":{"id":12345678901}}`))
+		default:
+			_, _ = w.Write([]byte(`{"id":1,"name":"` + r.URL.Query().Get("name") + `"}`))
+		}
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "cassettes", "test.json")
+	rec, err := New(path, ModeAuto)
+	assert.NoErr(t, err)
+	assert.True(t, rec.Recording())
+
+	c := &http.Client{Transport: rec}
+
+	// act
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v3/activities/1?name=Ride&client_secret=secret-client", nil)
+	req.Header.Set("Authorization", "Bearer secret-access")
+	resp, err := c.Do(req)
+	assert.NoErr(t, err)
+	_ = resp.Body.Close()
+
+	form := url.Values{"grant_type": {"refresh_token"}, "refresh_token": {"secret-refresh"}, "client_secret": {"secret-client"}}
+	resp, err = c.PostForm(srv.URL+"/oauth/token", form)
+	assert.NoErr(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+
+	assert.NoErr(t, rec.Stop())
+
+	// assert: the real response is passed through
+	assert.StrContains(t, string(body), "secret-access")
+
+	data, err := os.ReadFile(path)
+	assert.NoErr(t, err)
+	assert.NotContains(t, string(data), "secret-")
+	assert.StrContains(t, string(data), "12345678901")
+
+	cassette, err := LoadCassette(path)
+	assert.NoErr(t, err)
+	assert.Len(t, cassette.Interactions, 2)
+	assert.Eq(t, []string{Redacted}, cassette.Interactions[0].Request.Header["Authorization"])
+	assert.StrContains(t, cassette.Interactions[1].Request.Body, "refresh_token="+Redacted)
+
+	// act: replay without the server
+	srv.Close()
+	rec, err = New(path, ModeAuto)
+	assert.NoErr(t, err)
+	assert.False(t, rec.Recording())
+	c = &http.Client{Transport: rec}
+
+	status, body2, err := get(t, c, srv.URL+"/api/v3/activities/1?client_secret=other&name=Ride")
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, http.StatusOK, status)
+	assert.Eq(t, `{"id":1,"name":"Ride"}`, body2)
+
+	// act
+	_, _, err = get(t, c, srv.URL+"/api/v3/activities/1?name=Run")
+
+	// assert
+	assert.ErrIs(t, err, ErrNoInteraction)
+
+	// act: ignore the query
+	rec, err = New(path, ModeReplay, WithMatch(MatchMethod|MatchPath))
+	assert.NoErr(t, err)
+	c = &http.Client{Transport: rec}
+	_, body2, err = get(t, c, srv.URL+"/api/v3/activities/1?name=Run")
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, `{"id":1,"name":"Ride"}`, body2)
+}
+
+func TestRecorder_ReplayMissingCassette(t *testing.T) {
+	// act
+	_, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay)
+
+	// assert
+	assert.ErrIs(t, err, os.ErrNotExist)
+}
+
+func TestRecorder_Client(t *testing.T) {
+	// arrange
+	rec, err := New("testdata/athlete.json", ModeReplay)
+	assert.NoErr(t, err)
+
+	ts := &inmemory.TokenStorage{}
+	ctx := context.Background()
+	err = ts.Save(ctx, &strava.Token{
+		Token:     &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)},
+		AthleteID: 7,
+	})
+	assert.NoErr(t, err)
+
+	c := strava.NewClient("client_id", "client_secret", "", ts, strava.WithTransport(rec))
+
+	// act
+	athlete, err := c.GetAthlete(ctx, 7)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, "Jane", athlete.FirstName)
+
+	rl, ok := c.RateLimit()
+	assert.True(t, ok)
+	assert.Eq(t, 3, rl.Short.Usage)
+
+	// act
+	_, err = c.GetDetailedActivity(ctx, 7, 42)
+
+	// assert
+	assert.ErrIs(t, err, strava.ErrNotFound)
+	assert.StrContains(t, err.Error(), "invalid")
+}
@@ -0,0 +1,135 @@
+package recorder
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Redacted replaces scrubbed values in cassettes.
+const Redacted = "REDACTED"
+
+var (
+	// DefaultScrubbedHeaders are the headers whose values are redacted.
+	DefaultScrubbedHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+	// DefaultScrubbedParams are the query and form parameters whose values are redacted, including the
+	// authorization code and the webhook verify token.
+	DefaultScrubbedParams = []string{"client_secret", "access_token", "refresh_token", "code", "verify_token", "hub.verify_token"}
+	// DefaultScrubbedFields are the keys of JSON objects whose string values are redacted, e.g. the tokens
+	// of token responses.
+	DefaultScrubbedFields = []string{"client_secret", "access_token", "refresh_token"}
+)
+
+// scrubber redacts secrets from recorded interactions.
+type scrubber struct {
+	headers []string
+	params  map[string]bool
+	fields  map[string]bool
+}
+
+func newScrubber(headers, params, fields []string) *scrubber {
+	return &scrubber{headers: headers, params: set(params), fields: set(fields)}
+}
+
+func set(values []string) map[string]bool {
+	m := make(map[string]bool, len(values))
+	for _, v := range values {
+		m[v] = true
+	}
+	return m
+}
+
+func (s *scrubber) header(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+
+	h = h.Clone()
+	for _, name := range s.headers {
+		if h.Get(name) != "" {
+			h.Set(name, Redacted)
+		}
+	}
+
+	return h
+}
+
+// url redacts the scrubbed query parameters of rawURL.
+func (s *scrubber) url(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.RawQuery == "" {
+		return rawURL
+	}
+
+	u.RawQuery = s.query(u.Query()).Encode()
+	return u.String()
+}
+
+func (s *scrubber) query(q url.Values) url.Values {
+	for k := range q {
+		if s.params[k] {
+			q[k] = []string{Redacted}
+		}
+	}
+
+	return q
+}
+
+// body redacts the scrubbed parameters of form and JSON bodies. Other bodies are returned as is.
+func (s *scrubber) body(header http.Header, body []byte) string {
+	contentType := header.Get("Content-Type")
+
+	switch {
+	case strings.HasPrefix(contentType, "application/x-www-form-urlencoded"):
+		q, err := url.ParseQuery(string(body))
+		if err != nil {
+			return string(body)
+		}
+		return s.query(q).Encode()
+	case strings.Contains(contentType, "json"), json.Valid(body) && len(body) > 0:
+		var v any
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return string(body)
+		}
+
+		// Keep the response as sent unless something was redacted.
+		if !s.json(v) {
+			return string(body)
+		}
+
+		data, err := json.Marshal(v)
+		if err != nil {
+			return string(body)
+		}
+		return string(data)
+	default:
+		return string(body)
+	}
+}
+
+// json redacts the scrubbed keys of JSON objects in v and reports whether any was found.
+func (s *scrubber) json(v any) bool {
+	scrubbed := false
+
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if _, ok := val.(string); ok && s.fields[k] {
+				v[k] = Redacted
+				scrubbed = true
+				continue
+			}
+			scrubbed = s.json(val) || scrubbed
+		}
+	case []any:
+		for _, val := range v {
+			scrubbed = s.json(val) || scrubbed
+		}
+	}
+
+	return scrubbed
+}
@@ -0,0 +1,284 @@
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// respError is an error reply of the server.
+type respError string
+
+func (e respError) Error() string {
+	return string(e)
+}
+
+type conn struct {
+	nc net.Conn
+	r  *bufio.Reader
+	w  *bufio.Writer
+}
+
+func (c *conn) do(ctx context.Context, args ...any) (any, error) {
+	if err := c.send(ctx, args...); err != nil {
+		return nil, err
+	}
+
+	return c.receive()
+}
+
+func (c *conn) send(ctx context.Context, args ...any) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Time{}
+	}
+	if err := c.nc.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.w, "*%d\r\n", len(args))
+	for _, arg := range args {
+		var s string
+		switch v := arg.(type) {
+		case string:
+			s = v
+		case []byte:
+			s = string(v)
+		case int:
+			s = strconv.Itoa(v)
+		case int64:
+			s = strconv.FormatInt(v, 10)
+		case uint:
+			s = strconv.FormatUint(uint64(v), 10)
+		default:
+			return fmt.Errorf("unsupported argument type %T", arg)
+		}
+
+		fmt.Fprintf(c.w, "$%d\r\n%s\r\n", len(s), s)
+	}
+
+	return c.w.Flush()
+}
+
+// receive reads a reply: string for simple strings, int64 for integers,
+// []byte for bulk strings, []any for arrays and nil for null replies.
+// Error replies are returned as respError values, not as errors.
+func (c *conn) receive() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("malformed reply %q", line)
+	}
+	kind, payload := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return payload, nil
+	case '-':
+		return respError(payload), nil
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed bulk length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	case '*':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, fmt.Errorf("malformed array length %q", payload)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = c.receive(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type %q", kind)
+	}
+}
+
+// pool keeps idle connections to a RESP server.
+type pool struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+
+	idle chan *conn
+}
+
+func (p *pool) get(ctx context.Context) (*conn, error) {
+	select {
+	case c := <-p.idle:
+		return c, nil
+	default:
+	}
+
+	d := net.Dialer{Timeout: p.timeout}
+	nc, err := d.DialContext(ctx, "tcp", p.addr)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", p.addr, err)
+	}
+
+	c := &conn{nc: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	if p.password != "" {
+		if err := expectOK(c.do(ctx, "AUTH", p.password)); err != nil {
+			_ = nc.Close()
+			return nil, fmt.Errorf("auth: %w", err)
+		}
+	}
+
+	if p.db != 0 {
+		if err := expectOK(c.do(ctx, "SELECT", p.db)); err != nil {
+			_ = nc.Close()
+			return nil, fmt.Errorf("select %d: %w", p.db, err)
+		}
+	}
+
+	return c, nil
+}
+
+// put returns the connection to the pool unless it failed with an I/O error.
+func (p *pool) put(c *conn, err error) {
+	var rerr respError
+	if err != nil && !errors.As(err, &rerr) {
+		_ = c.nc.Close()
+		return
+	}
+
+	select {
+	case p.idle <- c:
+	default:
+		_ = c.nc.Close()
+	}
+}
+
+// with runs fn with a connection, so several commands can be sent over it, e.g. in a transaction.
+func (p *pool) with(ctx context.Context, fn func(c *conn) error) error {
+	c, err := p.get(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = fn(c)
+	p.put(c, err)
+
+	return err
+}
+
+func (p *pool) do(ctx context.Context, args ...any) (any, error) {
+	var reply any
+	err := p.with(ctx, func(c *conn) error {
+		var err error
+		reply, err = c.do(ctx, args...)
+		if err != nil {
+			return err
+		}
+
+		if rerr, ok := reply.(respError); ok {
+			return rerr
+		}
+		return nil
+	})
+
+	return reply, err
+}
+
+// multi executes the commands in a MULTI/EXEC transaction.
+func (p *pool) multi(ctx context.Context, cmds ...[]any) ([]any, error) {
+	var replies []any
+	err := p.with(ctx, func(c *conn) error {
+		if err := expectOK(c.do(ctx, "MULTI")); err != nil {
+			return err
+		}
+
+		for _, cmd := range cmds {
+			reply, err := c.do(ctx, cmd...)
+			if err != nil {
+				return err
+			}
+			if rerr, ok := reply.(respError); ok {
+				_, _ = c.do(ctx, "DISCARD")
+				return rerr
+			}
+		}
+
+		reply, err := c.do(ctx, "EXEC")
+		if err != nil {
+			return err
+		}
+
+		switch v := reply.(type) {
+		case respError:
+			return v
+		case []any:
+			for _, r := range v {
+				if rerr, ok := r.(respError); ok {
+					return rerr
+				}
+			}
+			replies = v
+			return nil
+		default:
+			return fmt.Errorf("transaction aborted")
+		}
+	})
+
+	return replies, err
+}
+
+func (p *pool) close() error {
+	for {
+		select {
+		case c := <-p.idle:
+			_ = c.nc.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func expectOK(reply any, err error) error {
+	if err != nil {
+		return err
+	}
+
+	switch v := reply.(type) {
+	case respError:
+		return v
+	case string:
+		if v == "OK" {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unexpected reply %v", reply)
+}
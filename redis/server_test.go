@@ -0,0 +1,212 @@
+package redis
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// testServer is an in-process stand-in for a RESP server, supporting the commands used by this package.
+type testServer struct {
+	ln net.Listener
+
+	mu      sync.Mutex
+	strings map[string]string
+	hashes  map[string]map[string]string
+	zsets   map[string]map[string]float64
+}
+
+func newTestServer(t *testing.T) *testServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &testServer{
+		ln:      ln,
+		strings: make(map[string]string),
+		hashes:  make(map[string]map[string]string),
+		zsets:   make(map[string]map[string]float64),
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	go s.serve()
+
+	return s
+}
+
+func (s *testServer) addr() string {
+	return s.ln.Addr().String()
+}
+
+func (s *testServer) serve() {
+	for {
+		nc, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go s.handle(nc)
+	}
+}
+
+func (s *testServer) handle(nc net.Conn) {
+	defer nc.Close()
+
+	c := &conn{nc: nc, r: bufio.NewReader(nc), w: bufio.NewWriter(nc)}
+
+	var queue [][]string
+	inMulti := false
+
+	for {
+		reply, err := c.receive()
+		if err != nil {
+			return
+		}
+
+		items, _ := reply.([]any)
+		args := make([]string, len(items))
+		for i, item := range items {
+			b, _ := item.([]byte)
+			args[i] = string(b)
+		}
+		if len(args) == 0 {
+			return
+		}
+
+		var out any
+		switch cmd := strings.ToUpper(args[0]); {
+		case cmd == "MULTI":
+			inMulti, queue, out = true, nil, "OK"
+		case cmd == "DISCARD":
+			inMulti, queue, out = false, nil, "OK"
+		case cmd == "EXEC":
+			results := make([]any, len(queue))
+			for i, q := range queue {
+				results[i] = s.exec(q)
+			}
+			inMulti, queue, out = false, nil, results
+		case inMulti:
+			queue, out = append(queue, args), "QUEUED"
+		default:
+			out = s.exec(args)
+		}
+
+		writeReply(c.w, out)
+		if err := c.w.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *testServer) exec(args []string) any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch strings.ToUpper(args[0]) {
+	case "PING", "AUTH", "SELECT", "PEXPIRE":
+		return "OK"
+	case "SET":
+		key, value := args[1], args[2]
+		if len(args) > 3 && strings.EqualFold(args[3], "NX") {
+			if _, ok := s.strings[key]; ok {
+				return nil
+			}
+		}
+		s.strings[key] = value
+		return "OK"
+	case "EVAL":
+		// Only the unlock script is supported.
+		key, value := args[3], args[4]
+		if s.strings[key] == value {
+			delete(s.strings, key)
+			return int64(1)
+		}
+		return int64(0)
+	case "DEL":
+		var n int64
+		for _, key := range args[1:] {
+			if _, ok := s.hashes[key]; ok {
+				n++
+			}
+			delete(s.hashes, key)
+			delete(s.strings, key)
+		}
+		return n
+	case "HSET":
+		h, ok := s.hashes[args[1]]
+		if !ok {
+			h = make(map[string]string)
+			s.hashes[args[1]] = h
+		}
+		for i := 2; i+1 < len(args); i += 2 {
+			h[args[i]] = args[i+1]
+		}
+		return int64(len(args)/2 - 1)
+	case "HGET":
+		v, ok := s.hashes[args[1]][args[2]]
+		if !ok {
+			return nil
+		}
+		return []byte(v)
+	case "ZADD":
+		z, ok := s.zsets[args[1]]
+		if !ok {
+			z = make(map[string]float64)
+			s.zsets[args[1]] = z
+		}
+		score, _ := strconv.ParseFloat(args[2], 64)
+		z[args[3]] = score
+		return int64(1)
+	case "ZREM":
+		delete(s.zsets[args[1]], args[2])
+		return int64(1)
+	case "ZRANGEBYSCORE":
+		max, exclusive := args[3], false
+		if strings.HasPrefix(max, "(") {
+			max, exclusive = max[1:], true
+		}
+		limit, _ := strconv.ParseFloat(max, 64)
+
+		var members []string
+		for m, score := range s.zsets[args[1]] {
+			if score < limit || (!exclusive && score == limit) {
+				members = append(members, m)
+			}
+		}
+		sort.Strings(members)
+
+		out := make([]any, len(members))
+		for i, m := range members {
+			out[i] = []byte(m)
+		}
+		return out
+	default:
+		return respError("ERR unknown command " + args[0])
+	}
+}
+
+func writeReply(w *bufio.Writer, v any) {
+	switch v := v.(type) {
+	case nil:
+		_, _ = w.WriteString("$-1\r\n")
+	case string:
+		fmt.Fprintf(w, "+%s\r\n", v)
+	case respError:
+		fmt.Fprintf(w, "-%s\r\n", v)
+	case int64:
+		fmt.Fprintf(w, ":%d\r\n", v)
+	case []byte:
+		fmt.Fprintf(w, "$%d\r\n%s\r\n", len(v), v)
+	case []any:
+		fmt.Fprintf(w, "*%d\r\n", len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	}
+}
@@ -0,0 +1,276 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+const (
+	DefaultKeyPrefix = "strava:"
+	DefaultLockTTL   = 30 * time.Second
+	DefaultPoolSize  = 10
+	DefaultTimeout   = 5 * time.Second
+)
+
+// unlockScript deletes the lock only if it's still held by the caller.
+const unlockScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+
+// TokenStorage stores tokens on a RESP-compatible server like Redis, Valkey or KeyDB.
+//
+// Each token is a hash under "<prefix>token:<athlete ID>" with the token JSON and its expiry,
+// and a sorted set "<prefix>token-expiry" indexes athletes by token expiry.
+type TokenStorage struct {
+	pool *pool
+
+	// rootPrefix is the prefix set with WithKeyPrefix, prefix also includes the namespace.
+	rootPrefix string
+	prefix     string
+	ttl        time.Duration
+	lockTTL    time.Duration
+}
+
+var (
+	_ strava.TokenStorage    = (*TokenStorage)(nil)
+	_ strava.TokenLister     = (*TokenStorage)(nil)
+	_ strava.TokenDeleter    = (*TokenStorage)(nil)
+	_ strava.TokenLocker     = (*TokenStorage)(nil)
+	_ strava.TokenNamespacer = (*TokenStorage)(nil)
+)
+
+type Option func(*TokenStorage)
+
+func WithPassword(password string) Option {
+	return func(ts *TokenStorage) {
+		ts.pool.password = password
+	}
+}
+
+func WithDB(db int) Option {
+	return func(ts *TokenStorage) {
+		ts.pool.db = db
+	}
+}
+
+func WithPoolSize(n int) Option {
+	return func(ts *TokenStorage) {
+		ts.pool.idle = make(chan *conn, n)
+	}
+}
+
+func WithTimeout(d time.Duration) Option {
+	return func(ts *TokenStorage) {
+		ts.pool.timeout = d
+	}
+}
+
+// WithKeyPrefix sets the prefix of all keys, so several apps can share a database.
+func WithKeyPrefix(prefix string) Option {
+	return func(ts *TokenStorage) {
+		ts.rootPrefix = prefix
+	}
+}
+
+// WithTTL makes tokens expire when they weren't saved for d, e.g. to forget inactive athletes.
+// Tokens don't expire by default.
+func WithTTL(d time.Duration) Option {
+	return func(ts *TokenStorage) {
+		ts.ttl = d
+	}
+}
+
+// WithLockTTL sets how long a refresh lock is held at most, in case its holder dies.
+func WithLockTTL(d time.Duration) Option {
+	return func(ts *TokenStorage) {
+		ts.lockTTL = d
+	}
+}
+
+func NewTokenStorage(addr string, opts ...Option) *TokenStorage {
+	ts := &TokenStorage{
+		pool: &pool{
+			addr:    addr,
+			timeout: DefaultTimeout,
+			idle:    make(chan *conn, DefaultPoolSize),
+		},
+		rootPrefix: DefaultKeyPrefix,
+		lockTTL:    DefaultLockTTL,
+	}
+
+	for _, opt := range opts {
+		opt(ts)
+	}
+	ts.prefix = ts.rootPrefix
+
+	return ts
+}
+
+// ForClient returns a view of the storage with keys prefixed by "<prefix>client:<client ID>:".
+func (ts *TokenStorage) ForClient(clientID string) strava.TokenStorage {
+	view := *ts
+	view.prefix = ts.rootPrefix + "client:" + clientID + ":"
+	return &view
+}
+
+// Close closes idle connections.
+func (ts *TokenStorage) Close() error {
+	return ts.pool.close()
+}
+
+func (ts *TokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token, error) {
+	reply, err := ts.pool.do(ctx, "HGET", ts.tokenKey(athleteID), "token")
+	if err != nil {
+		return nil, fmt.Errorf("get token: %w", err)
+	}
+
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, strava.ErrTokenNotFound
+	}
+
+	var token strava.Token
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, fmt.Errorf("unmarshal token: %w", err)
+	}
+
+	return &token, nil
+}
+
+func (ts *TokenStorage) Save(ctx context.Context, token *strava.Token) error {
+	data, err := json.Marshal(token)
+	if err != nil {
+		return fmt.Errorf("marshal token: %w", err)
+	}
+
+	score := "+inf"
+	var expiry int64
+	if token.Token != nil && !token.Expiry.IsZero() {
+		expiry = token.Expiry.Unix()
+		score = strconv.FormatInt(expiry, 10)
+	}
+
+	key := ts.tokenKey(token.AthleteID)
+	cmds := [][]any{
+		{"HSET", key, "token", data, "expiry", expiry, "scope", token.Scope},
+		{"ZADD", ts.expiryKey(), score, token.AthleteID},
+	}
+	if ts.ttl > 0 {
+		cmds = append(cmds, []any{"PEXPIRE", key, ts.ttl.Milliseconds()})
+	}
+
+	if _, err := ts.pool.multi(ctx, cmds...); err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+
+	return nil
+}
+
+func (ts *TokenStorage) Delete(ctx context.Context, athleteID uint) error {
+	_, err := ts.pool.multi(ctx,
+		[]any{"DEL", ts.tokenKey(athleteID)},
+		[]any{"ZREM", ts.expiryKey(), athleteID},
+	)
+	if err != nil {
+		return fmt.Errorf("delete token: %w", err)
+	}
+
+	return nil
+}
+
+func (ts *TokenStorage) List(ctx context.Context) ([]*strava.Token, error) {
+	return ts.listByScore(ctx, "+inf")
+}
+
+// ListExpiring returns tokens expiring before the given time.
+func (ts *TokenStorage) ListExpiring(ctx context.Context, before time.Time) ([]*strava.Token, error) {
+	return ts.listByScore(ctx, "("+strconv.FormatInt(before.Unix(), 10))
+}
+
+func (ts *TokenStorage) listByScore(ctx context.Context, max string) ([]*strava.Token, error) {
+	reply, err := ts.pool.do(ctx, "ZRANGEBYSCORE", ts.expiryKey(), "-inf", max)
+	if err != nil {
+		return nil, fmt.Errorf("list athletes: %w", err)
+	}
+
+	members, _ := reply.([]any)
+	tokens := make([]*strava.Token, 0, len(members))
+	for _, m := range members {
+		b, _ := m.([]byte)
+		athleteID, err := strconv.ParseUint(string(b), 10, 0)
+		if err != nil {
+			return nil, fmt.Errorf("parse athlete ID %q: %w", b, err)
+		}
+
+		token, err := ts.Get(ctx, uint(athleteID))
+		if errors.Is(err, strava.ErrTokenNotFound) {
+			// The token hash expired, but the index entry is still there.
+			_, _ = ts.pool.do(ctx, "ZREM", ts.expiryKey(), uint(athleteID))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, token)
+	}
+
+	return tokens, nil
+}
+
+// LockToken acquires a lock for refreshing the athlete's token, shared by all processes using the server.
+// It waits until the lock is acquired or ctx is done.
+func (ts *TokenStorage) LockToken(ctx context.Context, athleteID uint) (func() error, error) {
+	key := ts.prefix + "lock:" + strconv.FormatUint(uint64(athleteID), 10)
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("generate lock value: %w", err)
+	}
+	value := hex.EncodeToString(b)
+
+	delay := 10 * time.Millisecond
+	for {
+		reply, err := ts.pool.do(ctx, "SET", key, value, "NX", "PX", ts.lockTTL.Milliseconds())
+		if err != nil {
+			return nil, fmt.Errorf("acquire lock: %w", err)
+		}
+		if reply == "OK" {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("acquire lock: %w", ctx.Err())
+		case <-time.After(delay):
+		}
+
+		delay = min(delay*2, 100*time.Millisecond)
+	}
+
+	unlock := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), ts.pool.timeout)
+		defer cancel()
+
+		if _, err := ts.pool.do(ctx, "EVAL", unlockScript, 1, key, value); err != nil {
+			return fmt.Errorf("release lock: %w", err)
+		}
+		return nil
+	}
+
+	return unlock, nil
+}
+
+func (ts *TokenStorage) tokenKey(athleteID uint) string {
+	return ts.prefix + "token:" + strconv.FormatUint(uint64(athleteID), 10)
+}
+
+func (ts *TokenStorage) expiryKey() string {
+	return ts.prefix + "token-expiry"
+}
@@ -0,0 +1,95 @@
+package redis
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestTokenStorage(t *testing.T) {
+	storagetest.RunTokenStorageSuite(t, func(t *testing.T) strava.TokenStorage {
+		ts := NewTokenStorage(newTestServer(t).addr())
+		t.Cleanup(func() { _ = ts.Close() })
+		return ts
+	})
+}
+
+func TestTokenStorage_SaveGetList(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	ts := NewTokenStorage(newTestServer(t).addr())
+	t.Cleanup(func() { _ = ts.Close() })
+
+	now := time.Now()
+	for i, expiry := range []time.Time{now.Add(time.Minute), now.Add(time.Hour), {}} {
+		err := ts.Save(ctx, &strava.Token{
+			Token:     &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: expiry},
+			AthleteID: uint(i + 1),
+			Scope:     "read",
+		})
+		assert.NoErr(t, err)
+	}
+
+	// act
+	got, getErr := ts.Get(ctx, 1)
+	all, listErr := ts.List(ctx)
+	expiring, expiringErr := ts.ListExpiring(ctx, now.Add(10*time.Minute))
+	deleteErr := ts.Delete(ctx, 2)
+	_, deletedErr := ts.Get(ctx, 2)
+
+	// assert
+	assert.NoErr(t, getErr)
+	assert.Eq(t, "refresh", got.RefreshToken)
+	assert.NoErr(t, listErr)
+	assert.Len(t, all, 3)
+	assert.NoErr(t, expiringErr)
+	assert.Len(t, expiring, 1)
+	assert.Eq(t, uint(1), expiring[0].AthleteID)
+	assert.NoErr(t, deleteErr)
+	assert.ErrIs(t, deletedErr, strava.ErrTokenNotFound)
+}
+
+func TestTokenStorage_LockToken(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	srv := newTestServer(t)
+	replicas := []*TokenStorage{NewTokenStorage(srv.addr()), NewTokenStorage(srv.addr())}
+
+	var holders, maxHolders int32
+
+	// act
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(ts *TokenStorage) {
+			defer wg.Done()
+
+			unlock, err := ts.LockToken(ctx, 1)
+			assert.NoErr(t, err)
+
+			n := atomic.AddInt32(&holders, 1)
+			for {
+				m := atomic.LoadInt32(&maxHolders)
+				if n <= m || atomic.CompareAndSwapInt32(&maxHolders, m, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&holders, -1)
+
+			assert.NoErr(t, unlock())
+		}(replicas[i%2])
+	}
+	wg.Wait()
+
+	// assert
+	assert.Eq(t, int32(1), maxHolders)
+}
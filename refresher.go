@@ -0,0 +1,196 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultRefreshInterval    = 5 * time.Minute
+	DefaultRefreshWindow      = 15 * time.Minute
+	DefaultRefreshConcurrency = 4
+)
+
+// TokenRefresher periodically refreshes tokens that are about to expire,
+// so API calls and webhook handlers don't have to refresh them on demand.
+type TokenRefresher struct {
+	client *Client
+	lister TokenLister
+
+	interval    time.Duration
+	window      time.Duration
+	concurrency int
+
+	onSuccess func(token *Token)
+	onFailure func(athleteID uint, err error)
+	onRevoked func(athleteID uint)
+}
+
+type RefresherOption func(*TokenRefresher)
+
+// NewTokenRefresher creates a refresher for the client's tokens.
+// The client's token storage must implement TokenLister.
+func NewTokenRefresher(c *Client, opts ...RefresherOption) (*TokenRefresher, error) {
+	lister, ok := c.tstore.(TokenLister)
+	if !ok {
+		return nil, fmt.Errorf("token storage %T doesn't implement TokenLister", c.tstore)
+	}
+
+	r := &TokenRefresher{
+		client:      c,
+		lister:      lister,
+		interval:    DefaultRefreshInterval,
+		window:      DefaultRefreshWindow,
+		concurrency: DefaultRefreshConcurrency,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r, nil
+}
+
+// WithRefreshInterval sets how often the storage is scanned for expiring tokens.
+func WithRefreshInterval(d time.Duration) RefresherOption {
+	return func(r *TokenRefresher) {
+		r.interval = d
+	}
+}
+
+// WithRefreshWindow sets how long before expiry a token gets refreshed.
+func WithRefreshWindow(d time.Duration) RefresherOption {
+	return func(r *TokenRefresher) {
+		r.window = d
+	}
+}
+
+// WithRefreshConcurrency limits the number of simultaneous refresh requests.
+func WithRefreshConcurrency(n int) RefresherOption {
+	return func(r *TokenRefresher) {
+		if n > 0 {
+			r.concurrency = n
+		}
+	}
+}
+
+// WithRefreshSuccessCallback sets a callback called with every refreshed token.
+func WithRefreshSuccessCallback(fn func(token *Token)) RefresherOption {
+	return func(r *TokenRefresher) {
+		r.onSuccess = fn
+	}
+}
+
+// WithRefreshFailureCallback sets a callback called when a token couldn't be refreshed.
+func WithRefreshFailureCallback(fn func(athleteID uint, err error)) RefresherOption {
+	return func(r *TokenRefresher) {
+		r.onFailure = fn
+	}
+}
+
+// WithRevokedCallback sets a callback called when Strava rejects an athlete's refresh token.
+// The token is marked as revoked in the storage before the callback is called.
+func WithRevokedCallback(fn func(athleteID uint)) RefresherOption {
+	return func(r *TokenRefresher) {
+		r.onRevoked = fn
+	}
+}
+
+// Run refreshes expiring tokens every interval until ctx is done.
+func (r *TokenRefresher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := r.RefreshExpiring(ctx); err != nil {
+			r.client.logger.ErrorContext(ctx, "refresh expiring tokens", slog.Any("error", err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// RefreshExpiring refreshes all tokens expiring within the refresh window once.
+// Failures of individual tokens are reported through the callbacks.
+func (r *TokenRefresher) RefreshExpiring(ctx context.Context) error {
+	tokens, err := r.lister.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list tokens: %w", err)
+	}
+
+	deadline := time.Now().Add(r.window)
+	sem := make(chan struct{}, r.concurrency)
+
+	var wg sync.WaitGroup
+	for _, token := range tokens {
+		if !r.expiring(token, deadline) {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			return ctx.Err()
+		case sem <- struct{}{}:
+		}
+
+		wg.Add(1)
+		go func(athleteID uint) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			r.refresh(ctx, athleteID)
+		}(token.AthleteID)
+	}
+	wg.Wait()
+
+	return nil
+}
+
+func (r *TokenRefresher) expiring(token *Token, deadline time.Time) bool {
+	if token.Revoked || token.Token == nil || token.RefreshToken == "" {
+		return false
+	}
+
+	// Tokens without expiry never expire.
+	if token.Expiry.IsZero() {
+		return false
+	}
+
+	return token.Expiry.Before(deadline)
+}
+
+func (r *TokenRefresher) refresh(ctx context.Context, athleteID uint) {
+	token, err := r.client.RefreshToken(ctx, athleteID)
+	if err != nil {
+		if errors.Is(err, ErrTokenRevoked) {
+			r.client.logger.WarnContext(ctx, "refresh token revoked", slog.Uint64("athleteID", uint64(athleteID)))
+
+			if r.onRevoked != nil {
+				r.onRevoked(athleteID)
+			}
+			return
+		}
+
+		r.client.logger.ErrorContext(ctx, "refresh token", slog.Uint64("athleteID", uint64(athleteID)), slog.Any("error", err))
+
+		if r.onFailure != nil {
+			r.onFailure(athleteID, err)
+		}
+		return
+	}
+
+	if r.onSuccess != nil {
+		r.onSuccess(token)
+	}
+}
@@ -0,0 +1,160 @@
+package strava
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+)
+
+type testTokenStorage struct {
+	mu     sync.Mutex
+	tokens map[uint]Token
+}
+
+func newTestTokenStorage(tokens ...*Token) *testTokenStorage {
+	ts := &testTokenStorage{tokens: make(map[uint]Token)}
+	for _, t := range tokens {
+		ts.tokens[t.AthleteID] = *t
+	}
+	return ts
+}
+
+func (ts *testTokenStorage) Get(_ context.Context, athleteID uint) (*Token, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	t, ok := ts.tokens[athleteID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return &t, nil
+}
+
+func (ts *testTokenStorage) Save(_ context.Context, token *Token) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.tokens[token.AthleteID] = *token
+	return nil
+}
+
+func (ts *testTokenStorage) Delete(_ context.Context, athleteID uint) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	delete(ts.tokens, athleteID)
+	return nil
+}
+
+func (ts *testTokenStorage) List(_ context.Context) ([]*Token, error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	var tokens []*Token
+	for _, t := range ts.tokens {
+		t := t
+		tokens = append(tokens, &t)
+	}
+	return tokens, nil
+}
+
+// newTestTokenServer emulates the Strava token endpoint: the "revoked" refresh token is rejected,
+// the "unavailable" one fails with a server error and codes are exchanged for tokens of athlete 5.
+func newTestTokenServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		switch r.PostForm.Get("refresh_token") {
+		case "revoked":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"message":"Bad Request","errors":[{"resource":"RefreshToken","field":"refresh_token","code":"invalid"}]}`))
+			return
+		case "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"message":"Service Unavailable"}`))
+			return
+		}
+
+		if r.PostForm.Get("grant_type") == "authorization_code" {
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"token_type":    "Bearer",
+				"access_token":  "access-" + r.PostForm.Get("code"),
+				"refresh_token": "refresh-" + r.PostForm.Get("code"),
+				"expires_in":    21600,
+				"athlete":       map[string]any{"id": 5},
+			})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"token_type":    "Bearer",
+			"access_token":  "new-access-" + r.PostForm.Get("refresh_token"),
+			"refresh_token": r.PostForm.Get("refresh_token"),
+			"expires_in":    21600,
+		})
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func newTestClient(srv *httptest.Server, ts TokenStorage, opts ...Option) *Client {
+	c := NewClient("client_id", "client_secret", "", ts, opts...)
+	c.oacfg.Endpoint.TokenURL = srv.URL
+	return c
+}
+
+func TestTokenRefresher_RefreshExpiring(t *testing.T) {
+	// arrange
+	now := time.Now()
+	ts := newTestTokenStorage(
+		&Token{AthleteID: 1, Token: &oauth2.Token{AccessToken: "a1", RefreshToken: "r1", Expiry: now.Add(time.Minute)}},
+		&Token{AthleteID: 2, Token: &oauth2.Token{AccessToken: "a2", RefreshToken: "r2", Expiry: now.Add(time.Hour)}},
+		&Token{AthleteID: 3, Token: &oauth2.Token{AccessToken: "a3", RefreshToken: "revoked", Expiry: now.Add(time.Minute)}},
+	)
+	c := newTestClient(newTestTokenServer(t), ts)
+
+	var mu sync.Mutex
+	var refreshed, revoked []uint
+	r, err := NewTokenRefresher(c,
+		WithRefreshWindow(10*time.Minute),
+		WithRefreshSuccessCallback(func(token *Token) {
+			mu.Lock()
+			refreshed = append(refreshed, token.AthleteID)
+			mu.Unlock()
+		}),
+		WithRevokedCallback(func(athleteID uint) {
+			mu.Lock()
+			revoked = append(revoked, athleteID)
+			mu.Unlock()
+		}),
+	)
+	assert.NoErr(t, err)
+
+	// act
+	err = r.RefreshExpiring(context.Background())
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, []uint{1}, refreshed)
+	assert.Eq(t, []uint{3}, revoked)
+
+	t1, _ := ts.Get(context.Background(), 1)
+	assert.Eq(t, "new-access-r1", t1.AccessToken)
+	t2, _ := ts.Get(context.Background(), 2)
+	assert.Eq(t, "a2", t2.AccessToken)
+	t3, _ := ts.Get(context.Background(), 3)
+	assert.True(t, t3.Revoked)
+
+	_, err = c.token(context.Background(), 3)
+	assert.ErrIs(t, err, ErrTokenRevoked)
+}
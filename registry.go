@@ -0,0 +1,172 @@
+package strava
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Registry holds the clients of several Strava applications served by one process.
+// It routes API requests by application ID and webhook callbacks by subscription ID.
+//
+// Use WithTokenNamespace for the clients if they share a token storage.
+type Registry struct {
+	mu      sync.RWMutex
+	clients map[string]*Client
+}
+
+func NewRegistry(clients ...*Client) (*Registry, error) {
+	r := &Registry{
+		clients: make(map[string]*Client, len(clients)),
+	}
+
+	for _, c := range clients {
+		if err := r.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// Register adds the client, replacing a client with the same application ID.
+// Clients with invalid options, see Client.Err, are rejected.
+func (r *Registry) Register(c *Client) error {
+	if err := c.Err(); err != nil {
+		return fmt.Errorf("register client %s: %w", c.ClientID(), err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.clients[c.ClientID()] = c
+	return nil
+}
+
+// Client returns the client of the application. The application ID is the client ID.
+func (r *Registry) Client(applicationID string) (*Client, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.clients[applicationID]
+	return c, ok
+}
+
+// ClientBySubscription returns the client of the application the push subscription belongs to.
+func (r *Registry) ClientBySubscription(subscriptionID uint) (*Client, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(r.clients))
+	for _, c := range r.clients {
+		clients = append(clients, c)
+	}
+
+	return clientBySubscription(clients, subscriptionID)
+}
+
+// WebhookCallback is a webhook callback handler shared by all registered clients.
+// Validation requests are routed by verify token, events by subscription ID, both only to the clients
+// whose WithWebhookAllowedIPs allow the source address. Requests no client allows are rejected before
+// the body or the token are looked at.
+func (r *Registry) WebhookCallback(w http.ResponseWriter, req *http.Request) {
+	clients := r.allowedClients(req)
+	if len(clients) == 0 {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		c, ok := clientByVerifyToken(req.Context(), clients, req.URL.Query().Get("hub.verify_token"))
+		if !ok {
+			http.Error(w, "invalid verification token", http.StatusBadRequest)
+			return
+		}
+
+		c.WebhookCallback(w, req)
+	case http.MethodPost:
+		// The client isn't known before the body is read, so the largest limit applies here
+		// and the client's own one when it handles the request.
+		body, err := io.ReadAll(http.MaxBytesReader(w, req.Body, maxBodySize(clients)))
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		c, ok := clientBySubscription(clients, event.SubscriptionID)
+		if !ok {
+			http.Error(w, "unknown subscription", http.StatusNotFound)
+			return
+		}
+
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		c.WebhookCallback(w, req)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// allowedClients returns the clients accepting webhook requests from the request's source address.
+func (r *Registry) allowedClients(req *http.Request) []*Client {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var clients []*Client
+	for _, c := range r.clients {
+		if c.webhookSourceAllowed(req) {
+			clients = append(clients, c)
+		}
+	}
+
+	return clients
+}
+
+func clientByVerifyToken(ctx context.Context, clients []*Client, token string) (*Client, bool) {
+	for _, c := range clients {
+		if c.validVerifyToken(ctx, token) {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+func clientBySubscription(clients []*Client, subscriptionID uint) (*Client, bool) {
+	for _, c := range clients {
+		if id := c.SubscriptionID(); id != 0 && id == subscriptionID {
+			return c, true
+		}
+	}
+
+	return nil, false
+}
+
+// maxBodySize returns the largest webhook body size of the clients.
+func maxBodySize(clients []*Client) int64 {
+	var n int64
+	for _, c := range clients {
+		n = max(n, c.webhookMaxBodySize)
+	}
+	if n == 0 {
+		n = DefaultWebhookMaxBodySize
+	}
+
+	return n
+}
@@ -0,0 +1,164 @@
+package strava
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestRegistry_WebhookCallback(t *testing.T) {
+	// arrange
+	consumer := NewClient("1001", "secret", "", nil, WithWebhookVerifyToken("consumer-token"))
+	consumer.subscriptionID.Store(11)
+	coaching := NewClient("1002", "secret", "", nil, WithWebhookVerifyToken("coaching-token"))
+	coaching.subscriptionID.Store(12)
+
+	events := make(chan string, 2)
+	_ = consumer.RegisterEventHandler(func(_ context.Context, event Event) error {
+		events <- "consumer"
+		return nil
+	})
+	_ = coaching.RegisterEventHandler(func(_ context.Context, event Event) error {
+		events <- "coaching"
+		return nil
+	})
+	consumer.StartWebhookWorkers()
+	coaching.StartWebhookWorkers()
+
+	r, err := NewRegistry(consumer, coaching)
+	assert.NoErr(t, err)
+
+	// act
+	validation := httptest.NewRecorder()
+	r.WebhookCallback(validation, httptest.NewRequest(http.MethodGet, "/callback?hub.mode=subscribe&hub.challenge=15f7d1a91c1f40f8a748fd134752feb3&hub.verify_token=coaching-token", nil))
+
+	event := httptest.NewRecorder()
+	r.WebhookCallback(event, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(`{"object_type":"activity","object_id":1,"aspect_type":"create","owner_id":2,"subscription_id":12,"event_time":1516126040}`)))
+
+	unknown := httptest.NewRecorder()
+	r.WebhookCallback(unknown, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(`{"subscription_id":13}`)))
+
+	// assert
+	assert.Eq(t, http.StatusOK, validation.Code)
+	assert.StrContains(t, validation.Body.String(), "15f7d1a91c1f40f8a748fd134752feb3")
+	assert.Eq(t, http.StatusOK, event.Code)
+	assert.Eq(t, http.StatusNotFound, unknown.Code)
+
+	select {
+	case got := <-events:
+		assert.Eq(t, "coaching", got)
+	case <-time.After(time.Second):
+		t.Fatal("event wasn't handled")
+	}
+}
+
+func TestRegistry_WebhookCallbackBodySize(t *testing.T) {
+	// arrange
+	const event = `{"object_type":"activity","object_id":1,"aspect_type":"create","owner_id":2,"subscription_id":12,"event_time":1516126040}`
+	large := `{"object_type":"activity","object_id":1,"aspect_type":"create","owner_id":2,"subscription_id":11,"event_time":1516126040,"updates":{"title":"` + strings.Repeat("x", 200) + `"}}`
+
+	small := NewClient("1001", "secret", "", nil, WithWebhookMaxBodySize(int64(len(event))))
+	small.subscriptionID.Store(11)
+	big := NewClient("1002", "secret", "", nil, WithWebhookMaxBodySize(1<<10))
+	big.subscriptionID.Store(12)
+	_ = big.RegisterEventHandler(func(context.Context, Event) error { return nil })
+	big.StartWebhookWorkers()
+
+	r, err := NewRegistry(small, big)
+	assert.NoErr(t, err)
+
+	// act
+	bigEvent := httptest.NewRecorder()
+	r.WebhookCallback(bigEvent, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(strings.Replace(large, `"subscription_id":11`, `"subscription_id":12`, 1))))
+
+	smallEvent := httptest.NewRecorder()
+	r.WebhookCallback(smallEvent, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(large)))
+
+	tooLarge := httptest.NewRecorder()
+	r.WebhookCallback(tooLarge, httptest.NewRequest(http.MethodPost, "/callback", strings.NewReader(strings.Repeat(" ", 2<<10)+event)))
+
+	// assert
+	assert.Eq(t, http.StatusOK, bigEvent.Code)
+	assert.Eq(t, http.StatusRequestEntityTooLarge, smallEvent.Code)
+	assert.Eq(t, http.StatusRequestEntityTooLarge, tooLarge.Code)
+	assert.NoErr(t, big.Shutdown(context.Background()))
+}
+
+func TestRegistry_UnsupportedNamespace(t *testing.T) {
+	// arrange
+	c := NewClient("1001", "secret", "", newTestTokenStorage(), WithTokenNamespace())
+
+	// act
+	_, err := NewRegistry(c)
+
+	// assert
+	assert.ErrIs(t, err, ErrNamespacesUnsupported)
+	assert.ErrIs(t, c.Err(), ErrNamespacesUnsupported)
+	assert.NoErr(t, NewClient("1001", "secret", "", newTestTokenStorage()).Err())
+}
+
+// readCounter counts the reads of a request body.
+type readCounter struct {
+	r     *strings.Reader
+	reads int
+}
+
+func (rc *readCounter) Read(p []byte) (int, error) {
+	rc.reads++
+	return rc.r.Read(p)
+}
+
+func TestRegistry_WebhookCallbackAllowedIPs(t *testing.T) {
+	// arrange
+	consumer := NewClient("1001", "secret", "", nil, WithWebhookVerifyToken("consumer-token"), WithWebhookAllowedIPs(netip.MustParsePrefix("10.0.0.0/8")))
+	consumer.subscriptionID.Store(11)
+	coaching := NewClient("1002", "secret", "", nil, WithWebhookVerifyToken("coaching-token"), WithWebhookAllowedIPs(netip.MustParsePrefix("192.168.0.0/16")))
+	coaching.subscriptionID.Store(12)
+
+	r, err := NewRegistry(consumer, coaching)
+	assert.NoErr(t, err)
+
+	const validation = "/callback?hub.mode=subscribe&hub.challenge=challenge&hub.verify_token=coaching-token"
+	const event = `{"object_type":"activity","object_id":1,"aspect_type":"create","owner_id":2,"subscription_id":12,"event_time":1516126040}`
+
+	tests := []struct {
+		name       string
+		method     string
+		remoteAddr string
+		wantStatus int
+		wantRead   bool
+	}{
+		{name: "validation from unknown address", method: http.MethodGet, remoteAddr: "203.0.113.1:1234", wantStatus: http.StatusForbidden},
+		{name: "event from unknown address", method: http.MethodPost, remoteAddr: "203.0.113.1:1234", wantStatus: http.StatusForbidden},
+		{name: "validation from other client's address", method: http.MethodGet, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusBadRequest},
+		{name: "event from other client's address", method: http.MethodPost, remoteAddr: "10.0.0.1:1234", wantStatus: http.StatusNotFound, wantRead: true},
+		{name: "validation", method: http.MethodGet, remoteAddr: "192.168.0.1:1234", wantStatus: http.StatusOK, wantRead: true},
+		{name: "event", method: http.MethodPost, remoteAddr: "192.168.0.1:1234", wantStatus: http.StatusOK, wantRead: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := &readCounter{r: strings.NewReader(event)}
+			target := "/callback"
+			if tt.method == http.MethodGet {
+				target = validation
+			}
+			req := httptest.NewRequest(tt.method, target, body)
+			req.RemoteAddr = tt.remoteAddr
+			rec := httptest.NewRecorder()
+
+			// act
+			r.WebhookCallback(rec, req)
+
+			// assert
+			assert.Eq(t, tt.wantStatus, rec.Code)
+			assert.Eq(t, tt.wantRead, body.reads > 0)
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package strava
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ActivityEvent is the common part of activity webhook events.
+type ActivityEvent struct {
+	ActivityID     uint
+	AthleteID      uint
+	SubscriptionID uint
+	EventTime      time.Time
+}
+
+type ActivityCreate struct {
+	ActivityEvent
+}
+
+// ActivityUpdate holds the changed fields of an activity, unchanged ones are nil.
+type ActivityUpdate struct {
+	ActivityEvent
+	Title      *string
+	Type       *ActivityType
+	Private    *bool
+	Visibility *string
+	// Updates are the raw updates, including fields not parsed above.
+	Updates map[string]string
+}
+
+type ActivityDelete struct {
+	ActivityEvent
+}
+
+// AthleteEvent is the common part of athlete webhook events.
+type AthleteEvent struct {
+	AthleteID      uint
+	SubscriptionID uint
+	EventTime      time.Time
+}
+
+type AthleteUpdate struct {
+	AthleteEvent
+	Updates map[string]string
+}
+
+// AthleteDeauthorize is sent when the athlete revokes the application's access.
+type AthleteDeauthorize struct {
+	AthleteEvent
+}
+
+// EventRouter dispatches webhook events to handlers registered for their object and aspect type.
+// Register its Handle method with Client.RegisterEventHandler.
+type EventRouter struct {
+	mu                 sync.RWMutex
+	activityCreate     []func(context.Context, ActivityCreate) error
+	activityUpdate     []func(context.Context, ActivityUpdate) error
+	activityDelete     []func(context.Context, ActivityDelete) error
+	athleteUpdate      []func(context.Context, AthleteUpdate) error
+	athleteDeauthorize []func(context.Context, AthleteDeauthorize) error
+}
+
+func NewEventRouter() *EventRouter {
+	return &EventRouter{}
+}
+
+func (r *EventRouter) OnActivityCreate(fn func(context.Context, ActivityCreate) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.activityCreate = append(r.activityCreate, fn)
+}
+
+func (r *EventRouter) OnActivityUpdate(fn func(context.Context, ActivityUpdate) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.activityUpdate = append(r.activityUpdate, fn)
+}
+
+func (r *EventRouter) OnActivityDelete(fn func(context.Context, ActivityDelete) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.activityDelete = append(r.activityDelete, fn)
+}
+
+// OnAthleteUpdate registers a handler for athlete updates other than deauthorization.
+func (r *EventRouter) OnAthleteUpdate(fn func(context.Context, AthleteUpdate) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.athleteUpdate = append(r.athleteUpdate, fn)
+}
+
+func (r *EventRouter) OnAthleteDeauthorize(fn func(context.Context, AthleteDeauthorize) error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.athleteDeauthorize = append(r.athleteDeauthorize, fn)
+}
+
+// Handle calls the handlers registered for the event in order and joins their errors.
+// Events without handlers are ignored.
+func (r *EventRouter) Handle(ctx context.Context, event Event) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	switch event.ObjectType {
+	case EventObjectTypeActivity:
+		ae := ActivityEvent{
+			ActivityID:     event.ObjectID,
+			AthleteID:      event.OwnerID,
+			SubscriptionID: event.SubscriptionID,
+			EventTime:      event.Time(),
+		}
+
+		switch event.AspectType {
+		case EventAspectTypeCreate:
+			return dispatch(ctx, r.activityCreate, ActivityCreate{ActivityEvent: ae})
+		case EventAspectTypeUpdate:
+			return dispatch(ctx, r.activityUpdate, newActivityUpdate(ae, event.Updates))
+		case EventAspectTypeDelete:
+			return dispatch(ctx, r.activityDelete, ActivityDelete{ActivityEvent: ae})
+		}
+	case EventObjectTypeAthlete:
+		ae := AthleteEvent{
+			AthleteID:      event.OwnerID,
+			SubscriptionID: event.SubscriptionID,
+			EventTime:      event.Time(),
+		}
+
+		if event.IsDeauthorization() {
+			return dispatch(ctx, r.athleteDeauthorize, AthleteDeauthorize{AthleteEvent: ae})
+		}
+
+		if event.AspectType == EventAspectTypeUpdate {
+			return dispatch(ctx, r.athleteUpdate, AthleteUpdate{AthleteEvent: ae, Updates: event.Updates})
+		}
+	}
+
+	return nil
+}
+
+func dispatch[T any](ctx context.Context, handlers []func(context.Context, T) error, payload T) error {
+	var errs []error
+	for _, h := range handlers {
+		if err := h(ctx, payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func newActivityUpdate(ae ActivityEvent, updates map[string]string) ActivityUpdate {
+	u := ActivityUpdate{
+		ActivityEvent: ae,
+		Updates:       updates,
+	}
+
+	if v, ok := updates["title"]; ok {
+		u.Title = &v
+	}
+
+	if v, ok := updates["type"]; ok {
+		t := ActivityType(v)
+		u.Type = &t
+	}
+
+	if v, ok := updates["private"]; ok {
+		if private, err := strconv.ParseBool(v); err == nil {
+			u.Private = &private
+		}
+	}
+
+	if v, ok := updates["visibility"]; ok {
+		u.Visibility = &v
+	}
+
+	return u
+}
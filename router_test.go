@@ -0,0 +1,61 @@
+package strava
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+func TestEventRouter_Handle(t *testing.T) {
+	// arrange
+	r := NewEventRouter()
+
+	var update ActivityUpdate
+	r.OnActivityUpdate(func(_ context.Context, u ActivityUpdate) error {
+		update = u
+		return nil
+	})
+
+	var deauthorized []uint
+	r.OnAthleteDeauthorize(func(_ context.Context, d AthleteDeauthorize) error {
+		deauthorized = append(deauthorized, d.AthleteID)
+		return nil
+	})
+	r.OnAthleteUpdate(func(context.Context, AthleteUpdate) error {
+		t.Fatal("deauthorization dispatched as athlete update")
+		return nil
+	})
+
+	// act
+	err := r.Handle(context.Background(), Event{
+		ObjectType:     EventObjectTypeActivity,
+		ObjectID:       1360128428,
+		AspectType:     EventAspectTypeUpdate,
+		Updates:        map[string]string{"title": "Morning Run", "type": "Run", "private": "true"},
+		OwnerID:        134815,
+		SubscriptionID: 120475,
+		EventTime:      1516126040,
+	})
+	assert.NoErr(t, err)
+
+	err = r.Handle(context.Background(), Event{
+		ObjectType: EventObjectTypeAthlete,
+		ObjectID:   134815,
+		AspectType: EventAspectTypeUpdate,
+		Updates:    map[string]string{"authorized": "false"},
+		OwnerID:    134815,
+	})
+	assert.NoErr(t, err)
+
+	// assert
+	assert.Eq(t, uint(1360128428), update.ActivityID)
+	assert.Eq(t, uint(134815), update.AthleteID)
+	assert.True(t, update.EventTime.Equal(time.Unix(1516126040, 0)))
+	assert.Eq(t, "Morning Run", *update.Title)
+	assert.Eq(t, ActivityTypeRun, *update.Type)
+	assert.True(t, *update.Private)
+	assert.Nil(t, update.Visibility)
+	assert.Eq(t, []uint{134815}, deauthorized)
+}
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+
+	_ "modernc.org/sqlite"
+)
+
+// Open opens the SQLite database at path with settings suitable for the storages of this package:
+// WAL journal, a busy timeout and immediate write transactions.
+func Open(path string) (*sql.DB, error) {
+	// The path is escaped, SQLite decodes it from the URI and '?' or '#' would start the query or fragment.
+	dsn := &url.URL{
+		Scheme: "file",
+		Opaque: (&url.URL{Path: path}).EscapedPath(),
+		RawQuery: url.Values{
+			"_pragma": {
+				"busy_timeout(5000)",
+				"journal_mode(WAL)",
+				"synchronous(NORMAL)",
+			},
+			"_txlock": {"immediate"},
+		}.Encode(),
+	}
+
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("open database: %w", err)
+	}
+
+	return db, nil
+}
+
+// migrations are applied in order, the database's user_version holds the number of applied ones.
+var migrations = []string{
+	`CREATE TABLE strava_tokens (
+		athlete_id    INTEGER PRIMARY KEY,
+		access_token  TEXT    NOT NULL,
+		token_type    TEXT    NOT NULL DEFAULT '',
+		refresh_token TEXT    NOT NULL DEFAULT '',
+		expiry        INTEGER,
+		scope         TEXT    NOT NULL DEFAULT '',
+		revoked       INTEGER NOT NULL DEFAULT 0,
+		raw           TEXT,
+		created_at    INTEGER NOT NULL,
+		updated_at    INTEGER NOT NULL
+	);
+	CREATE INDEX strava_tokens_expiry_idx ON strava_tokens (expiry);`,
+
+	// 2: tokens are namespaced by client ID, SQLite can't change a primary key in place.
+	`CREATE TABLE strava_tokens_v2 (
+		client_id     TEXT    NOT NULL DEFAULT '',
+		athlete_id    INTEGER NOT NULL,
+		access_token  TEXT    NOT NULL,
+		token_type    TEXT    NOT NULL DEFAULT '',
+		refresh_token TEXT    NOT NULL DEFAULT '',
+		expiry        INTEGER,
+		scope         TEXT    NOT NULL DEFAULT '',
+		revoked       INTEGER NOT NULL DEFAULT 0,
+		raw           TEXT,
+		created_at    INTEGER NOT NULL,
+		updated_at    INTEGER NOT NULL,
+		PRIMARY KEY (client_id, athlete_id)
+	);
+	INSERT INTO strava_tokens_v2 (athlete_id, access_token, token_type, refresh_token, expiry, scope, revoked, raw, created_at, updated_at)
+	SELECT athlete_id, access_token, token_type, refresh_token, expiry, scope, revoked, raw, created_at, updated_at FROM strava_tokens;
+	DROP TABLE strava_tokens;
+	ALTER TABLE strava_tokens_v2 RENAME TO strava_tokens;
+	CREATE INDEX strava_tokens_expiry_idx ON strava_tokens (expiry);`,
+}
+
+func migrate(ctx context.Context, db *sql.DB) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	var version int
+	if err := tx.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("get schema version: %w", err)
+	}
+
+	if version > len(migrations) {
+		return fmt.Errorf("schema version %d is newer than supported %d", version, len(migrations))
+	}
+
+	for i := version; i < len(migrations); i++ {
+		if _, err := tx.ExecContext(ctx, migrations[i]); err != nil {
+			return fmt.Errorf("apply migration %d: %w", i+1, err)
+		}
+	}
+
+	// PRAGMA doesn't support placeholders.
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", len(migrations))); err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+
+	return tx.Commit()
+}
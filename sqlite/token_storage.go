@@ -0,0 +1,182 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/marvell/strava-go"
+)
+
+// TokenStorage stores tokens in a SQLite database.
+type TokenStorage struct {
+	db        *sql.DB
+	namespace string
+
+	// SQLite allows a single writer only, so writes are serialized
+	// instead of failing with SQLITE_BUSY under contention.
+	mu *sync.Mutex
+}
+
+var (
+	_ strava.TokenStorage    = (*TokenStorage)(nil)
+	_ strava.TokenLister     = (*TokenStorage)(nil)
+	_ strava.TokenPager      = (*TokenStorage)(nil)
+	_ strava.TokenDeleter    = (*TokenStorage)(nil)
+	_ strava.TokenNamespacer = (*TokenStorage)(nil)
+)
+
+// NewTokenStorage creates the storage and migrates the database schema. Use Open to open db.
+func NewTokenStorage(db *sql.DB) (*TokenStorage, error) {
+	if err := migrate(context.Background(), db); err != nil {
+		return nil, fmt.Errorf("migrate: %w", err)
+	}
+
+	return &TokenStorage{db: db, mu: &sync.Mutex{}}, nil
+}
+
+func (ts *TokenStorage) ForClient(clientID string) strava.TokenStorage {
+	return &TokenStorage{db: ts.db, namespace: clientID, mu: ts.mu}
+}
+
+const selectTokens = `SELECT athlete_id, access_token, token_type, refresh_token, expiry, scope, revoked, raw FROM strava_tokens`
+
+func (ts *TokenStorage) Get(ctx context.Context, athleteID uint) (*strava.Token, error) {
+	row := ts.db.QueryRowContext(ctx, selectTokens+` WHERE client_id = ? AND athlete_id = ?`, ts.namespace, athleteID)
+
+	token, err := scanToken(row)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, strava.ErrTokenNotFound
+		}
+		return nil, fmt.Errorf("get token: %w", err)
+	}
+
+	return token, nil
+}
+
+func (ts *TokenStorage) Save(ctx context.Context, token *strava.Token) error {
+	var raw sql.NullString
+	if token.Raw != nil {
+		data, err := json.Marshal(token.Raw)
+		if err != nil {
+			return fmt.Errorf("marshal raw: %w", err)
+		}
+		raw = sql.NullString{String: string(data), Valid: true}
+	}
+
+	var t oauth2.Token
+	if token.Token != nil {
+		t = *token.Token
+	}
+
+	var expiry sql.NullInt64
+	if !t.Expiry.IsZero() {
+		expiry = sql.NullInt64{Int64: t.Expiry.UnixNano(), Valid: true}
+	}
+
+	now := time.Now().UnixNano()
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	_, err := ts.db.ExecContext(ctx, `
+		INSERT INTO strava_tokens (client_id, athlete_id, access_token, token_type, refresh_token, expiry, scope, revoked, raw, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (client_id, athlete_id) DO UPDATE SET
+			access_token = excluded.access_token,
+			token_type = excluded.token_type,
+			refresh_token = excluded.refresh_token,
+			expiry = excluded.expiry,
+			scope = excluded.scope,
+			revoked = excluded.revoked,
+			raw = excluded.raw,
+			updated_at = excluded.updated_at`,
+		ts.namespace, token.AthleteID, t.AccessToken, t.TokenType, t.RefreshToken, expiry, token.Scope, token.Revoked, raw, now, now,
+	)
+	if err != nil {
+		return fmt.Errorf("save token: %w", err)
+	}
+
+	return nil
+}
+
+func (ts *TokenStorage) Delete(ctx context.Context, athleteID uint) error {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if _, err := ts.db.ExecContext(ctx, `DELETE FROM strava_tokens WHERE client_id = ? AND athlete_id = ?`, ts.namespace, athleteID); err != nil {
+		return fmt.Errorf("delete token: %w", err)
+	}
+
+	return nil
+}
+
+func (ts *TokenStorage) List(ctx context.Context) ([]*strava.Token, error) {
+	return ts.query(ctx, selectTokens+` WHERE client_id = ? ORDER BY athlete_id`, ts.namespace)
+}
+
+func (ts *TokenStorage) ListAfter(ctx context.Context, afterID uint, limit int) ([]*strava.Token, error) {
+	return ts.query(ctx, selectTokens+` WHERE client_id = ? AND athlete_id > ? ORDER BY athlete_id LIMIT ?`, ts.namespace, afterID, limit)
+}
+
+// ListExpiring returns not revoked tokens expiring before the given time.
+func (ts *TokenStorage) ListExpiring(ctx context.Context, before time.Time) ([]*strava.Token, error) {
+	return ts.query(ctx, selectTokens+` WHERE client_id = ? AND revoked = 0 AND expiry < ? ORDER BY expiry`, ts.namespace, before.UnixNano())
+}
+
+func (ts *TokenStorage) query(ctx context.Context, query string, args ...any) ([]*strava.Token, error) {
+	rows, err := ts.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*strava.Token
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("list tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+func scanToken(row interface{ Scan(dest ...any) error }) (*strava.Token, error) {
+	var (
+		t      strava.Token
+		ot     oauth2.Token
+		expiry sql.NullInt64
+		raw    sql.NullString
+	)
+
+	err := row.Scan(&t.AthleteID, &ot.AccessToken, &ot.TokenType, &ot.RefreshToken, &expiry, &t.Scope, &t.Revoked, &raw)
+	if err != nil {
+		return nil, err
+	}
+
+	if expiry.Valid {
+		ot.Expiry = time.Unix(0, expiry.Int64)
+	}
+
+	if raw.Valid {
+		if err := json.Unmarshal([]byte(raw.String), &t.Raw); err != nil {
+			return nil, fmt.Errorf("unmarshal raw: %w", err)
+		}
+	}
+
+	t.Token = &ot
+	return &t, nil
+}
@@ -0,0 +1,103 @@
+package sqlite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/storagetest"
+)
+
+func TestTokenStorage(t *testing.T) {
+	storagetest.RunTokenStorageSuite(t, func(t *testing.T) strava.TokenStorage {
+		return newTestTokenStorage(t)
+	})
+}
+
+func newTestTokenStorage(t *testing.T) *TokenStorage {
+	db, err := Open(filepath.Join(t.TempDir(), "tokens.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	ts, err := NewTokenStorage(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ts
+}
+
+func TestTokenStorage_ConcurrentSave(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	ts := newTestTokenStorage(t)
+
+	expiry := time.Now().Add(time.Hour)
+
+	// act
+	var wg sync.WaitGroup
+	for i := 1; i <= 20; i++ {
+		wg.Add(1)
+		go func(athleteID uint) {
+			defer wg.Done()
+
+			err := ts.Save(ctx, &strava.Token{
+				Token:     &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: expiry},
+				AthleteID: athleteID,
+				Scope:     "read",
+				Raw:       map[string]any{"expires_at": float64(expiry.Unix())},
+			})
+			assert.NoErr(t, err)
+		}(uint(i % 5))
+	}
+	wg.Wait()
+
+	// assert
+	tokens, err := ts.List(ctx)
+	assert.NoErr(t, err)
+	assert.Len(t, tokens, 5)
+
+	got, err := ts.Get(ctx, 1)
+	assert.NoErr(t, err)
+	assert.True(t, got.Expiry.Equal(expiry))
+	assert.Eq(t, float64(expiry.Unix()), got.Raw["expires_at"])
+
+	expiring, err := ts.ListExpiring(ctx, expiry.Add(time.Second))
+	assert.NoErr(t, err)
+	assert.Len(t, expiring, 5)
+
+	_, err = ts.Get(ctx, 42)
+	assert.ErrIs(t, err, strava.ErrTokenNotFound)
+}
+
+func TestOpen_SpecialPath(t *testing.T) {
+	// arrange
+	dir := filepath.Join(t.TempDir(), "tokens?mode=ro#100% sure")
+	assert.NoErr(t, os.Mkdir(dir, 0755))
+	path := filepath.Join(dir, "tokens.db")
+
+	// act
+	db, err := Open(path)
+	assert.NoErr(t, err)
+	t.Cleanup(func() { _ = db.Close() })
+
+	_, err = NewTokenStorage(db)
+
+	// assert
+	assert.NoErr(t, err)
+	_, err = os.Stat(path)
+	assert.NoErr(t, err)
+
+	var journalMode string
+	assert.NoErr(t, db.QueryRow("PRAGMA journal_mode").Scan(&journalMode))
+	assert.Eq(t, "wal", journalMode)
+}
@@ -0,0 +1,59 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// ActivityCursorStoreFactory returns an empty store for a single test.
+type ActivityCursorStoreFactory func(t *testing.T) strava.ActivityCursorStore
+
+// RunActivityCursorStoreSuite runs the conformance tests against stores created by factory.
+func RunActivityCursorStoreSuite(t *testing.T, factory ActivityCursorStoreFactory) {
+	t.Run("RoundTrip", func(t *testing.T) { testActivityCursorRoundTrip(t, factory(t)) })
+	t.Run("NotFound", func(t *testing.T) { testActivityCursorNotFound(t, factory(t)) })
+}
+
+func testActivityCursorRoundTrip(t *testing.T, s strava.ActivityCursorStore) {
+	ctx := context.Background()
+
+	// Start dates have second precision, like Strava's.
+	start := time.Date(2024, 5, 31, 6, 30, 0, 0, time.UTC)
+	cursors := map[uint]*strava.ActivityCursor{
+		7: {AthleteID: 7, StartDate: start, ActivityID: 11469384921},
+		8: {AthleteID: 8, StartDate: start.Add(-time.Hour), ActivityID: 11469384922},
+	}
+
+	for _, c := range cursors {
+		if err := s.SaveActivityCursor(ctx, c); err != nil {
+			t.Fatalf("SaveActivityCursor: %v", err)
+		}
+	}
+
+	// Advance the cursor of an athlete.
+	cursors[7] = &strava.ActivityCursor{AthleteID: 7, StartDate: start.Add(time.Hour), ActivityID: 11469384923}
+	if err := s.SaveActivityCursor(ctx, cursors[7]); err != nil {
+		t.Fatalf("SaveActivityCursor: %v", err)
+	}
+
+	for athleteID, want := range cursors {
+		got, err := s.GetActivityCursor(ctx, athleteID)
+		if err != nil {
+			t.Fatalf("GetActivityCursor: %v", err)
+		}
+		if got.AthleteID != want.AthleteID || !got.StartDate.Equal(want.StartDate) || got.ActivityID != want.ActivityID {
+			t.Fatalf("cursor of %d mismatch:\nwant %+v\ngot  %+v", athleteID, want, got)
+		}
+	}
+}
+
+func testActivityCursorNotFound(t *testing.T, s strava.ActivityCursorStore) {
+	_, err := s.GetActivityCursor(context.Background(), 404)
+	if !errors.Is(err, strava.ErrActivityCursorNotFound) {
+		t.Fatalf("GetActivityCursor of a missing athlete: want ErrActivityCursorNotFound, got %v", err)
+	}
+}
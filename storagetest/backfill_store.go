@@ -0,0 +1,106 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// BackfillStoreFactory returns an empty store for a single test.
+type BackfillStoreFactory func(t *testing.T) strava.BackfillStore
+
+// RunBackfillStoreSuite runs the conformance tests against stores created by factory.
+func RunBackfillStoreSuite(t *testing.T, factory BackfillStoreFactory) {
+	t.Run("RoundTrip", func(t *testing.T) { testBackfillRoundTrip(t, factory(t)) })
+	t.Run("NotFound", func(t *testing.T) { testBackfillNotFound(t, factory(t)) })
+}
+
+// NewBackfill returns a backfill with all fields set. Times have microsecond precision,
+// the least precision a store has to keep.
+func NewBackfill(athleteID uint) *strava.Backfill {
+	to := time.Date(2024, 5, 31, 6, 30, 0, 123456000, time.UTC)
+	return &strava.Backfill{
+		AthleteID:  athleteID,
+		From:       time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:         to,
+		Window:     90 * 24 * time.Hour,
+		Before:     to.Add(-180 * 24 * time.Hour),
+		Activities: 123,
+		Requests:   4,
+		LastError:  "API error (status code 500)",
+		Abandoned:  true,
+		UpdatedAt:  to.Add(time.Minute),
+	}
+}
+
+func assertBackfillEqual(t *testing.T, want, got *strava.Backfill) {
+	t.Helper()
+
+	if got.AthleteID != want.AthleteID || !got.From.Equal(want.From) || !got.To.Equal(want.To) ||
+		got.Window != want.Window || !got.Before.Equal(want.Before) || got.Activities != want.Activities ||
+		got.Requests != want.Requests || got.LastError != want.LastError || got.Abandoned != want.Abandoned ||
+		!got.UpdatedAt.Equal(want.UpdatedAt) {
+		t.Fatalf("backfill of %d mismatch:\nwant %+v\ngot  %+v", want.AthleteID, want, got)
+	}
+}
+
+func testBackfillRoundTrip(t *testing.T, s strava.BackfillStore) {
+	ctx := context.Background()
+
+	backfills := map[uint]*strava.Backfill{7: NewBackfill(7), 8: NewBackfill(8)}
+	for _, b := range backfills {
+		if err := s.SaveBackfill(ctx, b); err != nil {
+			t.Fatalf("SaveBackfill: %v", err)
+		}
+	}
+
+	// Progress the backfill of an athlete.
+	b := NewBackfill(7)
+	b.Before = b.From
+	b.Activities = 456
+	b.LastError = ""
+	b.Abandoned = false
+	backfills[7] = b
+	if err := s.SaveBackfill(ctx, b); err != nil {
+		t.Fatalf("SaveBackfill: %v", err)
+	}
+
+	for _, want := range backfills {
+		got, err := s.GetBackfill(ctx, want.AthleteID)
+		if err != nil {
+			t.Fatalf("GetBackfill: %v", err)
+		}
+		assertBackfillEqual(t, want, got)
+	}
+
+	list, err := s.ListBackfills(ctx)
+	if err != nil {
+		t.Fatalf("ListBackfills: %v", err)
+	}
+	if len(list) != len(backfills) {
+		t.Fatalf("ListBackfills: want %d backfills, got %d", len(backfills), len(list))
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].AthleteID < list[j].AthleteID })
+	assertBackfillEqual(t, backfills[7], list[0])
+	assertBackfillEqual(t, backfills[8], list[1])
+}
+
+func testBackfillNotFound(t *testing.T, s strava.BackfillStore) {
+	_, err := s.GetBackfill(context.Background(), 404)
+	if !errors.Is(err, strava.ErrBackfillNotFound) {
+		t.Fatalf("GetBackfill of a missing athlete: want ErrBackfillNotFound, got %v", err)
+	}
+
+	list, err := s.ListBackfills(context.Background())
+	if err != nil {
+		t.Fatalf("ListBackfills: %v", err)
+	}
+	if len(list) != 0 {
+		t.Fatalf("ListBackfills of an empty store: want no backfills, got %d", len(list))
+	}
+}
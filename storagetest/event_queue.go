@@ -0,0 +1,270 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// EventQueueFactory returns an empty queue for a single test.
+type EventQueueFactory func(t *testing.T) strava.EventQueue
+
+// RunEventQueueSuite runs the conformance tests against queues created by factory.
+func RunEventQueueSuite(t *testing.T, factory EventQueueFactory) {
+	t.Run("RoundTrip", func(t *testing.T) { testEventRoundTrip(t, factory(t)) })
+	t.Run("ClaimOrder", func(t *testing.T) { testClaimOrder(t, factory(t)) })
+	t.Run("ClaimTimeout", func(t *testing.T) { testClaimTimeout(t, factory(t)) })
+	t.Run("Ack", func(t *testing.T) { testAck(t, factory(t)) })
+	t.Run("Retry", func(t *testing.T) { testRetry(t, factory(t)) })
+	t.Run("DeadLetter", func(t *testing.T) { testDeadLetter(t, factory(t)) })
+	t.Run("StaleLease", func(t *testing.T) { testStaleLease(t, factory(t)) })
+	t.Run("ConcurrentClaims", func(t *testing.T) { testConcurrentClaims(t, factory(t)) })
+}
+
+// NewEvent returns an activity update event with all fields set.
+func NewEvent(objectID uint) strava.Event {
+	return strava.Event{
+		ObjectType:     strava.EventObjectTypeActivity,
+		ObjectID:       objectID,
+		AspectType:     strava.EventAspectTypeUpdate,
+		Updates:        map[string]string{"title": "Morning Ride", "private": "true"},
+		OwnerID:        134815,
+		SubscriptionID: 120475,
+		EventTime:      1516126040,
+	}
+}
+
+func mustEnqueue(t *testing.T, q strava.EventQueue, events ...strava.Event) {
+	t.Helper()
+
+	for _, e := range events {
+		if err := q.Enqueue(context.Background(), e); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+}
+
+func mustClaim(t *testing.T, q strava.EventQueue, timeout time.Duration) *strava.QueuedEvent {
+	t.Helper()
+
+	e, err := q.Claim(context.Background(), timeout)
+	if err != nil {
+		t.Fatalf("Claim: %v", err)
+	}
+
+	return e
+}
+
+func assertNoEventDue(t *testing.T, q strava.EventQueue) {
+	t.Helper()
+
+	if e, err := q.Claim(context.Background(), time.Minute); !errors.Is(err, strava.ErrNoEventDue) {
+		t.Fatalf("Claim: want ErrNoEventDue, got %+v, %v", e, err)
+	}
+}
+
+func testEventRoundTrip(t *testing.T, q strava.EventQueue) {
+	want := NewEvent(1)
+	mustEnqueue(t, q, want)
+
+	got := mustClaim(t, q, time.Minute)
+	if !reflect.DeepEqual(want, got.Event) {
+		t.Fatalf("event mismatch:\nwant %+v\ngot  %+v", want, got.Event)
+	}
+	if got.Attempts != 1 {
+		t.Fatalf("Attempts: want 1, got %d", got.Attempts)
+	}
+	if got.EnqueuedAt.IsZero() {
+		t.Fatalf("EnqueuedAt is not set")
+	}
+}
+
+func testClaimOrder(t *testing.T, q strava.EventQueue) {
+	mustEnqueue(t, q, NewEvent(1), NewEvent(2))
+
+	if e := mustClaim(t, q, time.Minute); e.Event.ObjectID != 1 {
+		t.Fatalf("first claim: want event 1, got %d", e.Event.ObjectID)
+	}
+	if e := mustClaim(t, q, time.Minute); e.Event.ObjectID != 2 {
+		t.Fatalf("second claim: want event 2, got %d", e.Event.ObjectID)
+	}
+
+	assertNoEventDue(t, q)
+}
+
+func testClaimTimeout(t *testing.T, q strava.EventQueue) {
+	mustEnqueue(t, q, NewEvent(1))
+
+	first := mustClaim(t, q, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+
+	second := mustClaim(t, q, time.Minute)
+	if second.ID != first.ID || second.Attempts != 2 {
+		t.Fatalf("claim after timeout: want event %d with 2 attempts, got %d with %d", first.ID, second.ID, second.Attempts)
+	}
+}
+
+func testAck(t *testing.T, q strava.EventQueue) {
+	ctx := context.Background()
+	mustEnqueue(t, q, NewEvent(1))
+
+	e := mustClaim(t, q, time.Millisecond)
+	if err := q.Ack(ctx, e.ID, e.Attempts); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	assertNoEventDue(t, q)
+
+	if err := q.Ack(ctx, e.ID, e.Attempts); !errors.Is(err, strava.ErrEventNotFound) {
+		t.Fatalf("Ack of a missing event: want ErrEventNotFound, got %v", err)
+	}
+}
+
+func testRetry(t *testing.T, q strava.EventQueue) {
+	ctx := context.Background()
+	mustEnqueue(t, q, NewEvent(1))
+
+	e := mustClaim(t, q, time.Minute)
+	if err := q.Retry(ctx, e.ID, e.Attempts, time.Now().Add(time.Hour), "boom"); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+	assertNoEventDue(t, q)
+
+	if err := q.Retry(ctx, e.ID, e.Attempts, time.Now().Add(-time.Second), "boom again"); err != nil {
+		t.Fatalf("Retry: %v", err)
+	}
+
+	got := mustClaim(t, q, time.Minute)
+	if got.ID != e.ID || got.Attempts != 2 || got.LastError != "boom again" {
+		t.Fatalf("claim after retry: want event %d with 2 attempts and last error, got %+v", e.ID, got)
+	}
+
+	if err := q.Retry(ctx, 404, 1, time.Now(), ""); !errors.Is(err, strava.ErrEventNotFound) {
+		t.Fatalf("Retry of a missing event: want ErrEventNotFound, got %v", err)
+	}
+}
+
+func testDeadLetter(t *testing.T, q strava.EventQueue) {
+	ctx := context.Background()
+	mustEnqueue(t, q, NewEvent(1), NewEvent(2))
+
+	e := mustClaim(t, q, time.Minute)
+	if err := q.DeadLetter(ctx, e.ID, e.Attempts, "gave up"); err != nil {
+		t.Fatalf("DeadLetter: %v", err)
+	}
+
+	dead, err := q.DeadLetters(ctx)
+	if err != nil {
+		t.Fatalf("DeadLetters: %v", err)
+	}
+	if len(dead) != 1 || dead[0].ID != e.ID || dead[0].LastError != "gave up" || dead[0].Event.ObjectID != 1 {
+		t.Fatalf("DeadLetters: want event %d, got %+v", e.ID, dead)
+	}
+
+	// The dead letter is no longer claimed.
+	if got := mustClaim(t, q, time.Minute); got.Event.ObjectID != 2 {
+		t.Fatalf("claim: want event 2, got %d", got.Event.ObjectID)
+	}
+	assertNoEventDue(t, q)
+
+	if err := q.Replay(ctx, e.ID); err != nil {
+		t.Fatalf("Replay: %v", err)
+	}
+
+	got := mustClaim(t, q, time.Minute)
+	if got.ID != e.ID || got.Attempts != 1 {
+		t.Fatalf("claim after replay: want event %d with 1 attempt, got %+v", e.ID, got)
+	}
+
+	if dead, err := q.DeadLetters(ctx); err != nil || len(dead) != 0 {
+		t.Fatalf("DeadLetters after replay: want none, got %+v, %v", dead, err)
+	}
+
+	if err := q.Replay(ctx, e.ID); !errors.Is(err, strava.ErrEventNotFound) {
+		t.Fatalf("Replay of a missing dead letter: want ErrEventNotFound, got %v", err)
+	}
+}
+
+// testStaleLease checks that a worker whose claim timed out can't change the event claimed by another one.
+func testStaleLease(t *testing.T, q strava.EventQueue) {
+	ctx := context.Background()
+	mustEnqueue(t, q, NewEvent(1))
+
+	stale := mustClaim(t, q, 10*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	current := mustClaim(t, q, time.Minute)
+
+	if err := q.Retry(ctx, stale.ID, stale.Attempts, time.Now(), "late"); !errors.Is(err, strava.ErrEventNotFound) {
+		t.Fatalf("Retry with a stale lease: want ErrEventNotFound, got %v", err)
+	}
+	if err := q.Ack(ctx, stale.ID, stale.Attempts); !errors.Is(err, strava.ErrEventNotFound) {
+		t.Fatalf("Ack with a stale lease: want ErrEventNotFound, got %v", err)
+	}
+	if err := q.DeadLetter(ctx, stale.ID, stale.Attempts, "late"); !errors.Is(err, strava.ErrEventNotFound) {
+		t.Fatalf("DeadLetter with a stale lease: want ErrEventNotFound, got %v", err)
+	}
+
+	// The current claim is kept: the event isn't due, and its lease still works.
+	assertNoEventDue(t, q)
+	if err := q.Ack(ctx, current.ID, current.Attempts); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+}
+
+func testConcurrentClaims(t *testing.T, q strava.EventQueue) {
+	const n = 20
+
+	for i := 1; i <= n; i++ {
+		mustEnqueue(t, q, NewEvent(uint(i)))
+	}
+
+	var (
+		mu   sync.Mutex
+		seen = make(map[uint]int)
+		wg   sync.WaitGroup
+	)
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			ctx := context.Background()
+			for {
+				e, err := q.Claim(ctx, time.Minute)
+				if errors.Is(err, strava.ErrNoEventDue) {
+					return
+				}
+				if err != nil {
+					t.Errorf("Claim: %v", err)
+					return
+				}
+
+				mu.Lock()
+				seen[e.Event.ObjectID]++
+				mu.Unlock()
+
+				if err := q.Ack(ctx, e.ID, e.Attempts); err != nil {
+					t.Errorf("Ack: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != n {
+		t.Fatalf("want %d events claimed, got %d", n, len(seen))
+	}
+	for id, count := range seen {
+		if count != 1 {
+			t.Fatalf("event %d claimed %d times", id, count)
+		}
+	}
+}
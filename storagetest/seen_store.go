@@ -0,0 +1,64 @@
+package storagetest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// SeenStoreFactory returns an empty store for a single test.
+type SeenStoreFactory func(t *testing.T) strava.SeenStore
+
+// RunSeenStoreSuite runs the conformance tests against stores created by factory.
+func RunSeenStoreSuite(t *testing.T, factory SeenStoreFactory) {
+	t.Run("MarkSeen", func(t *testing.T) { testMarkSeen(t, factory(t)) })
+	t.Run("Expiry", func(t *testing.T) { testSeenExpiry(t, factory(t)) })
+	t.Run("Forget", func(t *testing.T) { testForget(t, factory(t)) })
+}
+
+func assertSeen(t *testing.T, s strava.SeenStore, key string, ttl time.Duration, want bool) {
+	t.Helper()
+
+	seen, err := s.MarkSeen(context.Background(), key, ttl)
+	if err != nil {
+		t.Fatalf("MarkSeen: %v", err)
+	}
+	if seen != want {
+		t.Fatalf("MarkSeen(%q): want %v, got %v", key, want, seen)
+	}
+}
+
+func testMarkSeen(t *testing.T, s strava.SeenStore) {
+	a := NewEvent(1).IdempotencyKey()
+	b := NewEvent(2).IdempotencyKey()
+
+	assertSeen(t, s, a, time.Hour, false)
+	assertSeen(t, s, a, time.Hour, true)
+	assertSeen(t, s, b, time.Hour, false)
+	assertSeen(t, s, a, time.Hour, true)
+}
+
+func testSeenExpiry(t *testing.T, s strava.SeenStore) {
+	key := NewEvent(1).IdempotencyKey()
+
+	assertSeen(t, s, key, 20*time.Millisecond, false)
+	time.Sleep(50 * time.Millisecond)
+	assertSeen(t, s, key, time.Hour, false)
+	assertSeen(t, s, key, time.Hour, true)
+}
+
+func testForget(t *testing.T, s strava.SeenStore) {
+	key := NewEvent(1).IdempotencyKey()
+
+	assertSeen(t, s, key, time.Hour, false)
+	if err := s.Forget(context.Background(), key); err != nil {
+		t.Fatalf("Forget: %v", err)
+	}
+	assertSeen(t, s, key, time.Hour, false)
+
+	if err := s.Forget(context.Background(), "missing"); err != nil {
+		t.Fatalf("Forget of a missing key: %v", err)
+	}
+}
@@ -0,0 +1,384 @@
+// Package storagetest provides conformance test suites for the storage interfaces of the strava package.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/marvell/strava-go"
+)
+
+// Factory returns an empty storage for a single test.
+type Factory func(t *testing.T) strava.TokenStorage
+
+// RunTokenStorageSuite runs the conformance tests against storages created by factory.
+// Tests for strava.TokenLister, strava.TokenPager and strava.TokenDeleter run only if the storage implements them.
+func RunTokenStorageSuite(t *testing.T, factory Factory) {
+	t.Run("RoundTrip", func(t *testing.T) { testRoundTrip(t, factory(t)) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, factory(t)) })
+	t.Run("Overwrite", func(t *testing.T) { testOverwrite(t, factory(t)) })
+	t.Run("Isolation", func(t *testing.T) { testIsolation(t, factory(t)) })
+	t.Run("ConcurrentSaves", func(t *testing.T) { testConcurrentSaves(t, factory(t)) })
+	t.Run("ContextCancellation", func(t *testing.T) { testContextCancellation(t, factory(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, factory(t)) })
+	t.Run("List", func(t *testing.T) { testList(t, factory(t)) })
+	t.Run("ListAfter", func(t *testing.T) { testListAfter(t, factory(t)) })
+	t.Run("Namespaces", func(t *testing.T) { testNamespaces(t, factory(t)) })
+}
+
+// NewToken returns a token with all fields set. Expiry has microsecond precision,
+// the least precision a storage has to keep.
+func NewToken(athleteID uint) *strava.Token {
+	return &strava.Token{
+		Token: &oauth2.Token{
+			AccessToken:  fmt.Sprintf("access-%d", athleteID),
+			TokenType:    "Bearer",
+			RefreshToken: fmt.Sprintf("refresh-%d", athleteID),
+			Expiry:       time.Date(2024, 5, 17, 10, 30, 15, 123456000, time.FixedZone("CEST", 2*60*60)),
+		},
+		AthleteID: athleteID,
+		Scope:     "read,activity:read_all",
+		Raw: map[string]any{
+			"expires_at": float64(1715934615),
+			"athlete": map[string]any{
+				"id":        float64(athleteID),
+				"firstname": "Jane",
+			},
+		},
+	}
+}
+
+func testRoundTrip(t *testing.T, ts strava.TokenStorage) {
+	ctx := context.Background()
+
+	for _, want := range []*strava.Token{NewToken(1), revokedToken(2)} {
+		if err := ts.Save(ctx, want); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+
+		got, err := ts.Get(ctx, want.AthleteID)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+
+		assertTokenEqual(t, want, got)
+	}
+}
+
+func testNotFound(t *testing.T, ts strava.TokenStorage) {
+	_, err := ts.Get(context.Background(), 404)
+	if !errors.Is(err, strava.ErrTokenNotFound) {
+		t.Fatalf("Get of a missing token: want ErrTokenNotFound, got %v", err)
+	}
+}
+
+func testOverwrite(t *testing.T, ts strava.TokenStorage) {
+	ctx := context.Background()
+
+	if err := ts.Save(ctx, NewToken(1)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	want := NewToken(1)
+	want.AccessToken = "access-new"
+	want.Expiry = want.Expiry.Add(6 * time.Hour)
+	want.Scope = "read"
+	if err := ts.Save(ctx, want); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := ts.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertTokenEqual(t, want, got)
+
+	if lister, ok := ts.(strava.TokenLister); ok {
+		tokens, err := lister.List(ctx)
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if len(tokens) != 1 {
+			t.Fatalf("List after overwrite: want 1 token, got %d", len(tokens))
+		}
+	}
+}
+
+func testIsolation(t *testing.T, ts strava.TokenStorage) {
+	ctx := context.Background()
+
+	saved := NewToken(1)
+	if err := ts.Save(ctx, saved); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	saved.AccessToken = "mutated after save"
+	saved.Scope = "mutated after save"
+
+	got, err := ts.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	got.AccessToken = "mutated after get"
+	got.Revoked = true
+
+	got, err = ts.Get(ctx, 1)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	assertTokenEqual(t, NewToken(1), got)
+}
+
+func testConcurrentSaves(t *testing.T, ts strava.TokenStorage) {
+	ctx := context.Background()
+
+	const athletes, savesPerAthlete = 5, 10
+
+	var wg sync.WaitGroup
+	errs := make(chan error, athletes*savesPerAthlete)
+	for i := 0; i < athletes*savesPerAthlete; i++ {
+		wg.Add(1)
+		go func(athleteID uint) {
+			defer wg.Done()
+			errs <- ts.Save(ctx, NewToken(athleteID))
+		}(uint(i%athletes + 1))
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("concurrent Save: %v", err)
+		}
+	}
+
+	for i := uint(1); i <= athletes; i++ {
+		got, err := ts.Get(ctx, i)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		assertTokenEqual(t, NewToken(i), got)
+	}
+}
+
+func testContextCancellation(t *testing.T, ts strava.TokenStorage) {
+	if err := ts.Save(context.Background(), NewToken(1)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := ts.Get(ctx, 1); !errors.Is(err, context.Canceled) {
+		t.Errorf("Get with canceled context: want context.Canceled, got %v", err)
+	}
+
+	if err := ts.Save(ctx, NewToken(2)); !errors.Is(err, context.Canceled) {
+		t.Errorf("Save with canceled context: want context.Canceled, got %v", err)
+	}
+
+	if _, err := ts.Get(context.Background(), 2); !errors.Is(err, strava.ErrTokenNotFound) {
+		t.Errorf("Get of a token saved with canceled context: want ErrTokenNotFound, got %v", err)
+	}
+}
+
+func testDelete(t *testing.T, ts strava.TokenStorage) {
+	deleter, ok := ts.(strava.TokenDeleter)
+	if !ok {
+		t.Skipf("%T doesn't implement TokenDeleter", ts)
+	}
+
+	ctx := context.Background()
+
+	if err := ts.Save(ctx, NewToken(1)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	if err := deleter.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+
+	if _, err := ts.Get(ctx, 1); !errors.Is(err, strava.ErrTokenNotFound) {
+		t.Fatalf("Get of a deleted token: want ErrTokenNotFound, got %v", err)
+	}
+
+	if err := deleter.Delete(ctx, 1); err != nil {
+		t.Fatalf("Delete of a missing token: %v", err)
+	}
+}
+
+func testList(t *testing.T, ts strava.TokenStorage) {
+	lister, ok := ts.(strava.TokenLister)
+	if !ok {
+		t.Skipf("%T doesn't implement TokenLister", ts)
+	}
+
+	ctx := context.Background()
+
+	tokens, err := lister.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(tokens) != 0 {
+		t.Fatalf("List of an empty storage: want no tokens, got %d", len(tokens))
+	}
+
+	want := map[uint]*strava.Token{1: NewToken(1), 2: NewToken(2), 3: revokedToken(3)}
+	for _, token := range want {
+		if err := ts.Save(ctx, token); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	tokens, err = lister.List(ctx)
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(tokens) != len(want) {
+		t.Fatalf("List: want %d tokens, got %d", len(want), len(tokens))
+	}
+
+	for _, got := range tokens {
+		w, ok := want[got.AthleteID]
+		if !ok {
+			t.Fatalf("List: unexpected athlete %d", got.AthleteID)
+		}
+		assertTokenEqual(t, w, got)
+	}
+}
+
+func testListAfter(t *testing.T, ts strava.TokenStorage) {
+	pager, ok := ts.(strava.TokenPager)
+	if !ok {
+		t.Skipf("%T doesn't implement TokenPager", ts)
+	}
+
+	ctx := context.Background()
+
+	for _, athleteID := range []uint{5, 1, 12, 3, 8} {
+		if err := ts.Save(ctx, NewToken(athleteID)); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	var got []uint
+	var afterID uint
+	for {
+		tokens, err := pager.ListAfter(ctx, afterID, 2)
+		if err != nil {
+			t.Fatalf("ListAfter: %v", err)
+		}
+		if len(tokens) > 2 {
+			t.Fatalf("ListAfter with limit 2: got %d tokens", len(tokens))
+		}
+		if len(tokens) == 0 {
+			break
+		}
+
+		for _, token := range tokens {
+			assertTokenEqual(t, NewToken(token.AthleteID), token)
+			got = append(got, token.AthleteID)
+		}
+		afterID = tokens[len(tokens)-1].AthleteID
+	}
+
+	if want := []uint{1, 3, 5, 8, 12}; !reflect.DeepEqual(want, got) {
+		t.Fatalf("ListAfter pages: want athletes %v, got %v", want, got)
+	}
+}
+
+func testNamespaces(t *testing.T, ts strava.TokenStorage) {
+	if _, ok := ts.(strava.TokenNamespacer); !ok {
+		t.Skipf("%T doesn't implement TokenNamespacer", ts)
+	}
+
+	ctx := context.Background()
+
+	consumer := strava.ForClient(ts, "1001")
+	coaching := strava.ForClient(ts, "1002")
+
+	want := map[strava.TokenStorage]*strava.Token{
+		ts:       NewToken(1),
+		consumer: NewToken(1),
+		coaching: NewToken(1),
+	}
+	want[consumer].AccessToken = "access-consumer"
+	want[coaching].AccessToken = "access-coaching"
+
+	for s, token := range want {
+		if err := s.Save(ctx, token); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+	}
+
+	for s, w := range want {
+		got, err := s.Get(ctx, 1)
+		if err != nil {
+			t.Fatalf("Get: %v", err)
+		}
+		assertTokenEqual(t, w, got)
+
+		if lister, ok := s.(strava.TokenLister); ok {
+			tokens, err := lister.List(ctx)
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			if len(tokens) != 1 {
+				t.Fatalf("List of a namespace: want 1 token, got %d", len(tokens))
+			}
+		}
+	}
+
+	if deleter, ok := consumer.(strava.TokenDeleter); ok {
+		if err := deleter.Delete(ctx, 1); err != nil {
+			t.Fatalf("Delete: %v", err)
+		}
+
+		if _, err := coaching.Get(ctx, 1); err != nil {
+			t.Fatalf("Get after Delete in another namespace: %v", err)
+		}
+	}
+}
+
+func revokedToken(athleteID uint) *strava.Token {
+	token := NewToken(athleteID)
+	token.Revoked = true
+	token.Raw = nil
+	return token
+}
+
+func assertTokenEqual(t *testing.T, want, got *strava.Token) {
+	t.Helper()
+
+	if got == nil || got.Token == nil {
+		t.Fatalf("athlete %d: got no token", want.AthleteID)
+	}
+
+	checks := []struct {
+		field     string
+		want, got any
+	}{
+		{"AthleteID", want.AthleteID, got.AthleteID},
+		{"AccessToken", want.AccessToken, got.AccessToken},
+		{"TokenType", want.TokenType, got.TokenType},
+		{"RefreshToken", want.RefreshToken, got.RefreshToken},
+		{"Scope", want.Scope, got.Scope},
+		{"Revoked", want.Revoked, got.Revoked},
+		{"Raw", want.Raw, got.Raw},
+	}
+	for _, c := range checks {
+		if !reflect.DeepEqual(c.want, c.got) {
+			t.Errorf("athlete %d: %s: want %v, got %v", want.AthleteID, c.field, c.want, c.got)
+		}
+	}
+
+	if !want.Expiry.Equal(got.Expiry) {
+		t.Errorf("athlete %d: Expiry: want %v, got %v", want.AthleteID, want.Expiry, got.Expiry)
+	}
+}
@@ -0,0 +1,57 @@
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/marvell/strava-go"
+)
+
+// WebhookStateStoreFactory returns an empty store for a single test.
+type WebhookStateStoreFactory func(t *testing.T) strava.WebhookStateStore
+
+// RunWebhookStateStoreSuite runs the conformance tests against stores created by factory.
+func RunWebhookStateStoreSuite(t *testing.T, factory WebhookStateStoreFactory) {
+	t.Run("RoundTrip", func(t *testing.T) { testWebhookStateRoundTrip(t, factory(t)) })
+	t.Run("NotFound", func(t *testing.T) { testWebhookStateNotFound(t, factory(t)) })
+}
+
+func testWebhookStateRoundTrip(t *testing.T, s strava.WebhookStateStore) {
+	ctx := context.Background()
+
+	states := map[string]*strava.WebhookState{
+		"1234":  {SubscriptionID: 120475, CallbackURL: "https://example.com/callback", VerifyToken: "secret"},
+		"other": {SubscriptionID: 120476, CallbackURL: "https://example.com/other"},
+	}
+
+	for clientID, state := range states {
+		if err := s.SaveWebhookState(ctx, clientID, state); err != nil {
+			t.Fatalf("SaveWebhookState: %v", err)
+		}
+	}
+
+	// Overwrite the state of a client.
+	states["1234"] = &strava.WebhookState{SubscriptionID: 120477, CallbackURL: "https://example.com/new"}
+	if err := s.SaveWebhookState(ctx, "1234", states["1234"]); err != nil {
+		t.Fatalf("SaveWebhookState: %v", err)
+	}
+
+	for clientID, want := range states {
+		got, err := s.GetWebhookState(ctx, clientID)
+		if err != nil {
+			t.Fatalf("GetWebhookState: %v", err)
+		}
+		if !reflect.DeepEqual(want, got) {
+			t.Fatalf("state of %s mismatch:\nwant %+v\ngot  %+v", clientID, want, got)
+		}
+	}
+}
+
+func testWebhookStateNotFound(t *testing.T, s strava.WebhookStateStore) {
+	_, err := s.GetWebhookState(context.Background(), "missing")
+	if !errors.Is(err, strava.ErrWebhookStateNotFound) {
+		t.Fatalf("GetWebhookState of a missing client: want ErrWebhookStateNotFound, got %v", err)
+	}
+}
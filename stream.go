@@ -0,0 +1,80 @@
+package strava
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// StreamType is the kind of samples in an activity stream.
+type StreamType string
+
+const (
+	StreamTypeTime           StreamType = "time"
+	StreamTypeDistance       StreamType = "distance"
+	StreamTypeLatLng         StreamType = "latlng"
+	StreamTypeAltitude       StreamType = "altitude"
+	StreamTypeVelocitySmooth StreamType = "velocity_smooth"
+	StreamTypeHeartrate      StreamType = "heartrate"
+	StreamTypeCadence        StreamType = "cadence"
+	StreamTypeWatts          StreamType = "watts"
+	StreamTypeTemp           StreamType = "temp"
+	StreamTypeMoving         StreamType = "moving"
+	StreamTypeGradeSmooth    StreamType = "grade_smooth"
+)
+
+// Stream is a series of samples of an activity.
+type Stream[T any] struct {
+	OriginalSize int    `json:"original_size"`
+	Resolution   string `json:"resolution"`
+	SeriesType   string `json:"series_type"`
+	Data         []T    `json:"data"`
+}
+
+// StreamSet holds the requested streams of an activity. Streams the activity doesn't have are nil.
+type StreamSet struct {
+	Time           *Stream[int]        `json:"time,omitempty"`
+	Distance       *Stream[float64]    `json:"distance,omitempty"`
+	LatLng         *Stream[[2]float64] `json:"latlng,omitempty"`
+	Altitude       *Stream[float64]    `json:"altitude,omitempty"`
+	VelocitySmooth *Stream[float64]    `json:"velocity_smooth,omitempty"`
+	Heartrate      *Stream[int]        `json:"heartrate,omitempty"`
+	Cadence        *Stream[int]        `json:"cadence,omitempty"`
+	Watts          *Stream[int]        `json:"watts,omitempty"`
+	Temp           *Stream[int]        `json:"temp,omitempty"`
+	Moving         *Stream[bool]       `json:"moving,omitempty"`
+	GradeSmooth    *Stream[float64]    `json:"grade_smooth,omitempty"`
+}
+
+// GetActivityStreams retrieves the given streams of an activity
+func (c *Client) GetActivityStreams(ctx context.Context, athleteID, activityID uint, types ...StreamType) (*StreamSet, error) {
+	keys := make([]string, 0, len(types))
+	for _, t := range types {
+		keys = append(keys, string(t))
+	}
+
+	params := url.Values{}
+	params.Add("keys", strings.Join(keys, ","))
+	params.Add("key_by_type", "true")
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/activities/%d/streams?", APIBaseURL, activityID)+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+
+	body, err := c.call(ctx, athleteID, req, c.maxRetries)
+	if err != nil {
+		return nil, fmt.Errorf("could not call: %w", err)
+	}
+
+	var streams StreamSet
+	err = json.Unmarshal(body, &streams)
+	if err != nil {
+		return nil, err
+	}
+
+	return &streams, nil
+}
@@ -0,0 +1,331 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+const (
+	// DefaultBackfillWindow is the span of history fetched at once. One window costs a request per
+	// 100 activities, plus the requests of fetching their details and laps.
+	DefaultBackfillWindow = 90 * 24 * time.Hour
+	// DefaultBackfillShare is the share of the 15-minute and daily rate limits backfills may use.
+	DefaultBackfillShare = 0.5
+	// DefaultBackfillErrorDelay is the pause after a window failed, before the next one is fetched.
+	DefaultBackfillErrorDelay = time.Minute
+)
+
+// DefaultBackfillStart is where history starts unless set with WithBackfillStart.
+var DefaultBackfillStart = time.Date(2008, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Default rate limits of read requests, assumed until a response reports the application's limits.
+const (
+	defaultShortLimit = 100
+	defaultDailyLimit = 1000
+)
+
+type BackfillOption func(*Backfiller)
+
+// WithBackfillStart sets where the history of added athletes starts.
+func WithBackfillStart(t time.Time) BackfillOption {
+	return func(b *Backfiller) {
+		b.start = t
+	}
+}
+
+// WithBackfillWindow sets the span of history fetched at once for added athletes.
+func WithBackfillWindow(d time.Duration) BackfillOption {
+	return func(b *Backfiller) {
+		b.window = d
+	}
+}
+
+// WithBackfillShare sets the share of the 15-minute and daily rate limits backfills may use, between 0 and 1.
+// Before each window the Backfiller waits until the application's usage is below the share, so the rest
+// is left to other uses of the application.
+func WithBackfillShare(short, daily float64) BackfillOption {
+	return func(b *Backfiller) {
+		b.shortShare = short
+		b.dailyShare = daily
+	}
+}
+
+// WithBackfillErrorDelay sets the pause after a window failed.
+func WithBackfillErrorDelay(d time.Duration) BackfillOption {
+	return func(b *Backfiller) {
+		b.errorDelay = d
+	}
+}
+
+// Backfiller fetches the history of athletes into the Syncer's sink. Histories are split into windows,
+// which are fetched round-robin across the pending athletes within a share of the rate limits.
+type Backfiller struct {
+	s     *Syncer
+	store strava.BackfillStore
+
+	start      time.Time
+	window     time.Duration
+	shortShare float64
+	dailyShare float64
+	errorDelay time.Duration
+}
+
+func NewBackfiller(s *Syncer, store strava.BackfillStore, opts ...BackfillOption) *Backfiller {
+	b := &Backfiller{
+		s:          s,
+		store:      store,
+		start:      DefaultBackfillStart,
+		window:     DefaultBackfillWindow,
+		shortShare: DefaultBackfillShare,
+		dailyShare: DefaultBackfillShare,
+		errorDelay: DefaultBackfillErrorDelay,
+	}
+
+	for _, opt := range opts {
+		opt(b)
+	}
+
+	return b
+}
+
+// Add plans the backfill of the athlete's history until now. The Syncer's cursor of an athlete without
+// one is set to now, so SyncAthlete continues where the backfill ends. Adding an athlete again is a no-op.
+func (b *Backfiller) Add(ctx context.Context, athleteID uint) (*strava.Backfill, error) {
+	backfill, err := b.store.GetBackfill(ctx, athleteID)
+	if err == nil {
+		return backfill, nil
+	}
+	if !errors.Is(err, strava.ErrBackfillNotFound) {
+		return nil, fmt.Errorf("get backfill: %w", err)
+	}
+
+	now := b.s.now().Truncate(time.Second)
+
+	_, err = b.s.cursors.GetActivityCursor(ctx, athleteID)
+	if errors.Is(err, strava.ErrActivityCursorNotFound) {
+		err = b.s.cursors.SaveActivityCursor(ctx, &Cursor{AthleteID: athleteID, StartDate: now})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("set cursor: %w", err)
+	}
+
+	backfill = &strava.Backfill{
+		AthleteID: athleteID,
+		From:      b.start,
+		To:        now,
+		Window:    b.window,
+		Before:    now,
+		UpdatedAt: now,
+	}
+	if err := b.store.SaveBackfill(ctx, backfill); err != nil {
+		return nil, fmt.Errorf("save backfill: %w", err)
+	}
+
+	return backfill, nil
+}
+
+// Run fetches the windows of pending backfills until all are done or ctx is done. The athlete whose
+// backfill progressed least recently goes next, so athletes added later don't wait for earlier ones.
+// Failed windows are retried after the others, or after the daily reset if the daily rate limit was reached.
+func (b *Backfiller) Run(ctx context.Context) error {
+	for {
+		backfill, err := b.next(ctx)
+		if err != nil {
+			return err
+		}
+		if backfill == nil {
+			return nil
+		}
+
+		if err := b.waitShare(ctx); err != nil {
+			return err
+		}
+
+		if err := b.Step(ctx, backfill); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			b.s.logger.ErrorContext(ctx, "backfill window failed", slog.Uint64("athlete_id", uint64(backfill.AthleteID)), slog.Any("error", err))
+
+			delay := b.errorDelay
+			if rl, ok := b.s.c.RateLimit(); ok && errors.Is(err, ErrDailyRateLimit) {
+				delay = rl.DailyReset().Sub(b.s.now())
+			}
+
+			if err := b.s.sleep(ctx, delay); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// next returns the pending backfill that progressed least recently, nil if none is pending.
+func (b *Backfiller) next(ctx context.Context) (*strava.Backfill, error) {
+	backfills, err := b.store.ListBackfills(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list backfills: %w", err)
+	}
+
+	var next *strava.Backfill
+	for _, backfill := range backfills {
+		if backfill.Pending() && (next == nil || backfill.UpdatedAt.Before(next.UpdatedAt)) {
+			next = backfill
+		}
+	}
+
+	return next, nil
+}
+
+// Step fetches the next window of the backfill and saves its progress. The window is fetched
+// again by the next Step if it failed.
+func (b *Backfiller) Step(ctx context.Context, backfill *strava.Backfill) error {
+	if !backfill.Pending() {
+		return nil
+	}
+
+	after := backfill.Before.Add(-backfill.Window)
+	if after.Before(backfill.From) {
+		after = backfill.From
+	}
+
+	var activities, requests int
+	err := b.s.do(ctx, func() error {
+		n, r, err := b.s.syncWindow(ctx, backfill.AthleteID, after, backfill.Before)
+		activities, requests = activities+n, requests+r
+		return err
+	})
+
+	backfill.Requests += requests
+	backfill.UpdatedAt = b.s.now()
+	backfill.LastError = ""
+	if err != nil {
+		backfill.LastError = err.Error()
+		backfill.Abandoned = errors.Is(err, strava.ErrTokenNotFound) || errors.Is(err, strava.ErrTokenRevoked)
+	} else {
+		backfill.Before = after
+		backfill.Activities += activities
+	}
+
+	if serr := b.store.SaveBackfill(ctx, backfill); serr != nil {
+		return errors.Join(err, fmt.Errorf("save backfill: %w", serr))
+	}
+
+	return err
+}
+
+// waitShare waits until the application's usage of the rate limits is below the backfill's share.
+func (b *Backfiller) waitShare(ctx context.Context) error {
+	rl, ok := b.s.c.RateLimit()
+	if !ok {
+		return nil
+	}
+
+	now := b.s.now()
+
+	var until time.Time
+	switch {
+	case now.Before(rl.DailyReset()) && exceedsShare(rl.Daily, rl.ReadDaily, b.dailyShare):
+		until = rl.DailyReset()
+	case now.Before(rl.ShortReset()) && exceedsShare(rl.Short, rl.ReadShort, b.shortShare):
+		until = rl.ShortReset()
+	default:
+		return nil
+	}
+
+	b.s.logger.InfoContext(ctx, "backfill share of the rate limit used: waiting", slog.Time("until", until))
+	return b.s.sleep(ctx, until.Sub(now))
+}
+
+// exceedsShare reports whether the usage of the window or of the read window, if reported, reached the share.
+func exceedsShare(w, read strava.RateLimitWindow, share float64) bool {
+	for _, w := range []strava.RateLimitWindow{w, read} {
+		if w.Limit > 0 && float64(w.Usage) >= share*float64(w.Limit) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// BackfillStatus is the progress of all backfills.
+type BackfillStatus struct {
+	// Pending are the backfills with windows left, sorted by athlete ID.
+	Pending []*strava.Backfill
+	Done    int
+
+	WindowsLeft int
+	// RequestsLeft is estimated from the requests per window spent so far.
+	RequestsLeft int
+	// ETA is when the pending backfills are estimated to be done, if they get their whole share of the
+	// rate limits. It's zero if none is pending or the share is zero.
+	ETA time.Time
+}
+
+// Status returns the progress of all backfills and estimates when they're done.
+func (b *Backfiller) Status(ctx context.Context) (*BackfillStatus, error) {
+	backfills, err := b.store.ListBackfills(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list backfills: %w", err)
+	}
+
+	status := &BackfillStatus{}
+	windowsDone, requests := 0, 0
+	for _, backfill := range backfills {
+		windowsDone += backfill.WindowsDone()
+		requests += backfill.Requests
+
+		switch {
+		case backfill.Pending():
+			status.Pending = append(status.Pending, backfill)
+			status.WindowsLeft += backfill.WindowsLeft()
+		case backfill.Done():
+			status.Done++
+		}
+	}
+	sort.Slice(status.Pending, func(i, j int) bool { return status.Pending[i].AthleteID < status.Pending[j].AthleteID })
+
+	if status.WindowsLeft == 0 {
+		return status, nil
+	}
+
+	perWindow := 1.0
+	if windowsDone > 0 && requests > 0 {
+		perWindow = float64(requests) / float64(windowsDone)
+	}
+	status.RequestsLeft = int(float64(status.WindowsLeft)*perWindow + 0.5)
+
+	if rate := b.requestsPerSecond(); rate > 0 {
+		status.ETA = b.s.now().Add(time.Duration(float64(status.RequestsLeft) / rate * float64(time.Second)))
+	}
+
+	return status, nil
+}
+
+// requestsPerSecond returns the backfill's share of the rate limits, the lower of the 15-minute and daily one.
+func (b *Backfiller) requestsPerSecond() float64 {
+	short, daily := defaultShortLimit, defaultDailyLimit
+	if rl, ok := b.s.c.RateLimit(); ok {
+		short, daily = limit(rl.Short, rl.ReadShort), limit(rl.Daily, rl.ReadDaily)
+	}
+
+	return min(
+		b.shortShare*float64(short)/(15*time.Minute).Seconds(),
+		b.dailyShare*float64(daily)/(24*time.Hour).Seconds(),
+	)
+}
+
+// limit returns the limit of the window, or of the read window if reported and lower.
+func limit(w, read strava.RateLimitWindow) int {
+	if read.Limit > 0 {
+		return min(w.Limit, read.Limit)
+	}
+
+	return w.Limit
+}
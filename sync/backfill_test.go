@@ -0,0 +1,157 @@
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+
+	"github.com/marvell/strava-go/inmemory"
+)
+
+func TestBackfiller_Run(t *testing.T) {
+	// arrange
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	api := &testAPI{usage: "1,1"}
+	for i := uint(1); i <= 20; i++ {
+		api.add(i, start.Add(time.Duration(i)*80*24*time.Hour))
+	}
+	// On the boundary of two windows.
+	api.add(21, now.Add(-365*24*time.Hour))
+	// Started after the backfill was added, left to SyncAthlete.
+	api.add(22, now.Add(time.Minute))
+
+	sink := &testSink{}
+	s, cursors, sleeps := newTestSyncer(t, api, sink)
+	s.now = func() time.Time { return now }
+
+	store := &inmemory.BackfillStore{}
+	b := NewBackfiller(s, store, WithBackfillStart(start), WithBackfillWindow(365*24*time.Hour))
+	ctx := context.Background()
+
+	_, err := b.Add(ctx, 7)
+	assert.NoErr(t, err)
+	// Athlete 8 has no token.
+	_, err = b.Add(ctx, 8)
+	assert.NoErr(t, err)
+
+	// act
+	err = b.Run(ctx)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Len(t, sink.upserted, 21)
+	assert.Eq(t, []time.Duration{DefaultBackfillErrorDelay}, *sleeps)
+
+	backfill, err := store.GetBackfill(ctx, 7)
+	assert.NoErr(t, err)
+	assert.True(t, backfill.Done())
+	assert.Eq(t, 21, backfill.Activities)
+	assert.Eq(t, 5, backfill.Requests)
+	assert.Eq(t, 5, backfill.WindowsDone())
+	assert.Eq(t, "", backfill.LastError)
+
+	backfill, err = store.GetBackfill(ctx, 8)
+	assert.NoErr(t, err)
+	assert.True(t, backfill.Abandoned)
+	assert.StrContains(t, backfill.LastError, "token not found")
+
+	cursor, err := cursors.GetActivityCursor(ctx, 7)
+	assert.NoErr(t, err)
+	assert.Eq(t, now, cursor.StartDate)
+
+	// act
+	n, err := s.SyncAthlete(ctx, 7)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 0, n)
+}
+
+func TestBackfiller_Status(t *testing.T) {
+	// arrange
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	api := &testAPI{usage: "1,1"}
+	s, _, _ := newTestSyncer(t, api, &testSink{})
+	s.now = func() time.Time { return now }
+
+	store := &inmemory.BackfillStore{}
+	b := NewBackfiller(s, store, WithBackfillStart(now.Add(-5*DefaultBackfillWindow)))
+	ctx := context.Background()
+
+	_, err := b.Add(ctx, 7)
+	assert.NoErr(t, err)
+
+	// act
+	status, err := b.Status(ctx)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Len(t, status.Pending, 1)
+	assert.Eq(t, 5, status.WindowsLeft)
+	assert.Eq(t, 5, status.RequestsLeft)
+	// Half of the default daily read limit of 1000 requests.
+	assert.Eq(t, now.Add(864*time.Second), status.ETA)
+
+	// act
+	assert.NoErr(t, b.Step(ctx, status.Pending[0]))
+	status, err = b.Status(ctx)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 4, status.WindowsLeft)
+	assert.Eq(t, 4, status.RequestsLeft)
+	// The reported limit is 2000 requests a day.
+	assert.Eq(t, now.Add(345600*time.Millisecond), status.ETA)
+
+	// act
+	assert.NoErr(t, b.Run(ctx))
+	status, err = b.Status(ctx)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Len(t, status.Pending, 0)
+	assert.Eq(t, 1, status.Done)
+	assert.True(t, status.ETA.IsZero())
+}
+
+func TestBackfiller_Share(t *testing.T) {
+	tests := []struct {
+		name  string
+		usage string
+		max   time.Duration
+	}{
+		{name: "15-minute", usage: "100,300", max: 15 * time.Minute},
+		{name: "daily", usage: "10,1000", max: 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			api := &testAPI{usage: tt.usage}
+			s, _, sleeps := newTestSyncer(t, api, &testSink{})
+
+			b := NewBackfiller(s, &inmemory.BackfillStore{},
+				WithBackfillStart(time.Now().Add(-2*DefaultBackfillWindow)),
+				WithBackfillShare(0.5, 0.5),
+			)
+			ctx := context.Background()
+
+			_, err := b.Add(ctx, 7)
+			assert.NoErr(t, err)
+
+			// act
+			err = b.Run(ctx)
+
+			// assert
+			assert.NoErr(t, err)
+			assert.Len(t, *sleeps, 1)
+			assert.Gt(t, int64((*sleeps)[0]), 0)
+			assert.Lte(t, int64((*sleeps)[0]), int64(tt.max))
+		})
+	}
+}
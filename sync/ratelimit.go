@@ -0,0 +1,79 @@
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+// rateLimitRetries is how often a request rejected by the rate limit is retried in the next 15-minute window.
+const rateLimitRetries = 1
+
+// do calls fn when the rate limit allows it, and again in the next 15-minute window if it was rate limited.
+func (s *Syncer) do(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := s.waitRateLimit(ctx); err != nil {
+			return err
+		}
+
+		err := fn()
+		if !errors.Is(err, strava.ErrRateLimited) || attempt == rateLimitRetries {
+			return err
+		}
+
+		reset := strava.RateLimit{UpdatedAt: s.now()}.ShortReset()
+		s.logger.WarnContext(ctx, "rate limited: waiting for the next window", slog.Time("until", reset))
+
+		if err := s.sleep(ctx, reset.Sub(s.now())); err != nil {
+			return err
+		}
+	}
+}
+
+// waitRateLimit waits for the next 15-minute window if the requests left in the current one don't exceed
+// the reserve, and returns ErrDailyRateLimit if the ones left today don't.
+func (s *Syncer) waitRateLimit(ctx context.Context) error {
+	rl, ok := s.c.RateLimit()
+	if !ok {
+		return nil
+	}
+
+	now := s.now()
+
+	// The syncer only reads, so the lower read limits apply too.
+	if now.Before(rl.DailyReset()) && remaining(rl.Daily, rl.ReadDaily) <= s.dailyReserve {
+		return fmt.Errorf("%w: resets at %s", ErrDailyRateLimit, rl.DailyReset().Format(time.RFC3339))
+	}
+
+	if reset := rl.ShortReset(); now.Before(reset) && remaining(rl.Short, rl.ReadShort) <= s.shortReserve {
+		s.logger.InfoContext(ctx, "rate limit reserve reached: waiting for the next window", slog.Time("until", reset))
+		return s.sleep(ctx, reset.Sub(now))
+	}
+
+	return nil
+}
+
+// remaining returns the requests left in the window, limited by the read window if reported.
+func remaining(w, read strava.RateLimitWindow) int {
+	if read.Limit > 0 {
+		return min(w.Remaining(), read.Remaining())
+	}
+
+	return w.Remaining()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
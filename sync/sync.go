@@ -0,0 +1,316 @@
+// Package sync mirrors the activities of athletes into a Sink. A Syncer lists the activities each athlete
+// added since the last sync, keeping its progress in a CursorStore, and applies webhook events to the same Sink.
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/marvell/strava-go"
+)
+
+type (
+	Cursor      = strava.ActivityCursor
+	CursorStore = strava.ActivityCursorStore
+)
+
+// ErrDailyRateLimit is returned when the daily rate limit is reached. The progress is kept, so the sync
+// resumes where it stopped when called again after strava.RateLimit.DailyReset.
+var ErrDailyRateLimit = errors.New("daily rate limit reached")
+
+// Activity is a synced activity.
+type Activity struct {
+	AthleteID uint
+	Summary   *strava.SummaryActivity
+	// Detailed is set if the Syncer fetches details, and for activities of webhook events.
+	Detailed *strava.DetailedActivity
+	// Laps is set if the Syncer fetches laps.
+	Laps []*strava.Lap
+}
+
+// ID returns the activity ID.
+func (a *Activity) ID() uint {
+	return a.Summary.ID
+}
+
+// Sink receives synced activities. An activity can be passed more than once, e.g. when resuming an
+// interrupted sync or for webhook updates, so Upsert must replace the stored activity.
+type Sink interface {
+	Upsert(ctx context.Context, activity *Activity) error
+	// Delete removes an activity that was deleted or can't be accessed anymore. Deleting a missing
+	// activity is not an error.
+	Delete(ctx context.Context, athleteID, activityID uint) error
+}
+
+type Option func(*Syncer)
+
+// WithDetails fetches the detailed activity of each listed activity.
+func WithDetails() Option {
+	return func(s *Syncer) {
+		s.details = true
+	}
+}
+
+// WithLaps fetches the laps of each activity.
+func WithLaps() Option {
+	return func(s *Syncer) {
+		s.laps = true
+	}
+}
+
+// WithStartDate limits the first sync of an athlete to activities started after t.
+// By default all activities are synced.
+func WithStartDate(t time.Time) Option {
+	return func(s *Syncer) {
+		s.startDate = t
+	}
+}
+
+// WithRateLimitReserve leaves the given number of requests of the 15-minute and daily windows to other
+// uses of the application. The Syncer waits for the next 15-minute window and stops with ErrDailyRateLimit
+// when they're reached.
+func WithRateLimitReserve(short, daily int) Option {
+	return func(s *Syncer) {
+		s.shortReserve = short
+		s.dailyReserve = daily
+	}
+}
+
+func WithLogger(logger *slog.Logger) Option {
+	return func(s *Syncer) {
+		s.logger = logger
+	}
+}
+
+// Syncer mirrors the activities of athletes into a Sink.
+type Syncer struct {
+	c       *strava.Client
+	cursors CursorStore
+	sink    Sink
+
+	details   bool
+	laps      bool
+	startDate time.Time
+
+	shortReserve int
+	dailyReserve int
+
+	logger *slog.Logger
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+func New(c *strava.Client, cursors CursorStore, sink Sink, opts ...Option) *Syncer {
+	s := &Syncer{
+		c:       c,
+		cursors: cursors,
+		sink:    sink,
+		logger:  slog.Default(),
+		now:     time.Now,
+		sleep:   sleep,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// SyncAthlete passes the activities the athlete started since the last sync to the sink, in order of their
+// start date, and returns their number. The cursor is saved after each page, so an interrupted sync resumes
+// from the last synced activity.
+func (s *Syncer) SyncAthlete(ctx context.Context, athleteID uint) (int, error) {
+	var synced int
+	err := s.do(ctx, func() error {
+		n, err := s.syncAthlete(ctx, athleteID)
+		synced += n
+		return err
+	})
+
+	return synced, err
+}
+
+func (s *Syncer) syncAthlete(ctx context.Context, athleteID uint) (int, error) {
+	cursor, err := s.cursors.GetActivityCursor(ctx, athleteID)
+	if errors.Is(err, strava.ErrActivityCursorNotFound) {
+		cursor = &Cursor{AthleteID: athleteID, StartDate: s.startDate}
+	} else if err != nil {
+		return 0, fmt.Errorf("get cursor: %w", err)
+	}
+
+	// after is exclusive and has second precision, so the activities starting in the cursor's second are
+	// listed again and skipped by their ID.
+	after := time.Unix(0, 0)
+	if cursor.StartDate.After(after) {
+		after = cursor.StartDate.Add(-time.Second)
+	}
+
+	synced := 0
+	err = s.c.GetSummaryActivitiesWithCallback(ctx, athleteID, after, s.now(), func(page []*strava.SummaryActivity) error {
+		// Strava lists activities after a date in ascending order of their start date.
+		sort.Slice(page, func(i, j int) bool {
+			if !page[i].StartDate.Equal(page[j].StartDate) {
+				return page[i].StartDate.Before(page[j].StartDate)
+			}
+			return page[i].ID < page[j].ID
+		})
+
+		next := *cursor
+		for _, a := range page {
+			if !next.After(a.StartDate, a.ID) {
+				continue
+			}
+
+			if err := s.syncActivity(ctx, athleteID, a); err != nil {
+				return errors.Join(err, s.saveCursor(ctx, cursor, &next))
+			}
+
+			next.StartDate, next.ActivityID = a.StartDate, a.ID
+			synced++
+		}
+
+		if err := s.saveCursor(ctx, cursor, &next); err != nil {
+			return err
+		}
+		cursor = &next
+
+		return s.waitRateLimit(ctx)
+	})
+	if err != nil {
+		return synced, fmt.Errorf("sync activities of athlete %d: %w", athleteID, err)
+	}
+
+	return synced, nil
+}
+
+// syncWindow passes the activities started in [after, before) to the sink, and returns their number and
+// the number of requests spent.
+func (s *Syncer) syncWindow(ctx context.Context, athleteID uint, after, before time.Time) (int, int, error) {
+	perActivity := 0
+	if s.details {
+		perActivity++
+	}
+	if s.laps {
+		perActivity++
+	}
+
+	synced, requests := 0, 0
+	// after is exclusive and has second precision.
+	err := s.c.GetSummaryActivitiesWithCallback(ctx, athleteID, after.Add(-time.Second), before, func(page []*strava.SummaryActivity) error {
+		requests++
+
+		for _, a := range page {
+			if err := s.syncActivity(ctx, athleteID, a); err != nil {
+				return err
+			}
+
+			synced++
+			requests += perActivity
+		}
+
+		return s.waitRateLimit(ctx)
+	})
+	if err != nil {
+		return synced, requests, fmt.Errorf("sync activities of athlete %d: %w", athleteID, err)
+	}
+
+	return synced, requests, nil
+}
+
+// saveCursor saves next if it moved past cursor.
+func (s *Syncer) saveCursor(ctx context.Context, cursor, next *Cursor) error {
+	if *next == *cursor {
+		return nil
+	}
+
+	if err := s.cursors.SaveActivityCursor(ctx, next); err != nil {
+		return fmt.Errorf("save cursor: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Syncer) syncActivity(ctx context.Context, athleteID uint, summary *strava.SummaryActivity) error {
+	activity := &Activity{AthleteID: athleteID, Summary: summary}
+
+	if s.details {
+		err := s.do(ctx, func() (err error) {
+			activity.Detailed, err = s.c.GetDetailedActivity(ctx, athleteID, summary.ID)
+			return err
+		})
+		if errors.Is(err, strava.ErrNotFound) {
+			return s.delete(ctx, athleteID, summary.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("get activity %d: %w", summary.ID, err)
+		}
+	}
+
+	return s.upsert(ctx, activity)
+}
+
+// HandleEvent applies activity webhook events to the sink. Register it with Client.RegisterEventHandler.
+// Created and updated activities are fetched and upserted, deleted ones and ones that can't be fetched
+// anymore are deleted. Other events are ignored.
+//
+// The cursor isn't moved, as activities started earlier may not be synced yet.
+func (s *Syncer) HandleEvent(ctx context.Context, event strava.Event) error {
+	if event.ObjectType != strava.EventObjectTypeActivity {
+		return nil
+	}
+
+	athleteID, activityID := event.OwnerID, event.ObjectID
+	if event.AspectType == strava.EventAspectTypeDelete {
+		return s.delete(ctx, athleteID, activityID)
+	}
+
+	var detailed *strava.DetailedActivity
+	err := s.do(ctx, func() (err error) {
+		detailed, err = s.c.GetDetailedActivity(ctx, athleteID, activityID)
+		return err
+	})
+	if errors.Is(err, strava.ErrNotFound) {
+		return s.delete(ctx, athleteID, activityID)
+	}
+	if err != nil {
+		return fmt.Errorf("get activity %d: %w", activityID, err)
+	}
+
+	return s.upsert(ctx, &Activity{AthleteID: athleteID, Summary: &detailed.SummaryActivity, Detailed: detailed})
+}
+
+// upsert fetches the laps of the activity if configured and passes it to the sink.
+func (s *Syncer) upsert(ctx context.Context, activity *Activity) error {
+	if s.laps {
+		err := s.do(ctx, func() (err error) {
+			activity.Laps, err = s.c.GetActivityLaps(ctx, activity.AthleteID, activity.ID())
+			return err
+		})
+		if errors.Is(err, strava.ErrNotFound) {
+			return s.delete(ctx, activity.AthleteID, activity.ID())
+		}
+		if err != nil {
+			return fmt.Errorf("get laps of activity %d: %w", activity.ID(), err)
+		}
+	}
+
+	if err := s.sink.Upsert(ctx, activity); err != nil {
+		return fmt.Errorf("upsert activity %d: %w", activity.ID(), err)
+	}
+
+	return nil
+}
+
+func (s *Syncer) delete(ctx context.Context, athleteID, activityID uint) error {
+	if err := s.sink.Delete(ctx, athleteID, activityID); err != nil {
+		return fmt.Errorf("delete activity %d: %w", activityID, err)
+	}
+
+	return nil
+}
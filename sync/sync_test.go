@@ -0,0 +1,340 @@
+package sync
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	gosync "sync"
+	"testing"
+	"time"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+
+	"github.com/marvell/strava-go"
+	"github.com/marvell/strava-go/inmemory"
+)
+
+var testStart = time.Date(2024, 5, 31, 6, 0, 0, 0, time.UTC)
+
+// testAPI emulates the activity endpoints for athlete 7.
+type testAPI struct {
+	mu         gosync.Mutex
+	activities []*strava.SummaryActivity
+	// rateLimited is the number of following requests rejected with 429.
+	rateLimited int
+	// usage is reported in the X-RateLimit-Usage header.
+	usage string
+	// missing activities can be listed, but not fetched.
+	missing map[uint]bool
+}
+
+func (a *testAPI) add(id uint, start time.Time) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.activities = append(a.activities, &strava.SummaryActivity{ID: id, Name: fmt.Sprintf("Activity %d", id), StartDate: start})
+}
+
+func (a *testAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-RateLimit-Limit", "200,2000")
+	w.Header().Set("X-RateLimit-Usage", a.usage)
+
+	if a.rateLimited > 0 {
+		a.rateLimited--
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"message":"Rate Limit Exceeded"}`))
+		return
+	}
+
+	var id uint
+	switch {
+	case r.URL.Path == "/api/v3/athlete/activities":
+		q := r.URL.Query()
+		after, _ := strconv.ParseInt(q.Get("after"), 10, 64)
+		before, _ := strconv.ParseInt(q.Get("before"), 10, 64)
+		page, _ := strconv.Atoi(q.Get("page"))
+		perPage, _ := strconv.Atoi(q.Get("per_page"))
+
+		var listed []*strava.SummaryActivity
+		for _, activity := range a.activities {
+			if activity.StartDate.Unix() > after && activity.StartDate.Unix() < before {
+				listed = append(listed, activity)
+			}
+		}
+
+		from := min((page-1)*perPage, len(listed))
+		_ = json.NewEncoder(w).Encode(listed[from:min(from+perPage, len(listed))])
+		return
+	case scan(r.URL.Path, "/api/v3/activities/%d/laps", &id):
+		if !a.missing[id] {
+			_, _ = fmt.Fprintf(w, `[{"id":%d1},{"id":%d2}]`, id, id)
+			return
+		}
+	case scan(r.URL.Path, "/api/v3/activities/%d", &id):
+		if !a.missing[id] {
+			_, _ = fmt.Fprintf(w, `{"id":%d,"name":"Activity %d","description":"details"}`, id, id)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+	_, _ = w.Write([]byte(`{"message":"Record Not Found"}`))
+}
+
+func scan(path, format string, id *uint) bool {
+	var rest string
+	n, _ := fmt.Sscanf(path+" end", format+" %s", id, &rest)
+	return n == 2 && rest == "end"
+}
+
+// testSink records the upserted and deleted activities.
+type testSink struct {
+	upserted []*Activity
+	deleted  []uint
+	// fail fails the upsert of the activity.
+	fail uint
+}
+
+func (s *testSink) Upsert(_ context.Context, activity *Activity) error {
+	if activity.ID() == s.fail {
+		return errors.New("sink unavailable")
+	}
+
+	s.upserted = append(s.upserted, activity)
+	return nil
+}
+
+func (s *testSink) Delete(_ context.Context, _, activityID uint) error {
+	s.deleted = append(s.deleted, activityID)
+	return nil
+}
+
+func (s *testSink) ids() []uint {
+	ids := make([]uint, 0, len(s.upserted))
+	for _, a := range s.upserted {
+		ids = append(ids, a.ID())
+	}
+	return ids
+}
+
+// newTestSyncer returns a syncer of the API's athlete 7, recording its sleeps instead of sleeping.
+func newTestSyncer(t *testing.T, api http.Handler, sink Sink, opts ...Option) (*Syncer, *inmemory.ActivityCursorStore, *[]time.Duration) {
+	srv := httptest.NewTLSServer(api)
+	t.Cleanup(srv.Close)
+
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+
+	ts := &inmemory.TokenStorage{}
+	err := ts.Save(context.Background(), &strava.Token{
+		Token:     &oauth2.Token{AccessToken: "access", Expiry: time.Now().Add(time.Hour)},
+		AthleteID: 7,
+	})
+	assert.NoErr(t, err)
+
+	c := strava.NewClient("client_id", "client_secret", "", ts, strava.WithTransport(transport))
+	cursors := &inmemory.ActivityCursorStore{}
+
+	s := New(c, cursors, sink, opts...)
+
+	var sleeps []time.Duration
+	s.sleep = func(_ context.Context, d time.Duration) error {
+		sleeps = append(sleeps, d)
+		return nil
+	}
+
+	return s, cursors, &sleeps
+}
+
+func TestSyncer_SyncAthlete(t *testing.T) {
+	// arrange
+	api := &testAPI{usage: "1,1"}
+	for i := uint(1); i <= 150; i++ {
+		api.add(i, testStart.Add(time.Duration(i)*time.Minute))
+	}
+	// Activity 151 starts in the same second as 150.
+	api.add(151, testStart.Add(150*time.Minute))
+
+	sink := &testSink{}
+	s, cursors, _ := newTestSyncer(t, api, sink)
+	ctx := context.Background()
+
+	// act
+	n, err := s.SyncAthlete(ctx, 7)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 151, n)
+	assert.Eq(t, uint(1), sink.ids()[0])
+	assert.Eq(t, uint(151), sink.ids()[150])
+	assert.Nil(t, sink.upserted[0].Detailed)
+
+	cursor, err := cursors.GetActivityCursor(ctx, 7)
+	assert.NoErr(t, err)
+	assert.Eq(t, uint(151), cursor.ActivityID)
+	assert.True(t, cursor.StartDate.Equal(testStart.Add(150*time.Minute)))
+
+	// act
+	api.add(152, testStart.Add(150*time.Minute))
+	api.add(153, testStart.Add(151*time.Minute))
+	sink.upserted = nil
+	n, err = s.SyncAthlete(ctx, 7)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 2, n)
+	assert.Eq(t, []uint{152, 153}, sink.ids())
+}
+
+func TestSyncer_SyncAthlete_Resume(t *testing.T) {
+	// arrange
+	api := &testAPI{usage: "1,1"}
+	for i := uint(1); i <= 5; i++ {
+		api.add(i, testStart.Add(time.Duration(i)*time.Minute))
+	}
+
+	sink := &testSink{fail: 3}
+	s, cursors, _ := newTestSyncer(t, api, sink)
+	ctx := context.Background()
+
+	// act
+	n, err := s.SyncAthlete(ctx, 7)
+
+	// assert
+	assert.ErrMsg(t, err, "sync activities of athlete 7: callback failed: upsert activity 3: sink unavailable")
+	assert.Eq(t, 2, n)
+
+	cursor, err := cursors.GetActivityCursor(ctx, 7)
+	assert.NoErr(t, err)
+	assert.Eq(t, uint(2), cursor.ActivityID)
+
+	// act
+	sink.fail = 0
+	n, err = s.SyncAthlete(ctx, 7)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 3, n)
+	assert.Eq(t, []uint{1, 2, 3, 4, 5}, sink.ids())
+}
+
+func TestSyncer_SyncAthlete_Hydrate(t *testing.T) {
+	// arrange
+	api := &testAPI{usage: "1,1", missing: map[uint]bool{2: true}}
+	api.add(1, testStart)
+	api.add(2, testStart.Add(time.Minute))
+
+	sink := &testSink{}
+	s, _, _ := newTestSyncer(t, api, sink, WithDetails(), WithLaps(), WithStartDate(testStart.Add(-time.Hour)))
+
+	// act
+	n, err := s.SyncAthlete(context.Background(), 7)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 2, n)
+	assert.Eq(t, []uint{1}, sink.ids())
+	assert.Eq(t, "details", sink.upserted[0].Detailed.Description)
+	assert.Len(t, sink.upserted[0].Laps, 2)
+	assert.Eq(t, []uint{2}, sink.deleted)
+}
+
+func TestSyncer_SyncAthlete_RateLimit(t *testing.T) {
+	t.Run("rate limited", func(t *testing.T) {
+		// arrange
+		api := &testAPI{usage: "10,300", rateLimited: 1}
+		api.add(1, testStart)
+
+		sink := &testSink{}
+		s, _, sleeps := newTestSyncer(t, api, sink)
+		s.now = func() time.Time { return time.Date(2024, 6, 1, 10, 5, 0, 0, time.UTC) }
+
+		// act
+		n, err := s.SyncAthlete(context.Background(), 7)
+
+		// assert
+		assert.NoErr(t, err)
+		assert.Eq(t, 1, n)
+		assert.Eq(t, []time.Duration{10 * time.Minute}, *sleeps)
+	})
+
+	t.Run("reserve", func(t *testing.T) {
+		// arrange
+		api := &testAPI{usage: "195,300"}
+		api.add(1, testStart)
+		api.add(2, testStart.Add(time.Minute))
+
+		sink := &testSink{}
+		s, _, sleeps := newTestSyncer(t, api, sink, WithDetails(), WithRateLimitReserve(10, 100))
+
+		// act
+		n, err := s.SyncAthlete(context.Background(), 7)
+
+		// assert
+		assert.NoErr(t, err)
+		assert.Eq(t, 2, n)
+		assert.Len(t, *sleeps, 3)
+	})
+
+	t.Run("daily", func(t *testing.T) {
+		// arrange
+		api := &testAPI{usage: "10,1950"}
+		api.add(1, testStart)
+		api.add(2, testStart.Add(time.Minute))
+
+		sink := &testSink{}
+		s, _, _ := newTestSyncer(t, api, sink, WithDetails(), WithRateLimitReserve(10, 100))
+
+		// act
+		n, err := s.SyncAthlete(context.Background(), 7)
+
+		// assert
+		assert.ErrIs(t, err, ErrDailyRateLimit)
+		assert.Eq(t, 0, n)
+	})
+}
+
+func TestSyncer_HandleEvent(t *testing.T) {
+	// arrange
+	api := &testAPI{usage: "1,1", missing: map[uint]bool{2: true}}
+	sink := &testSink{}
+	s, cursors, _ := newTestSyncer(t, api, sink, WithLaps())
+	ctx := context.Background()
+
+	events := []strava.Event{
+		{ObjectType: strava.EventObjectTypeActivity, ObjectID: 1, AspectType: strava.EventAspectTypeUpdate, OwnerID: 7},
+		{ObjectType: strava.EventObjectTypeActivity, ObjectID: 2, AspectType: strava.EventAspectTypeCreate, OwnerID: 7},
+		{ObjectType: strava.EventObjectTypeActivity, ObjectID: 3, AspectType: strava.EventAspectTypeDelete, OwnerID: 7},
+		{ObjectType: strava.EventObjectTypeAthlete, ObjectID: 7, AspectType: strava.EventAspectTypeUpdate, OwnerID: 7},
+	}
+
+	// act
+	for _, event := range events {
+		assert.NoErr(t, s.HandleEvent(ctx, event))
+	}
+
+	// assert
+	assert.Eq(t, []uint{1}, sink.ids())
+	assert.Eq(t, "details", sink.upserted[0].Detailed.Description)
+	assert.Eq(t, "Activity 1", sink.upserted[0].Summary.Name)
+	assert.Len(t, sink.upserted[0].Laps, 2)
+	assert.Eq(t, []uint{2, 3}, sink.deleted)
+
+	_, err := cursors.GetActivityCursor(ctx, 7)
+	assert.ErrIs(t, err, strava.ErrActivityCursorNotFound)
+}
@@ -0,0 +1,135 @@
+package strava
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// Upload is the processing status of an uploaded activity file.
+type Upload struct {
+	ID         uint   `json:"id"`
+	ExternalID string `json:"external_id"`
+	// Error is set if the file couldn't be processed, e.g. because it duplicates an existing activity.
+	Error  string `json:"error"`
+	Status string `json:"status"`
+	// ActivityID is set once the file is processed.
+	ActivityID uint `json:"activity_id"`
+}
+
+// Done reports whether the upload is processed, successfully or not.
+func (u *Upload) Done() bool {
+	return u.ActivityID != 0 || u.Error != ""
+}
+
+// UploadParams describes an uploaded activity file.
+type UploadParams struct {
+	// DataType is fit, tcx or gpx, optionally with the .gz suffix. It's taken from the file name if empty.
+	DataType    string
+	Name        string
+	Description string
+	Trainer     bool
+	Commute     bool
+	ExternalID  string
+}
+
+// UploadActivity uploads an activity file. Strava processes it asynchronously, poll GetUpload until
+// the upload is done. The request isn't retried, as a retried upload could create a duplicate.
+func (c *Client) UploadActivity(ctx context.Context, athleteID uint, filename string, file io.Reader, params UploadParams) (*Upload, error) {
+	dataType := params.DataType
+	if dataType == "" {
+		dataType = uploadDataType(filename)
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	fields := map[string]string{
+		"data_type":   dataType,
+		"name":        params.Name,
+		"description": params.Description,
+		"external_id": params.ExternalID,
+	}
+	if params.Trainer {
+		fields["trainer"] = "1"
+	}
+	if params.Commute {
+		fields["commute"] = "1"
+	}
+
+	for k, v := range fields {
+		if v == "" {
+			continue
+		}
+		if err := mw.WriteField(k, v); err != nil {
+			return nil, fmt.Errorf("could not write field %s: %w", k, err)
+		}
+	}
+
+	fw, err := mw.CreateFormFile("file", filepath.Base(filename))
+	if err != nil {
+		return nil, fmt.Errorf("could not create file field: %w", err)
+	}
+	if _, err := io.Copy(fw, file); err != nil {
+		return nil, fmt.Errorf("could not read file: %w", err)
+	}
+	if err := mw.Close(); err != nil {
+		return nil, fmt.Errorf("could not close multipart body: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, APIBaseURL+"/uploads", &body)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	resp, err := c.call(ctx, athleteID, req, 0)
+	if err != nil {
+		return nil, fmt.Errorf("could not call: %w", err)
+	}
+
+	var v Upload
+	if err := json.Unmarshal(resp, &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// GetUpload retrieves the processing status of an upload.
+func (c *Client) GetUpload(ctx context.Context, athleteID, uploadID uint) (*Upload, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/uploads/%d", APIBaseURL, uploadID), nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not create request: %w", err)
+	}
+
+	body, err := c.call(ctx, athleteID, req, c.maxRetries)
+	if err != nil {
+		return nil, fmt.Errorf("could not call: %w", err)
+	}
+
+	var v Upload
+	if err := json.Unmarshal(body, &v); err != nil {
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// uploadDataType returns the data type of the file name, e.g. "fit.gz" for "ride.fit.gz".
+func uploadDataType(filename string) string {
+	name := strings.ToLower(filepath.Base(filename))
+
+	ext := filepath.Ext(name)
+	if ext == ".gz" {
+		ext = filepath.Ext(strings.TrimSuffix(name, ext)) + ext
+	}
+
+	return strings.TrimPrefix(ext, ".")
+}
@@ -0,0 +1,71 @@
+package strava
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gookit/goutil/testutil/assert"
+	"golang.org/x/oauth2"
+)
+
+func TestClient_UploadActivity(t *testing.T) {
+	// arrange
+	var (
+		fields map[string][]string
+		file   string
+	)
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v3/uploads":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			fields = r.MultipartForm.Value
+
+			f, _, err := r.FormFile("file")
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			data, _ := io.ReadAll(f)
+			file = string(data)
+
+			_ = json.NewEncoder(w).Encode(Upload{ID: 3, Status: "Your activity is still being processed."})
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v3/uploads/3":
+			_ = json.NewEncoder(w).Encode(Upload{ID: 3, Status: "Your activity is ready.", ActivityID: 42})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	ctx := context.Background()
+	ts := newTestTokenStorage()
+	assert.NoErr(t, ts.Save(ctx, &Token{Token: &oauth2.Token{AccessToken: "access"}, AthleteID: 7}))
+	c := NewClient("client_id", "client_secret", "", ts, WithTransport(newTestAPITransport(t, api)))
+
+	// act
+	upload, err := c.UploadActivity(ctx, 7, "rides/Morning.FIT.gz", strings.NewReader("fit data"), UploadParams{Name: "Morning Ride", Commute: true})
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, uint(3), upload.ID)
+	assert.False(t, upload.Done())
+	assert.Eq(t, "fit data", file)
+	assert.Eq(t, []string{"fit.gz"}, fields["data_type"])
+	assert.Eq(t, []string{"Morning Ride"}, fields["name"])
+	assert.Eq(t, []string{"1"}, fields["commute"])
+	assert.Nil(t, fields["trainer"])
+
+	// act
+	upload, err = c.GetUpload(ctx, 7, upload.ID)
+
+	// assert
+	assert.NoErr(t, err)
+	assert.True(t, upload.Done())
+	assert.Eq(t, uint(42), upload.ActivityID)
+}
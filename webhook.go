@@ -1,9 +1,12 @@
 package strava
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"net/http/httputil"
@@ -11,6 +14,8 @@ import (
 	"time"
 )
 
+// InitWebhook deletes the application's push subscriptions and creates a new one.
+// Events sent in between are lost; use ReconcileWebhook to keep a matching subscription.
 func (c *Client) InitWebhook(ctx context.Context) error {
 	subs, err := c.GetSubscriptions(ctx)
 	if err != nil {
@@ -29,17 +34,120 @@ func (c *Client) InitWebhook(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("create subscription: %w", err)
 	}
-	c.subscriptionID = subID
 
+	if err := c.setSubscription(ctx, subID); err != nil {
+		return err
+	}
+
+	c.StartWebhookWorkers()
 	return nil
 }
 
+// ReconcileWebhook keeps the application's push subscription if its callback URL matches the
+// client's, and recreates it otherwise. Replicas of a deployment can call it concurrently on startup,
+// none of them drops events of a subscription the others rely on.
+func (c *Client) ReconcileWebhook(ctx context.Context) error {
+	if c.webhookCallbackURL == "" {
+		return fmt.Errorf("webhook callback URL is not set")
+	}
+
+	subs, err := c.GetSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("get subscriptions: %w", err)
+	}
+
+	var keep *Subscription
+	for _, sub := range subs {
+		if keep == nil && sub.CallbackURL == c.webhookCallbackURL {
+			keep = sub
+			continue
+		}
+
+		c.logger.InfoContext(ctx, "delete mismatching subscription", slog.Uint64("id", uint64(sub.ID)), slog.String("callbackURL", sub.CallbackURL))
+		if err := c.DeleteSubscription(ctx, sub.ID); err != nil {
+			return fmt.Errorf("delete subscription: %w", err)
+		}
+	}
+
+	var subID uint
+	if keep != nil {
+		c.logger.DebugContext(ctx, "keep subscription", slog.Uint64("id", uint64(keep.ID)))
+		subID = keep.ID
+	} else if subID, err = c.CreateSubscription(ctx); err != nil {
+		return fmt.Errorf("create subscription: %w", err)
+	}
+
+	if err := c.setSubscription(ctx, subID); err != nil {
+		return err
+	}
+
+	c.StartWebhookWorkers()
+	return nil
+}
+
+// CloseWebhook deletes the push subscription. Replicas sharing the subscription shouldn't call it on shutdown.
 func (c *Client) CloseWebhook(ctx context.Context) error {
-	if c.subscriptionID == 0 {
+	id := c.SubscriptionID()
+	if id == 0 {
+		return nil
+	}
+
+	if err := c.DeleteSubscription(ctx, id); err != nil {
+		return err
+	}
+
+	return c.setSubscription(ctx, 0)
+}
+
+// SubscriptionID returns the ID of the push subscription created or kept by InitWebhook or ReconcileWebhook,
+// or loaded by LoadWebhookState.
+func (c *Client) SubscriptionID() uint {
+	return uint(c.subscriptionID.Load())
+}
+
+// LoadWebhookState sets the subscription ID saved in the WebhookStateStore, so events can be
+// routed before the subscription is reconciled. A missing state is not an error.
+func (c *Client) LoadWebhookState(ctx context.Context) error {
+	if c.webhookState == nil {
+		return nil
+	}
+
+	state, err := c.webhookState.GetWebhookState(ctx, c.oacfg.ClientID)
+	if err != nil {
+		if errors.Is(err, ErrWebhookStateNotFound) {
+			return nil
+		}
+		return fmt.Errorf("get webhook state from %T: %w", c.webhookState, err)
+	}
+
+	c.subscriptionID.Store(uint64(state.SubscriptionID))
+
+	c.verifyTokenMu.Lock()
+	if c.verifyToken == "" {
+		c.verifyToken = state.VerifyToken
+	}
+	c.verifyTokenMu.Unlock()
+
+	return nil
+}
+
+// setSubscription sets the subscription ID and saves it in the WebhookStateStore.
+func (c *Client) setSubscription(ctx context.Context, id uint) error {
+	c.subscriptionID.Store(uint64(id))
+
+	if c.webhookState == nil {
 		return nil
 	}
 
-	return c.DeleteSubscription(ctx, c.subscriptionID)
+	c.verifyTokenMu.Lock()
+	state := &WebhookState{SubscriptionID: id, CallbackURL: c.webhookCallbackURL, VerifyToken: c.verifyToken}
+	c.verifyTokenMu.Unlock()
+
+	if err := c.webhookState.SaveWebhookState(ctx, c.oacfg.ClientID, state); err != nil {
+		return fmt.Errorf("save webhook state to %T: %w", c.webhookState, err)
+	}
+
+	return nil
 }
 
 func (c *Client) CreateSubscription(ctx context.Context) (uint, error) {
@@ -47,12 +155,17 @@ func (c *Client) CreateSubscription(ctx context.Context) (uint, error) {
 		return 0, fmt.Errorf("webhook callback URL is not set")
 	}
 
+	verifyToken, err := c.webhookVerifyToken(ctx)
+	if err != nil {
+		return 0, err
+	}
+
 	endpoint := MustParseURL(APIBaseURL + "/push_subscriptions")
 	endpoint.RawQuery = url.Values{
 		"client_id":     {c.oacfg.ClientID},
 		"client_secret": {c.oacfg.ClientSecret},
 		"callback_url":  {c.webhookCallbackURL},
-		"verify_token":  {c.webhookVerifyToken()},
+		"verify_token":  {verifyToken},
 	}.Encode()
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.String(), nil)
@@ -132,13 +245,13 @@ func (c *Client) GetSubscriptions(ctx context.Context) ([]*Subscription, error)
 	return v, nil
 }
 
-type EventHandler func(event Event) error
+// EventHandler handles a webhook event. ctx is canceled when the client's Shutdown times out.
+type EventHandler func(ctx context.Context, event Event) error
 
+// RegisterEventHandler adds a handler for webhook events. Register all handlers before the workers
+// are started by StartWebhookWorkers, InitWebhook or ReconcileWebhook; events are only handled by the
+// handlers registered by then.
 func (c *Client) RegisterEventHandler(handler EventHandler) error {
-	if c.subscriptionID == 0 {
-		return fmt.Errorf("webhook is not initialized")
-	}
-
 	c.eventHandlersLock.Lock()
 	c.eventHandlers = append(c.eventHandlers, handler)
 	c.eventHandlersLock.Unlock()
@@ -146,11 +259,34 @@ func (c *Client) RegisterEventHandler(handler EventHandler) error {
 	return nil
 }
 
-func (c *Client) webhookVerifyToken() string {
-	return fmt.Sprintf("strava-go-%s", c.oacfg.ClientID)
+// StartWebhookWorkers starts handling webhook events with the registered handlers. Events received
+// before are queued, and events of an EventQueue are only claimed once the workers are started.
+// InitWebhook and ReconcileWebhook start the workers, call it when the subscription is managed otherwise,
+// e.g. with a Registry. Calling it again has no effect.
+func (c *Client) StartWebhookWorkers() {
+	c.dispatcher.startWorkers()
 }
 
 func (c *Client) WebhookCallback(w http.ResponseWriter, r *http.Request) {
+	if !c.webhookSourceAllowed(r) {
+		c.logger.WarnContext(r.Context(), "webhook request from disallowed address", slog.String("remoteAddr", r.RemoteAddr))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	// Read the limited body, so it can be dumped and decoded
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, c.webhookMaxBodySize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
 	// Dump request
 	dump, err := httputil.DumpRequest(r, true)
 	if err != nil {
@@ -176,7 +312,7 @@ func (c *Client) WebhookCallback(w http.ResponseWriter, r *http.Request) {
 
 func (c *Client) webhookValidation(w http.ResponseWriter, r *http.Request) {
 	// Verify the token matches
-	if token := r.URL.Query().Get("hub.verify_token"); token != c.webhookVerifyToken() {
+	if !c.validVerifyToken(r.Context(), r.URL.Query().Get("hub.verify_token")) {
 		http.Error(w, "invalid verification token", http.StatusBadRequest)
 		return
 	}
@@ -200,6 +336,18 @@ type Event struct {
 	EventTime      uint              `json:"event_time"`
 }
 
+// Time returns the time the event occurred.
+func (e Event) Time() time.Time {
+	return time.Unix(int64(e.EventTime), 0)
+}
+
+// IsDeauthorization reports whether the event is sent because the athlete revoked the application's access.
+func (e Event) IsDeauthorization() bool {
+	return e.ObjectType == EventObjectTypeAthlete &&
+		e.AspectType == EventAspectTypeUpdate &&
+		e.Updates["authorized"] == "false"
+}
+
 type EventObjectType string
 
 const (
@@ -222,15 +370,27 @@ func (c *Client) webhookEvent(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c.eventHandlersLock.RLock()
-	defer c.eventHandlersLock.RUnlock()
+	// Only Strava knows the subscription ID, events of other subscriptions are forged or misrouted.
+	switch id := c.SubscriptionID(); {
+	case id == 0:
+		http.Error(w, "webhook is not initialized", http.StatusServiceUnavailable)
+		return
+	case event.SubscriptionID != id:
+		c.logger.WarnContext(r.Context(), "webhook event of unknown subscription", slog.Any("event", event))
+		http.Error(w, "unknown subscription", http.StatusForbidden)
+		return
+	}
 
-	for _, handler := range c.eventHandlers {
-		go func(handler EventHandler) {
-			if err := handler(event); err != nil {
-				c.logger.Error("webhook event handled with error", "error", err)
-			}
-		}(handler)
+	if c.markEventSeen(r.Context(), event) {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := c.dispatcher.enqueue(r.Context(), event); err != nil {
+		c.forgetEvent(r.Context(), event)
+		c.logger.WarnContext(r.Context(), "webhook event rejected", slog.Any("event", event), slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
 	}
 
 	w.WriteHeader(http.StatusOK)
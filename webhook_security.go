@@ -0,0 +1,137 @@
+package strava
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// DefaultWebhookMaxBodySize limits the size of webhook request bodies. Strava events are a few hundred bytes.
+const DefaultWebhookMaxBodySize = 64 << 10
+
+// webhookVerifyToken returns the token Strava echoes when validating the callback URL. Unless set
+// with WithWebhookVerifyToken, it's loaded from the WebhookStateStore or generated randomly.
+// A generated token is saved before it's sent to Strava, as the validation request may reach another
+// replica while the subscription is still being created.
+func (c *Client) webhookVerifyToken(ctx context.Context) (string, error) {
+	c.verifyTokenMu.Lock()
+	defer c.verifyTokenMu.Unlock()
+
+	if c.verifyToken != "" {
+		return c.verifyToken, nil
+	}
+
+	var state *WebhookState
+	if c.webhookState != nil {
+		var err error
+		state, err = c.webhookState.GetWebhookState(ctx, c.oacfg.ClientID)
+		if err != nil && !errors.Is(err, ErrWebhookStateNotFound) {
+			return "", fmt.Errorf("get webhook state from %T: %w", c.webhookState, err)
+		}
+
+		if state != nil && state.VerifyToken != "" {
+			c.verifyToken = state.VerifyToken
+			return c.verifyToken, nil
+		}
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate verify token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+
+	if c.webhookState != nil {
+		saved := &WebhookState{SubscriptionID: c.SubscriptionID(), CallbackURL: c.webhookCallbackURL, VerifyToken: token}
+		if state != nil {
+			saved.SubscriptionID, saved.CallbackURL = state.SubscriptionID, state.CallbackURL
+		}
+
+		if err := c.webhookState.SaveWebhookState(ctx, c.oacfg.ClientID, saved); err != nil {
+			return "", fmt.Errorf("save webhook state to %T: %w", c.webhookState, err)
+		}
+	}
+
+	c.verifyToken = token
+	return c.verifyToken, nil
+}
+
+// validVerifyToken reports whether token is the client's verify token or the one in the
+// WebhookStateStore, which another replica may have generated while creating the subscription.
+func (c *Client) validVerifyToken(ctx context.Context, token string) bool {
+	c.verifyTokenMu.Lock()
+	want := c.verifyToken
+	c.verifyTokenMu.Unlock()
+
+	if want != "" && subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1 {
+		return true
+	}
+
+	if c.webhookState == nil {
+		return false
+	}
+
+	state, err := c.webhookState.GetWebhookState(ctx, c.oacfg.ClientID)
+	if err != nil {
+		if !errors.Is(err, ErrWebhookStateNotFound) {
+			c.logger.ErrorContext(ctx, "get webhook state", slog.Any("error", err))
+		}
+		return false
+	}
+
+	return state.VerifyToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(state.VerifyToken)) == 1
+}
+
+// webhookSourceAllowed reports whether the request comes from an address allowed by WithWebhookAllowedIPs.
+func (c *Client) webhookSourceAllowed(r *http.Request) bool {
+	if len(c.webhookAllowedIPs) == 0 {
+		return true
+	}
+
+	addr, ok := webhookSourceIP(r, c.webhookClientIPHeader)
+	if !ok {
+		return false
+	}
+
+	for _, p := range c.webhookAllowedIPs {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// webhookSourceIP returns the request's source address, taken from the last value of header if set,
+// as that's the one added by the trusted proxy.
+func webhookSourceIP(r *http.Request, header string) (netip.Addr, bool) {
+	raw := r.RemoteAddr
+	if header != "" {
+		values := r.Header.Values(header)
+		if len(values) == 0 {
+			return netip.Addr{}, false
+		}
+
+		parts := strings.Split(values[len(values)-1], ",")
+		raw = strings.TrimSpace(parts[len(parts)-1])
+	}
+
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+
+	addr, err := netip.ParseAddr(raw)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+
+	return addr.Unmap(), true
+}
@@ -0,0 +1,23 @@
+package strava
+
+import (
+	"context"
+	"errors"
+)
+
+var ErrWebhookStateNotFound = errors.New("webhook state not found")
+
+// WebhookState is the push subscription of a client, persisted across restarts and shared by its replicas.
+type WebhookState struct {
+	SubscriptionID uint   `json:"subscription_id"`
+	CallbackURL    string `json:"callback_url"`
+	// VerifyToken is the random verify token used to create the subscription.
+	VerifyToken string `json:"verify_token,omitempty"`
+}
+
+// WebhookStateStore persists the WebhookState of a client.
+type WebhookStateStore interface {
+	// GetWebhookState returns ErrWebhookStateNotFound if no state was saved for the client ID.
+	GetWebhookState(ctx context.Context, clientID string) (*WebhookState, error)
+	SaveWebhookState(ctx context.Context, clientID string, state *WebhookState) error
+}
@@ -0,0 +1,307 @@
+package strava
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync"
+	"testing"
+	"testing/iotest"
+
+	"github.com/gookit/goutil/testutil/assert"
+)
+
+// newTestAPITransport returns a transport sending all requests to handler instead of Strava.
+func newTestAPITransport(t *testing.T, handler http.Handler) *http.Transport {
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	return &http.Transport{
+		DialContext: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+		},
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	}
+}
+
+// testSubscriptionAPI emulates the push subscription endpoints.
+type testSubscriptionAPI struct {
+	mu      sync.Mutex
+	subs    []*Subscription
+	nextID  uint
+	deleted []uint
+	// verifyToken is the token of the last created subscription.
+	verifyToken string
+}
+
+func (a *testSubscriptionAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/api/v3/push_subscriptions":
+		_ = json.NewEncoder(w).Encode(a.subs)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/v3/push_subscriptions":
+		a.nextID++
+		a.verifyToken = r.URL.Query().Get("verify_token")
+		a.subs = append(a.subs, &Subscription{ID: a.nextID, CallbackURL: r.URL.Query().Get("callback_url")})
+		_ = json.NewEncoder(w).Encode(map[string]uint{"id": a.nextID})
+	case r.Method == http.MethodDelete:
+		for i, sub := range a.subs {
+			if r.URL.Path == fmt.Sprintf("/api/v3/push_subscriptions/%d", sub.ID) {
+				a.subs = append(a.subs[:i], a.subs[i+1:]...)
+				a.deleted = append(a.deleted, sub.ID)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		http.NotFound(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// testWebhookStateStore keeps the state of a single client.
+type testWebhookStateStore struct {
+	mu    sync.Mutex
+	state *WebhookState
+}
+
+func (s *testWebhookStateStore) GetWebhookState(context.Context, string) (*WebhookState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.state == nil {
+		return nil, ErrWebhookStateNotFound
+	}
+	return s.state, nil
+}
+
+func (s *testWebhookStateStore) SaveWebhookState(_ context.Context, _ string, state *WebhookState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state = state
+	return nil
+}
+
+func TestClient_ReconcileWebhook(t *testing.T) {
+	const callbackURL = "https://example.com/callback"
+
+	tests := []struct {
+		name        string
+		subs        []*Subscription
+		wantID      uint
+		wantDeleted []uint
+	}{
+		{
+			name:   "keep matching",
+			subs:   []*Subscription{{ID: 1, CallbackURL: "https://old.example.com/callback"}, {ID: 2, CallbackURL: callbackURL}},
+			wantID: 2, wantDeleted: []uint{1},
+		},
+		{
+			name:   "recreate mismatching",
+			subs:   []*Subscription{{ID: 1, CallbackURL: "https://old.example.com/callback"}},
+			wantID: 2, wantDeleted: []uint{1},
+		},
+		{
+			name:   "create missing",
+			wantID: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			api := &testSubscriptionAPI{subs: tt.subs, nextID: 1}
+			state := &testWebhookStateStore{}
+			c := NewClient("client_id", "client_secret", "", nil,
+				WithTransport(newTestAPITransport(t, api)),
+				WithWebhookCallbackURL(callbackURL),
+				WithWebhookStateStore(state),
+			)
+
+			// act
+			err := c.ReconcileWebhook(context.Background())
+
+			// assert
+			assert.NoErr(t, err)
+			assert.Eq(t, tt.wantID, c.SubscriptionID())
+			assert.Eq(t, tt.wantDeleted, api.deleted)
+			assert.Eq(t, &WebhookState{SubscriptionID: tt.wantID, CallbackURL: callbackURL, VerifyToken: api.verifyToken}, state.state)
+
+			// A restarted client knows the subscription before reconciling.
+			restarted := NewClient("client_id", "client_secret", "", nil, WithWebhookStateStore(state))
+			assert.NoErr(t, restarted.LoadWebhookState(context.Background()))
+			assert.Eq(t, tt.wantID, restarted.SubscriptionID())
+			assert.Eq(t, api.verifyToken, restarted.verifyToken)
+		})
+	}
+}
+
+func TestClient_WebhookCallbackSecurity(t *testing.T) {
+	const event = `{"object_type":"activity","object_id":1,"aspect_type":"create","owner_id":2,"subscription_id":12,"event_time":1516126040}`
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		method     string
+		target     string
+		body       string
+		remoteAddr string
+		header     http.Header
+		want       int
+	}{
+		{
+			name:   "validation",
+			method: http.MethodGet, target: "/callback?hub.challenge=abc&hub.verify_token=secret",
+			want: http.StatusOK,
+		},
+		{
+			name:   "validation with wrong token",
+			method: http.MethodGet, target: "/callback?hub.challenge=abc&hub.verify_token=strava-go-client_id",
+			want: http.StatusBadRequest,
+		},
+		{
+			name:   "event",
+			method: http.MethodPost, target: "/callback", body: event,
+			want: http.StatusOK,
+		},
+		{
+			name:   "event of other subscription",
+			method: http.MethodPost, target: "/callback", body: strings.Replace(event, "12", "13", 1),
+			want: http.StatusForbidden,
+		},
+		{
+			name:   "body too large",
+			opts:   []Option{WithWebhookMaxBodySize(64)},
+			method: http.MethodPost, target: "/callback", body: event,
+			want: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:   "unreadable body",
+			method: http.MethodPost, target: "/callback", body: "unreadable",
+			want: http.StatusBadRequest,
+		},
+		{
+			name:   "allowed address",
+			opts:   []Option{WithWebhookAllowedIPs(netip.MustParsePrefix("192.0.2.0/24"))},
+			method: http.MethodPost, target: "/callback", body: event, remoteAddr: "192.0.2.10:4242",
+			want: http.StatusOK,
+		},
+		{
+			name:   "disallowed address",
+			opts:   []Option{WithWebhookAllowedIPs(netip.MustParsePrefix("192.0.2.0/24"))},
+			method: http.MethodPost, target: "/callback", body: event, remoteAddr: "198.51.100.1:4242",
+			want: http.StatusForbidden,
+		},
+		{
+			name: "allowed address behind proxy",
+			opts: []Option{
+				WithWebhookAllowedIPs(netip.MustParsePrefix("192.0.2.0/24")),
+				WithWebhookClientIPHeader("X-Forwarded-For"),
+			},
+			method: http.MethodPost, target: "/callback", body: event, remoteAddr: "10.0.0.1:4242",
+			header: http.Header{"X-Forwarded-For": {"198.51.100.1, 192.0.2.10"}},
+			want:   http.StatusOK,
+		},
+		{
+			name: "spoofed address behind proxy",
+			opts: []Option{
+				WithWebhookAllowedIPs(netip.MustParsePrefix("192.0.2.0/24")),
+				WithWebhookClientIPHeader("X-Forwarded-For"),
+			},
+			method: http.MethodPost, target: "/callback", body: event, remoteAddr: "10.0.0.1:4242",
+			header: http.Header{"X-Forwarded-For": {"192.0.2.10, 198.51.100.1"}},
+			want:   http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// arrange
+			c := NewClient("client_id", "client_secret", "", nil, append([]Option{WithWebhookVerifyToken("secret")}, tt.opts...)...)
+			c.subscriptionID.Store(12)
+
+			req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+			if tt.body == "unreadable" {
+				req.Body = io.NopCloser(iotest.ErrReader(errors.New("connection reset")))
+			}
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			for k, v := range tt.header {
+				req.Header[k] = v
+			}
+
+			// act
+			rec := httptest.NewRecorder()
+			c.WebhookCallback(rec, req)
+
+			// assert
+			assert.Eq(t, tt.want, rec.Code)
+			assert.NoErr(t, c.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestClient_webhookVerifyToken(t *testing.T) {
+	// arrange
+	ctx := context.Background()
+	a := NewClient("client_id", "client_secret", "", nil)
+	b := NewClient("client_id", "client_secret", "", nil)
+
+	// act
+	tokenA, errA := a.webhookVerifyToken(ctx)
+	again, _ := a.webhookVerifyToken(ctx)
+	tokenB, errB := b.webhookVerifyToken(ctx)
+
+	// assert
+	assert.NoErr(t, errA)
+	assert.NoErr(t, errB)
+	assert.Len(t, tokenA, 48)
+	assert.Eq(t, tokenA, again)
+	assert.NotEq(t, tokenA, tokenB)
+}
+
+func TestClient_CreateSubscriptionValidatedByReplica(t *testing.T) {
+	// arrange
+	const callbackURL = "https://example.com/callback"
+	state := &testWebhookStateStore{}
+	replica := NewClient("client_id", "client_secret", "", nil, WithWebhookStateStore(state))
+
+	api := &testSubscriptionAPI{}
+	var validation int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Strava validates the callback URL before responding, here on another replica.
+		if r.Method == http.MethodPost {
+			target := "/callback?hub.challenge=abc&hub.verify_token=" + r.URL.Query().Get("verify_token")
+			rec := httptest.NewRecorder()
+			replica.WebhookCallback(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			validation = rec.Code
+		}
+		api.ServeHTTP(w, r)
+	})
+
+	c := NewClient("client_id", "client_secret", "", nil,
+		WithTransport(newTestAPITransport(t, handler)),
+		WithWebhookCallbackURL(callbackURL),
+		WithWebhookStateStore(state),
+	)
+
+	// act
+	err := c.ReconcileWebhook(context.Background())
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, http.StatusOK, validation)
+	assert.Eq(t, &WebhookState{SubscriptionID: 1, CallbackURL: callbackURL, VerifyToken: api.verifyToken}, state.state)
+}
@@ -0,0 +1,236 @@
+// Package webhooksim simulates Strava's push subscription requests against a local webhook
+// callback, e.g. a server using strava.Client.WebhookCallback.
+package webhooksim
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/marvell/strava-go"
+)
+
+const DefaultTimeout = 5 * time.Second
+
+type Option func(*Simulator)
+
+// WithVerifyToken sets the verify token sent in the validation request, see strava.WithWebhookVerifyToken.
+func WithVerifyToken(token string) Option {
+	return func(s *Simulator) {
+		s.verifyToken = token
+	}
+}
+
+// WithSubscriptionID sets the subscription ID of sent events, see strava.WithWebhookSubscriptionID.
+func WithSubscriptionID(id uint) Option {
+	return func(s *Simulator) {
+		s.subscriptionID = id
+	}
+}
+
+func WithHTTPClient(hc *http.Client) Option {
+	return func(s *Simulator) {
+		s.hc = hc
+	}
+}
+
+// Simulator sends the requests Strava sends to a webhook callback URL.
+type Simulator struct {
+	callbackURL    string
+	verifyToken    string
+	subscriptionID uint
+	hc             *http.Client
+}
+
+func New(callbackURL string, opts ...Option) *Simulator {
+	s := &Simulator{
+		callbackURL: callbackURL,
+		hc:          &http.Client{Timeout: DefaultTimeout},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Validate performs the validation handshake Strava does when a subscription is created:
+// it sends a random challenge with the verify token and checks that it's echoed.
+func (s *Simulator) Validate(ctx context.Context) error {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Errorf("generate challenge: %w", err)
+	}
+	challenge := hex.EncodeToString(b)
+
+	endpoint, err := url.Parse(s.callbackURL)
+	if err != nil {
+		return fmt.Errorf("parse callback URL: %w", err)
+	}
+
+	q := endpoint.Query()
+	q.Set("hub.mode", "subscribe")
+	q.Set("hub.challenge", challenge)
+	q.Set("hub.verify_token", s.verifyToken)
+	endpoint.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+
+	body, err := s.do(req)
+	if err != nil {
+		return err
+	}
+
+	var v struct {
+		Challenge string `json:"hub.challenge"`
+	}
+	if err := json.Unmarshal(body, &v); err != nil {
+		return fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	if v.Challenge != challenge {
+		return fmt.Errorf("challenge mismatch: sent %q, got %q", challenge, v.Challenge)
+	}
+
+	return nil
+}
+
+// Send posts the event. Its subscription ID is replaced by the simulator's, if set.
+func (s *Simulator) Send(ctx context.Context, event strava.Event) error {
+	if s.subscriptionID != 0 {
+		event.SubscriptionID = s.subscriptionID
+	}
+
+	b, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshal event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.callbackURL, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	_, err = s.do(req)
+	return err
+}
+
+// Replay sends the events of a log with one JSON event per line, at most perSecond events a second
+// if perSecond is positive. Empty lines are skipped. It returns the number of sent events.
+func (s *Simulator) Replay(ctx context.Context, r io.Reader, perSecond float64) (int, error) {
+	lmt := rate.NewLimiter(rate.Inf, 1)
+	if perSecond > 0 {
+		lmt = rate.NewLimiter(rate.Limit(perSecond), 1)
+	}
+
+	sc := bufio.NewScanner(r)
+	sent := 0
+	for line := 1; sc.Scan(); line++ {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+
+		var event strava.Event
+		if err := json.Unmarshal(sc.Bytes(), &event); err != nil {
+			return sent, fmt.Errorf("parse line %d: %w", line, err)
+		}
+
+		if err := lmt.Wait(ctx); err != nil {
+			return sent, err
+		}
+
+		if err := s.Send(ctx, event); err != nil {
+			return sent, fmt.Errorf("send line %d: %w", line, err)
+		}
+		sent++
+	}
+
+	if err := sc.Err(); err != nil {
+		return sent, fmt.Errorf("read events: %w", err)
+	}
+
+	return sent, nil
+}
+
+func (s *Simulator) do(req *http.Request) ([]byte, error) {
+	resp, err := s.hc.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("callback responded with status code %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+
+	return body, nil
+}
+
+// ActivityCreate returns the event of a new activity.
+func ActivityCreate(athleteID, activityID uint) strava.Event {
+	return newEvent(strava.EventObjectTypeActivity, activityID, strava.EventAspectTypeCreate, athleteID, nil)
+}
+
+// ActivityUpdate returns the event of an activity change. Strava sends the changed title, type and
+// private fields in the updates, see ActivityUpdates.
+func ActivityUpdate(athleteID, activityID uint, updates map[string]string) strava.Event {
+	return newEvent(strava.EventObjectTypeActivity, activityID, strava.EventAspectTypeUpdate, athleteID, updates)
+}
+
+// ActivityUpdates returns the updates of an activity change, leaving out empty title and type and a nil private.
+func ActivityUpdates(title, activityType string, private *bool) map[string]string {
+	updates := map[string]string{}
+	if title != "" {
+		updates["title"] = title
+	}
+	if activityType != "" {
+		updates["type"] = activityType
+	}
+	if private != nil {
+		updates["private"] = strconv.FormatBool(*private)
+	}
+
+	return updates
+}
+
+// ActivityDelete returns the event of a deleted activity.
+func ActivityDelete(athleteID, activityID uint) strava.Event {
+	return newEvent(strava.EventObjectTypeActivity, activityID, strava.EventAspectTypeDelete, athleteID, nil)
+}
+
+// Deauthorize returns the event sent when the athlete revokes the application's access.
+func Deauthorize(athleteID uint) strava.Event {
+	return newEvent(strava.EventObjectTypeAthlete, athleteID, strava.EventAspectTypeUpdate, athleteID, map[string]string{"authorized": "false"})
+}
+
+func newEvent(objectType strava.EventObjectType, objectID uint, aspectType strava.EventAspectType, ownerID uint, updates map[string]string) strava.Event {
+	return strava.Event{
+		ObjectType: objectType,
+		ObjectID:   objectID,
+		AspectType: aspectType,
+		Updates:    updates,
+		OwnerID:    ownerID,
+		EventTime:  uint(time.Now().Unix()),
+	}
+}
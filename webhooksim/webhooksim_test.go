@@ -0,0 +1,93 @@
+package webhooksim
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gookit/goutil/testutil/assert"
+
+	"github.com/marvell/strava-go"
+)
+
+func newTestClient(t *testing.T) (*strava.Client, *httptest.Server) {
+	c := strava.NewClient("client_id", "client_secret", "", nil,
+		strava.WithWebhookVerifyToken("secret"),
+		strava.WithWebhookSubscriptionID(42),
+	)
+
+	srv := httptest.NewServer(http.HandlerFunc(c.WebhookCallback))
+	t.Cleanup(srv.Close)
+
+	return c, srv
+}
+
+func TestSimulator_Validate(t *testing.T) {
+	// arrange
+	_, srv := newTestClient(t)
+
+	// act
+	valid := New(srv.URL, WithVerifyToken("secret")).Validate(context.Background())
+	invalid := New(srv.URL, WithVerifyToken("guess")).Validate(context.Background())
+
+	// assert
+	assert.NoErr(t, valid)
+	assert.ErrSubMsg(t, invalid, "status code 400")
+}
+
+func TestSimulator_Replay(t *testing.T) {
+	// arrange
+	c, srv := newTestClient(t)
+
+	var (
+		mu     sync.Mutex
+		events []strava.Event
+	)
+	_ = c.RegisterEventHandler(func(ctx context.Context, event strava.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event)
+		return nil
+	})
+	c.StartWebhookWorkers()
+
+	log := `{"object_type":"activity","object_id":1,"aspect_type":"create","owner_id":7,"subscription_id":120475,"event_time":1516126040}
+
+{"object_type":"activity","object_id":1,"aspect_type":"update","owner_id":7,"subscription_id":120475,"event_time":1516126041,"updates":{"title":"Messy"}}
+`
+
+	// act
+	sent, err := New(srv.URL, WithSubscriptionID(42)).Replay(context.Background(), strings.NewReader(log), 100)
+	assert.NoErr(t, c.Shutdown(context.Background()))
+
+	// assert
+	assert.NoErr(t, err)
+	assert.Eq(t, 2, sent)
+	assert.Len(t, events, 2)
+	assert.Eq(t, uint(42), events[1].SubscriptionID)
+	assert.Eq(t, "Messy", events[1].Updates["title"])
+}
+
+func TestSimulator_Send(t *testing.T) {
+	// arrange
+	_, srv := newTestClient(t)
+	private := true
+
+	// act
+	ours := New(srv.URL, WithSubscriptionID(42)).Send(context.Background(), ActivityUpdate(7, 1, ActivityUpdates("Messy", "Ride", &private)))
+	theirs := New(srv.URL, WithSubscriptionID(43)).Send(context.Background(), Deauthorize(7))
+
+	// assert
+	assert.NoErr(t, ours)
+	assert.ErrSubMsg(t, theirs, "status code 403")
+}
+
+func TestActivityUpdates(t *testing.T) {
+	private := false
+
+	assert.Eq(t, map[string]string{"title": "Messy", "private": "false"}, ActivityUpdates("Messy", "", &private))
+	assert.Eq(t, map[string]string{"type": "Run"}, ActivityUpdates("", "Run", nil))
+}